@@ -1,11 +1,14 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"math"
 	"net"
 	"os"
+	"strings"
 	"sync"
 	"time"
 
@@ -15,9 +18,104 @@ import (
 // ConstructConfig holds the configuration for a construct, loaded from JSON.
 type ConstructConfig struct {
 	Cubes       []Cube             `json:"cubes"`        // List of cubes with their positions
-	Chains      [][]string         `json:"chains"`       // Chains of cube names to link
-	JointType   string             `json:"joint_type"`   // Type of joint (e.g., "hinge")
+	Chains      []ChainSpec        `json:"chains"`       // Chains of cube names to link
+	JointType   string             `json:"joint_type"`   // Default type of joint (e.g., "hinge")
 	JointParams map[string]float64 `json:"joint_params"` // Parameters for joints
+
+	// JointOverrides replaces individual JointParams for specific links, keyed by "cubeA->cubeB"
+	// (either order matches). Keys left out of an override keep the JointParams value.
+	JointOverrides map[string]map[string]float64 `json:"joint_overrides,omitempty"`
+}
+
+// ChainSpec is one chain of cubes to link in order. In JSON a chain is either a flat list of cube
+// names, linked with the config's joint_type, or {"names": [...], "joint_type": "fixed"}.
+type ChainSpec struct {
+	Names     []string `json:"names"`
+	JointType string   `json:"joint_type,omitempty"` // Overrides ConstructConfig.JointType for this chain
+}
+
+// UnmarshalJSON accepts both the flat list and the object form of a chain. Unknown fields in the
+// object form are ignored here; strict config loading rejects them with checkChainFields.
+func (cs *ChainSpec) UnmarshalJSON(data []byte) error {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		*cs = ChainSpec{}
+		return json.Unmarshal(trimmed, &cs.Names)
+	}
+
+	// Decode into an alias so this method is not called recursively
+	type chainSpec ChainSpec
+	var spec chainSpec
+	if err := json.Unmarshal(trimmed, &spec); err != nil {
+		return fmt.Errorf("invalid chain: %v", err)
+	}
+	*cs = ChainSpec(spec)
+	return nil
+}
+
+// checkChainFields rejects unknown fields in the object form of a config's chains. The outer
+// decoder's DisallowUnknownFields does not reach ChainSpec.UnmarshalJSON, so strict loading runs it.
+func checkChainFields(data []byte) error {
+	var raw struct {
+		Chains []json.RawMessage `json:"chains"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	type chainSpec ChainSpec
+	for i, chain := range raw.Chains {
+		trimmed := bytes.TrimSpace(chain)
+		if len(trimmed) == 0 || trimmed[0] != '{' {
+			continue
+		}
+		decoder := json.NewDecoder(bytes.NewReader(trimmed))
+		decoder.DisallowUnknownFields()
+		var spec chainSpec
+		if err := decoder.Decode(&spec); err != nil {
+			return fmt.Errorf("invalid chain %d: %v", i, err)
+		}
+	}
+	return nil
+}
+
+// MarshalJSON writes chains without their own joint type in the flat list form.
+func (cs ChainSpec) MarshalJSON() ([]byte, error) {
+	if cs.JointType == "" {
+		return json.Marshal(cs.Names)
+	}
+	type chainSpec ChainSpec
+	return json.Marshal(chainSpec(cs))
+}
+
+// chainJointType returns the joint type used to link a chain.
+func (c *ConstructConfig) chainJointType(chain ChainSpec) string {
+	if chain.JointType != "" {
+		return chain.JointType
+	}
+	return c.JointType
+}
+
+// jointOverrideKey builds the JointOverrides key for a link between two cubes.
+func jointOverrideKey(cubeA, cubeB string) string {
+	return cubeA + "->" + cubeB
+}
+
+// jointOverride returns the override for the link between two cubes in either order.
+func (c *ConstructConfig) jointOverride(cubeA, cubeB string) (map[string]float64, bool) {
+	if override, ok := c.JointOverrides[jointOverrideKey(cubeA, cubeB)]; ok {
+		return override, true
+	}
+	override, ok := c.JointOverrides[jointOverrideKey(cubeB, cubeA)]
+	return override, ok
+}
+
+// Translate shifts every cube in the template by delta. Chains and all other fields are unchanged.
+func (c *ConstructConfig) Translate(delta [3]float64) {
+	for i := range c.Cubes {
+		for axis := 0; axis < 3 && axis < len(c.Cubes[i].Position); axis++ {
+			c.Cubes[i].Position[axis] += delta[axis]
+		}
+	}
 }
 
 // Construct represents a dynamic construct with its configuration and server details.
@@ -27,7 +125,18 @@ type Construct struct {
 	constructAuthPass   string // Authentication password for the server
 	constructDelimiter  string // Message delimiter for the TCP protocol
 	unitName            string // Unique identifier for this construct instance
-	RawJSON             string // New field to store the raw JSON string
+	ParentCube          string // Optional: server name of a cube to attach the construct to with a fixed joint
+	LinkBatchSize       int    // Optional: max joints per link_cube_chains message (defaults to LinkBatchSize)
+	spawned             []Cube // Cubes as placed by the last Spawn, used to reset the construct
+	spawnedMu           sync.Mutex
+	spawnedCubes        []string // Server names of the cubes this construct spawned, guarded by spawnedMu
+	RawJSON             string   // New field to store the raw JSON string
+	LenientConfig       bool     // Accept unknown fields and missing required fields when loading a config
+	Logger              Logger   // Optional: receives diagnostics (nil uses DefaultLogger)
+	Metrics             Metrics  // Optional: receives spawn and despawn counts (nil uses DefaultMetrics)
+	DryRun              bool     // Record and log commands instead of sending them (see DryRunMessages)
+	DryRunForwardReads  bool     // With DryRun, send read-only queries to the real server
+	dryRun              DryRunRecorder
 	Model               *paragon.Network
 	LstModels           []*paragon.Network
 }
@@ -42,46 +151,83 @@ func NewConstruct(constructServerAddr, constructAuthPass, constructDelimiter str
 }
 
 // LoadConfigFromJSON loads the construct configuration from a JSON file and applies the unitName.
-func (c *Construct) LoadConfigFromJSON(filename, unitName string) error {
+// Optional template variables resolve string values such as "$armLen" to numbers before parsing.
+func (c *Construct) LoadConfigFromJSON(filename, unitName string, vars ...map[string]float64) error {
 	data, err := os.ReadFile(filename)
 	if err != nil {
 		return fmt.Errorf("failed to read JSON file %s: %v", filename, err)
 	}
 
-	var config ConstructConfig
-	if err := json.Unmarshal(data, &config); err != nil {
-		return fmt.Errorf("failed to unmarshal JSON: %v", err)
+	config, err := parseConstructConfig(data, unitName, !c.LenientConfig, vars...)
+	if err != nil {
+		return fmt.Errorf("failed to load config %s: %v", filename, err)
+	}
+	if !c.LenientConfig {
+		if err := validateConstructConfig(config); err != nil {
+			return fmt.Errorf("invalid config %s: %v", filename, err)
+		}
 	}
 
 	// Set the unitName for this construct instance
 	c.unitName = unitName
+	c.Config = config
+	return nil
+}
 
-	// Prefix all cube names with the unitName
-	for i := range config.Cubes {
-		config.Cubes[i].Name = unitName + "_" + config.Cubes[i].Name
+// LoadConfigFromJSONString loads the construct configuration from a JSON string and applies the unitName.
+// Optional template variables are resolved the same way as in LoadConfigFromJSON.
+func (c *Construct) LoadConfigFromJSONString(jsonStr, unitName string, vars ...map[string]float64) error {
+	config, err := parseConstructConfig([]byte(jsonStr), unitName, !c.LenientConfig, vars...)
+	if err != nil {
+		return fmt.Errorf("failed to load config from JSON string: %v", err)
 	}
-
-	// Prefix all chain names with the unitName
-	for i := range config.Chains {
-		for j := range config.Chains[i] {
-			config.Chains[i][j] = unitName + "_" + config.Chains[i][j]
+	if !c.LenientConfig {
+		if err := validateConstructConfig(config); err != nil {
+			return fmt.Errorf("invalid config in JSON string: %v", err)
 		}
 	}
 
+	// Set the unitName for this construct instance
+	c.unitName = unitName
 	c.Config = config
 	return nil
 }
 
-// LoadConfigFromJSONString loads the construct configuration from a JSON string and applies the unitName.
-func (c *Construct) LoadConfigFromJSONString(jsonStr, unitName string) error {
-	// Unmarshal the JSON string into a ConstructConfig
+// parseConstructConfig resolves template variables, unmarshals the config, and prefixes
+// every cube and chain name with the unitName. In strict mode unknown fields are rejected
+// and the config must declare at least one cube and a joint type.
+func parseConstructConfig(data []byte, unitName string, strict bool, vars ...map[string]float64) (ConstructConfig, error) {
 	var config ConstructConfig
-	if err := json.Unmarshal([]byte(jsonStr), &config); err != nil {
-		return fmt.Errorf("failed to unmarshal JSON string: %v", err)
+
+	merged := make(map[string]float64)
+	for _, v := range vars {
+		for name, value := range v {
+			merged[name] = value
+		}
+	}
+	data, err := resolveTemplateVars(data, merged)
+	if err != nil {
+		return config, err
 	}
 
-	// Set the unitName for this construct instance
-	c.unitName = unitName
+	if strict {
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&config); err != nil {
+			return config, fmt.Errorf("invalid config: %v", err)
+		}
+		if err := checkChainFields(data); err != nil {
+			return config, fmt.Errorf("invalid config: %v", err)
+		}
+		if len(config.Cubes) == 0 {
+			return config, fmt.Errorf("invalid config: no cubes declared (expected a non-empty \"cubes\" list)")
+		}
+		if config.JointType == "" {
+			return config, fmt.Errorf("invalid config: \"joint_type\" is required")
+		}
+	} else if err := json.Unmarshal(data, &config); err != nil {
+		return config, err
+	}
 
 	// Prefix all cube names with the unitName
 	for i := range config.Cubes {
@@ -90,12 +236,99 @@ func (c *Construct) LoadConfigFromJSONString(jsonStr, unitName string) error {
 
 	// Prefix all chain names with the unitName
 	for i := range config.Chains {
-		for j := range config.Chains[i] {
-			config.Chains[i][j] = unitName + "_" + config.Chains[i][j]
+		for j := range config.Chains[i].Names {
+			config.Chains[i].Names[j] = unitName + "_" + config.Chains[i].Names[j]
 		}
 	}
 
-	c.Config = config
+	// Prefix both cube names in every override key
+	if len(config.JointOverrides) > 0 {
+		overrides := make(map[string]map[string]float64, len(config.JointOverrides))
+		for key, params := range config.JointOverrides {
+			cubeA, cubeB, ok := strings.Cut(key, "->")
+			if !ok {
+				return config, fmt.Errorf("invalid joint override key %q, expected \"cubeA->cubeB\"", key)
+			}
+			overrides[jointOverrideKey(unitName+"_"+cubeA, unitName+"_"+cubeB)] = params
+		}
+		config.JointOverrides = overrides
+	}
+
+	return config, nil
+}
+
+// SaveConfigToJSON writes the construct's config to filename as an indented template. The unitName
+// prefix is stripped from cube and chain names so the file can be loaded again under any unit name.
+func (c *Construct) SaveConfigToJSON(filename string) error {
+	prefix := c.unitName + "_"
+	config := c.Config
+	config.Cubes = make([]Cube, len(c.Config.Cubes))
+	for i, cube := range c.Config.Cubes {
+		cube.Name = strings.TrimPrefix(cube.Name, prefix)
+		config.Cubes[i] = cube
+	}
+	config.Chains = make([]ChainSpec, len(c.Config.Chains))
+	for i, chain := range c.Config.Chains {
+		config.Chains[i] = ChainSpec{Names: make([]string, len(chain.Names)), JointType: chain.JointType}
+		for j, name := range chain.Names {
+			config.Chains[i].Names[j] = strings.TrimPrefix(name, prefix)
+		}
+	}
+
+	if len(c.Config.JointOverrides) > 0 {
+		config.JointOverrides = make(map[string]map[string]float64, len(c.Config.JointOverrides))
+		for key, params := range c.Config.JointOverrides {
+			cubeA, cubeB, _ := strings.Cut(key, "->")
+			config.JointOverrides[jointOverrideKey(strings.TrimPrefix(cubeA, prefix), strings.TrimPrefix(cubeB, prefix))] = params
+		}
+	}
+
+	data, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode config for %s: %v", c.unitName, err)
+	}
+	if err := os.WriteFile(filename, data, 0644); err != nil {
+		return fmt.Errorf("failed to write JSON file %s: %v", filename, err)
+	}
+	return nil
+}
+
+// ValidateConfig checks that every chain references a declared cube (a trailing "_BASE" on a
+// chain name is ignored), that a joint type is set, and that every cube position has 3 components.
+// The loaders run it automatically unless LenientConfig is set.
+func (c *Construct) ValidateConfig() error {
+	return validateConstructConfig(c.Config)
+}
+
+func validateConstructConfig(config ConstructConfig) error {
+	if config.JointType == "" {
+		return fmt.Errorf("\"joint_type\" is required")
+	}
+	declared := make(map[string]bool, len(config.Cubes))
+	for _, cube := range config.Cubes {
+		if len(cube.Position) != 3 {
+			return fmt.Errorf("cube %s has position %v, expected 3 components", cube.Name, cube.Position)
+		}
+		declared[cube.Name] = true
+	}
+	linked := make(map[string]bool)
+	for i, spec := range config.Chains {
+		chain := spec.Names
+		for j, name := range chain {
+			if !declared[name] && !declared[strings.TrimSuffix(name, "_BASE")] {
+				return fmt.Errorf("chain %d references undeclared cube %s", i, name)
+			}
+			if j > 0 {
+				linked[jointOverrideKey(chain[j-1], name)] = true
+				linked[jointOverrideKey(name, chain[j-1])] = true
+			}
+		}
+	}
+	for key := range config.JointOverrides {
+		if !linked[key] {
+			return fmt.Errorf("joint override %s does not match any link in the chains", key)
+		}
+	}
 	return nil
 }
 
@@ -113,116 +346,172 @@ func (c *Construct) LoadJSONToString(jsonStr string) error {
 }
 
 // spawnCubeWithConfig spawns a cube using the Construct's server configuration.
-func (c *Construct) spawnCubeWithConfig(cube Cube, wg *sync.WaitGroup) {
-	defer wg.Done()
-	conn, err := net.Dial("tcp", c.constructServerAddr)
+func (c *Construct) spawnCubeWithConfig(cube Cube) error {
+	start := time.Now()
+	conn, _, err := c.connect()
 	if err != nil {
-		fmt.Printf("[Spawn] Failed to connect to %s: %v\n", c.constructServerAddr, err)
-		return
+		c.metrics().CubeSpawnFailed()
+		return err
 	}
 	defer conn.Close()
 
-	if _, err := conn.Write([]byte(c.constructAuthPass + c.constructDelimiter)); err != nil {
-		fmt.Printf("[Spawn] Auth write error to %s: %v\n", c.constructServerAddr, err)
-		return
+	if err := sendSpawn(conn, cube); err != nil {
+		c.metrics().CubeSpawnFailed()
+		return fmt.Errorf("%v on %s", err, c.constructServerAddr)
 	}
+	c.metrics().ObserveSpawnDuration(time.Since(start))
+	c.metrics().CubeSpawned()
 
-	_, err = readResponse(conn)
-	if err != nil {
-		fmt.Printf("[Spawn] Failed to read auth response from %s: %v\n", c.constructServerAddr, err)
-		return
-	}
+	c.spawnedMu.Lock()
+	c.spawnedCubes = append(c.spawnedCubes, resolveCubeID(cube.Name))
+	c.spawnedMu.Unlock()
+	return nil
+}
 
-	// Check for NaN or Inf in cube.Position
-	for i, coord := range cube.Position {
-		if math.IsNaN(coord) || math.IsInf(coord, 0) {
-			fmt.Printf("[Spawn] Invalid position for cube %s: coordinate %d is %v\n", cube.Name, i, coord)
-			return
-		}
-	}
+// SpawnedCubes returns the server names of the cubes this construct spawned, in spawn completion order.
+func (c *Construct) SpawnedCubes() []string {
+	c.spawnedMu.Lock()
+	defer c.spawnedMu.Unlock()
+	return append([]string(nil), c.spawnedCubes...)
+}
 
-	spawn := Message{
-		"type":      "spawn_cube",
-		"cube_name": cube.Name,
-		"position":  cube.Position,
-		"rotation":  []float64{0, 0, 0},
-		"is_base":   true,
-	}
-	if err := sendJSONMessage(conn, spawn); err != nil {
-		fmt.Printf("[Spawn] Failed to spawn cube on %s: %v\n", c.constructServerAddr, err)
-		return
+// log returns the construct's logger.
+func (c *Construct) log() Logger {
+	return loggerOrDefault(c.Logger)
+}
+
+// metrics returns the construct's metrics sink.
+func (c *Construct) metrics() Metrics {
+	return metricsOrDefault(c.Metrics)
+}
+
+// connect dials the construct's server and authenticates with its credentials.
+// In dry-run mode it returns a connection that records every command that would change the world.
+func (c *Construct) connect() (net.Conn, string, error) {
+	if c.DryRun {
+		return dialDryRun(context.Background(), c.constructServerAddr, c.constructAuthPass, c.constructDelimiter, c.DryRunForwardReads, &c.dryRun, c.log())
 	}
+	return dialAndAuth(c.constructServerAddr, c.constructAuthPass, c.constructDelimiter)
+}
 
-	fullCubeName := cube.Name + "_BASE"
-	cubeListMutex.Lock()
-	globalCubeList = append(globalCubeList, fullCubeName)
-	cubeListMutex.Unlock()
+// DryRunMessages returns the commands the construct would have sent while DryRun was set.
+func (c *Construct) DryRunMessages() []string {
+	return c.dryRun.Messages()
 }
 
 // linkCubeChainsWithConfig links cube chains using the Construct's server configuration.
-func (c *Construct) linkCubeChainsWithConfig(chains [][]string, jointType string, jointParams map[string]float64) error {
-	conn, err := net.Dial("tcp", c.constructServerAddr)
+// Chains are resolved to server cube IDs and linked in one pass per joint type, each chain with
+// its own joint type or the config's default. Pairs that are already linked are skipped, so
+// running it again is safe.
+//
+// Every joint is created with jointParams. Links listed in Config.JointOverrides are then updated
+// with jointParams merged with their override, so an override value always wins over the base
+// value for the same key and base keys the override leaves out still apply.
+func (c *Construct) linkCubeChainsWithConfig(chains []ChainSpec, jointParams map[string]float64) (LinkReport, error) {
+	conn, authResp, err := c.connect()
 	if err != nil {
-		return fmt.Errorf("[linkCubeChains] Failed to connect to %s: %v", c.constructServerAddr, err)
+		return LinkReport{}, fmt.Errorf("[linkCubeChains] %v", err)
 	}
 	defer conn.Close()
+	c.log().Debugf("[linkCubeChains] Auth response from %s: %s", c.constructServerAddr, authResp)
 
-	if _, err := conn.Write([]byte(c.constructAuthPass + c.constructDelimiter)); err != nil {
-		return fmt.Errorf("[linkCubeChains] Auth write error to %s: %v", c.constructServerAddr, err)
+	// Group the chains by joint type, keeping the order in which types first appear
+	var jointTypes []string
+	byType := make(map[string][][]string)
+	for _, chain := range chains {
+		jointType := c.Config.chainJointType(chain)
+		if _, ok := byType[jointType]; !ok {
+			jointTypes = append(jointTypes, jointType)
+		}
+		byType[jointType] = append(byType[jointType], chain.Names)
 	}
 
-	authResp, err := readResponse(conn)
-	if err != nil {
-		return fmt.Errorf("[linkCubeChains] Failed to read auth response from %s: %v", c.constructServerAddr, err)
+	var report LinkReport
+	for _, jointType := range jointTypes {
+		typeReport, err := sendLinkChains(conn, resolveChains(byType[jointType]), jointType, jointParams, c.LinkBatchSize, c.log())
+		report.Created += typeReport.Created
+		report.Skipped += typeReport.Skipped
+		report.Batches = append(report.Batches, typeReport.Batches...)
+		if err != nil {
+			return report, fmt.Errorf("%v (joint type %s, server %s)", err, jointType, c.constructServerAddr)
+		}
 	}
-	fmt.Printf("[linkCubeChains] Auth response from %s: %s\n", c.constructServerAddr, authResp)
-
-	cmd := Message{
-		"type":         "link_cube_chains",
-		"chains":       chains,
-		"joint_type":   jointType,
-		"joint_params": jointParams,
-	}
-
-	if err := sendJSONMessage(conn, cmd); err != nil {
-		return fmt.Errorf("[linkCubeChains] Failed to send command to %s: %v", c.constructServerAddr, err)
+	if err := c.applyJointOverrides(conn, jointParams); err != nil {
+		return report, fmt.Errorf("%v (server %s)", err, c.constructServerAddr)
 	}
+	return report, nil
+}
 
-	resp, err := readResponse(conn)
-	if err != nil {
-		return fmt.Errorf("[linkCubeChains] Error reading response from %s: %v", c.constructServerAddr, err)
+// applyJointOverrides sends the merged parameters of every overridden link in the config's chains.
+func (c *Construct) applyJointOverrides(conn net.Conn, jointParams map[string]float64) error {
+	if len(c.Config.JointOverrides) == 0 {
+		return nil
 	}
-	fmt.Printf("[linkCubeChains] Server response from %s: %s\n", c.constructServerAddr, resp)
-
-	linkListMutex.Lock()
-	defer linkListMutex.Unlock()
-	for _, chain := range chains {
+	for _, spec := range c.Config.Chains {
+		chain := spec.Names
 		for i := 0; i < len(chain)-1; i++ {
-			cubeA := chain[i]
-			cubeB := chain[i+1]
-			jointName := fmt.Sprintf("joint_%s_%s_%s", jointType, cubeA, cubeB)
-			globalCubeLinks = append(globalCubeLinks, CubeLink{
-				JointName: jointName,
-				CubeA:     cubeA,
-				CubeB:     cubeB,
-			})
+			override, ok := c.Config.jointOverride(chain[i], chain[i+1])
+			if !ok {
+				continue
+			}
+			cubeA, cubeB := resolveCubeID(chain[i]), resolveCubeID(chain[i+1])
+			linkListMutex.Lock()
+			jointName, linked := linkedJointName(cubeA, cubeB)
+			linkListMutex.Unlock()
+			if !linked && isDryRun(conn) {
+				// Dry-run links are not tracked; preview the name the server would have used
+				jointName, linked = fmt.Sprintf("joint_%s_%s_%s", c.Config.chainJointType(spec), cubeA, cubeB), true
+			}
+			if !linked {
+				return fmt.Errorf("[linkCubeChains] No joint tracked between %s and %s for override", cubeA, cubeB)
+			}
+
+			merged := make(map[string]float64, len(jointParams)+len(override))
+			for key, value := range jointParams {
+				merged[key] = value
+			}
+			for key, value := range override {
+				merged[key] = value
+			}
+			if _, err := sendCommand(conn, Message{
+				"type":       "set_joint_params",
+				"joint_name": jointName,
+				"params":     merged,
+			}); err != nil {
+				return fmt.Errorf("[linkCubeChains] Failed to apply override to joint %s: %v", jointName, err)
+			}
+			trackJointParams(jointName, merged)
 		}
 	}
-
 	return nil
 }
 
-// Spawn spawns the construct at the specified orbit position around the planet.
+// Spawn spawns the construct at the specified orbit position around the planet. If it fails part
+// way, the cubes already spawned stay in place and are listed by SpawnedCubes; call Despawn to remove them.
 func (c *Construct) Spawn(orbitPosition []float64, planetCenter []float64) error {
-	fmt.Printf("\n🚀 Spawning unit: %s at planet center (%.2f, %.2f, %.2f)\n",
+	if len(c.Config.Cubes) == 0 {
+		return fmt.Errorf("❌ Construct %s has no cubes to spawn", c.unitName)
+	}
+	if err := validatePositions([][]float64{orbitPosition, planetCenter}); err != nil {
+		return fmt.Errorf("❌ Invalid orbit position or planet center for %s: %v", c.unitName, err)
+	}
+
+	if floating := c.FloatingCubes(); len(floating) > 0 {
+		c.log().Warnf("⚠️ Construct %s has cubes in no chain, they will not be linked: %v", c.unitName, floating)
+	}
+
+	c.log().Infof("🚀 Spawning unit: %s at planet center (%.2f, %.2f, %.2f)",
 		c.unitName, planetCenter[0], planetCenter[1], planetCenter[2])
 
 	// Create a copy of the cubes to adjust their positions
 	adjustedCubes := make([]Cube, len(c.Config.Cubes))
 	for i, cube := range c.Config.Cubes {
 		adjustedCubes[i] = Cube{
-			Name:     cube.Name,
-			Position: make([]float64, 3),
+			Name:           cube.Name,
+			Position:       make([]float64, 3),
+			Rotation:       cube.Rotation,
+			CollisionGroup: cube.CollisionGroup,
+			CollisionMask:  cube.CollisionMask,
 		}
 		copy(adjustedCubes[i].Position, cube.Position)
 	}
@@ -252,7 +541,7 @@ func (c *Construct) Spawn(orbitPosition []float64, planetCenter []float64) error
 		adjustedCubes[i].Position[2] = orbitPosition[2] + relZ
 
 		// Log the adjusted position for debugging
-		fmt.Printf("Adjusted position for cube %s: [%.2f, %.2f, %.2f]\n",
+		c.log().Debugf("Adjusted position for cube %s: [%.2f, %.2f, %.2f]",
 			adjustedCubes[i].Name,
 			adjustedCubes[i].Position[0],
 			adjustedCubes[i].Position[1],
@@ -260,6 +549,20 @@ func (c *Construct) Spawn(orbitPosition []float64, planetCenter []float64) error
 		)
 	}
 
+	// Abort before spawning anything if the position math produced NaN or Inf
+	adjustedPositions := make([][]float64, len(adjustedCubes))
+	for i, cube := range adjustedCubes {
+		adjustedPositions[i] = cube.Position
+	}
+	if err := validatePositions(adjustedPositions); err != nil {
+		return fmt.Errorf("❌ Invalid computed cube positions for %s: %v", c.unitName, err)
+	}
+	for _, cube := range adjustedCubes {
+		if err := validateRotation(cube.Rotation); err != nil {
+			return fmt.Errorf("❌ Invalid rotation for cube %s: %v", cube.Name, err)
+		}
+	}
+
 	// Calculate the angle in the XZ plane for logging, with a fallback for zero displacement
 	dx := orbitPosition[0] - planetCenter[0]
 	dz := orbitPosition[2] - planetCenter[2]
@@ -267,7 +570,7 @@ func (c *Construct) Spawn(orbitPosition []float64, planetCenter []float64) error
 	if dx != 0 || dz != 0 {
 		angle = math.Atan2(dz, dx) * (180.0 / math.Pi)
 	} else {
-		fmt.Println("Warning: Orbit position coincides with planet center, angle set to 0 degrees")
+		c.log().Warnf("Warning: Orbit position coincides with planet center, angle set to 0 degrees")
 	}
 
 	// Calculate the radius from the planet center to the orbit position
@@ -277,40 +580,404 @@ func (c *Construct) Spawn(orbitPosition []float64, planetCenter []float64) error
 			(orbitPosition[2]-planetCenter[2])*(orbitPosition[2]-planetCenter[2]),
 	)
 
-	fmt.Printf("🪐 Orbiting construct %s around planet at radius %.2f with angle %.2f degrees\n",
+	c.log().Infof("🪐 Orbiting construct %s around planet at radius %.2f with angle %.2f degrees",
 		c.unitName, radius, angle)
 
-	// Record the orbit position as occupied
+	// Record the orbit position as occupied, replacing any reservation made for this unit
 	positionMutex.Lock()
-	occupiedPositions = append(occupiedPositions, occupiedPosition{
-		Position: orbitPosition,
-		UnitName: c.unitName,
-	})
+	occupied := occupiedPosition{Position: orbitPosition, UnitName: c.unitName}
+	reserved := false
+	for i := range occupiedPositions {
+		if occupiedPositions[i].UnitName == c.unitName {
+			occupiedPositions[i], reserved = occupied, true
+			break
+		}
+	}
+	if !reserved {
+		occupiedPositions = append(occupiedPositions, occupied)
+	}
 	positionMutex.Unlock()
 
 	// Step 1: Spawn all cubes concurrently with adjusted positions
+	c.spawnedMu.Lock()
+	c.spawnedCubes = nil
+	c.spawnedMu.Unlock()
 	var wg sync.WaitGroup
+	var spawnMu sync.Mutex
+	var spawnErrs []error
+	spawned := make([]Cube, 0, len(adjustedCubes))
 	wg.Add(len(adjustedCubes))
 	for _, cube := range adjustedCubes {
-		go c.spawnCubeWithConfig(cube, &wg)
+		go func(cube Cube) {
+			defer wg.Done()
+			err := c.spawnCubeWithConfig(cube)
+			spawnMu.Lock()
+			defer spawnMu.Unlock()
+			if err != nil {
+				spawnErrs = append(spawnErrs, err)
+				return
+			}
+			spawned = append(spawned, cube)
+		}(cube)
 	}
 	wg.Wait()
-	fmt.Printf("✅ Construct %s spawned\n", c.unitName)
+	c.spawned = spawned
+	if len(spawnErrs) > 0 {
+		return fmt.Errorf("❌ %d of %d cubes failed to spawn for %s, first: %v", len(spawnErrs), len(adjustedCubes), c.unitName, spawnErrs[0])
+	}
+	c.log().Infof("✅ Construct %s spawned", c.unitName)
 
 	// Step 2: Link the cubes using the specified chains
-	adjustedChains := make([][]string, len(c.Config.Chains))
-	for i, chain := range c.Config.Chains {
-		adjustedChains[i] = make([]string, len(chain))
-		for j, cubeName := range chain {
-			adjustedChains[i][j] = cubeName + "_BASE"
+	report, err := c.linkCubeChainsWithConfig(c.Config.Chains, c.Config.JointParams)
+	if err != nil {
+		return fmt.Errorf("❌ Error linking cubes for %s: %v", c.unitName, err)
+	}
+	c.log().Infof("🔗 Construct %s linked (%d joints created, %d skipped)", c.unitName, report.Created, report.Skipped)
+
+	// Step 3: Ride on the parent cube, if one is configured
+	if c.ParentCube != "" {
+		if err := c.attachToParent(); err != nil {
+			return fmt.Errorf("❌ Error attaching %s to parent %s: %v", c.unitName, c.ParentCube, err)
 		}
+		c.log().Infof("📎 Construct %s attached to %s", c.unitName, c.ParentCube)
 	}
 
-	if err := c.linkCubeChainsWithConfig(adjustedChains, c.Config.JointType, c.Config.JointParams); err != nil {
-		return fmt.Errorf("❌ Error linking cubes for %s: %v", c.unitName, err)
+	return nil
+}
+
+// Reset returns a spawned construct to the pose it was spawned in without despawning it:
+// it freezes the cubes, moves each back to its spawn transform, zeroes joint motor velocities,
+// and unfreezes. Server-side cube and joint identities are preserved.
+func (c *Construct) Reset() error {
+	if len(c.spawned) == 0 {
+		return fmt.Errorf("[Reset] construct %s has not been spawned", c.unitName)
+	}
+
+	conn, _, err := c.connect()
+	if err != nil {
+		return fmt.Errorf("[Reset] %v", err)
+	}
+	defer conn.Close()
+
+	cubeNames := make(map[string]bool, len(c.spawned))
+	for _, name := range c.SpawnedCubes() {
+		cubeNames[name] = true
+	}
+
+	// Step 1: Freeze every cube so nothing moves while it is repositioned
+	for name := range cubeNames {
+		if err := sendFreeze(conn, name, true); err != nil {
+			return fmt.Errorf("[Reset] Failed to freeze %s: %v", name, err)
+		}
+	}
+
+	// Step 2: Move every cube back to its spawn transform
+	for _, cube := range c.spawned {
+		name := resolveCubeID(cube.Name)
+		if _, err := sendCommand(conn, Message{
+			"type":      "set_cube_transform",
+			"cube_name": name,
+			"position":  cube.Position,
+			"rotation":  cubeRotation(cube),
+		}); err != nil {
+			return fmt.Errorf("[Reset] Failed to reset transform of %s: %v", name, err)
+		}
+	}
+
+	// Step 3: Stop every joint motor that belongs to this construct
+	linkListMutex.Lock()
+	var joints []string
+	for _, link := range globalCubeLinks {
+		if cubeNames[link.CubeA] || cubeNames[link.CubeB] {
+			joints = append(joints, link.JointName)
+		}
+	}
+	linkListMutex.Unlock()
+	for _, joint := range joints {
+		if _, err := sendCommand(conn, Message{
+			"type":       "set_joint_params",
+			"joint_name": joint,
+			"params":     map[string]float64{"motor_target_velocity": 0.0},
+		}); err != nil {
+			return fmt.Errorf("[Reset] Failed to zero velocity of joint %s: %v", joint, err)
+		}
+	}
+
+	// Step 4: Unfreeze so the next episode can start
+	for name := range cubeNames {
+		if err := sendFreeze(conn, name, false); err != nil {
+			return fmt.Errorf("[Reset] Failed to unfreeze %s: %v", name, err)
+		}
+	}
+
+	c.log().Infof("🔄 Construct %s reset to its spawn pose", c.unitName)
+	return nil
+}
+
+// RotateToFace turns a spawned construct about the vertical axis through its centroid so that its
+// local +X axis points away from planetCenter. Each cube is moved to its rotated position and its
+// yaw is increased by the same angle, over one connection. Angles are in degrees, measured in the
+// XZ plane from +X toward +Z, as returned by calculateRotationOutward.
+func (c *Construct) RotateToFace(planetCenter []float64) error {
+	if len(c.spawned) == 0 {
+		return fmt.Errorf("[RotateToFace] construct %s has not been spawned", c.unitName)
+	}
+	if err := validatePositions([][]float64{planetCenter}); err != nil {
+		return fmt.Errorf("[RotateToFace] invalid planet center: %v", err)
+	}
+
+	centroid, yaw := c.outwardYaw(planetCenter)
+	sin, cos := math.Sincos(yaw * math.Pi / 180)
+
+	conn, _, err := c.connect()
+	if err != nil {
+		return fmt.Errorf("[RotateToFace] %v", err)
+	}
+	defer conn.Close()
+
+	for _, cube := range c.spawned {
+		dx := cube.Position[0] - centroid[0]
+		dz := cube.Position[2] - centroid[2]
+		position := []float64{
+			centroid[0] + dx*cos - dz*sin,
+			cube.Position[1],
+			centroid[2] + dx*sin + dz*cos,
+		}
+		rotation := append([]float64(nil), cubeRotation(cube)...)
+		rotation[1] += yaw
+
+		name := resolveCubeID(cube.Name)
+		if _, err := sendCommand(conn, Message{
+			"type":      "set_cube_transform",
+			"cube_name": name,
+			"position":  position,
+			"rotation":  rotation,
+		}); err != nil {
+			return fmt.Errorf("[RotateToFace] Failed to rotate %s: %v", name, err)
+		}
+	}
+
+	c.log().Infof("🧭 Construct %s turned %.2f degrees to face away from the planet", c.unitName, yaw)
+	return nil
+}
+
+// outwardYaw returns the centroid of the spawned cubes and the yaw, in degrees, that points
+// from planetCenter through it.
+func (c *Construct) outwardYaw(planetCenter []float64) ([3]float64, float64) {
+	var centroid [3]float64
+	for _, cube := range c.spawned {
+		centroid[0] += cube.Position[0]
+		centroid[1] += cube.Position[1]
+		centroid[2] += cube.Position[2]
+	}
+	count := float64(len(c.spawned))
+	centroid[0] /= count
+	centroid[1] /= count
+	centroid[2] /= count
+	return centroid, calculateRotationOutward(planetCenter, centroid[:])
+}
+
+// Freeze freezes every cube this construct spawned on the construct's own server.
+func (c *Construct) Freeze() error {
+	return c.setFrozen(true)
+}
+
+// Unfreeze unfreezes every cube this construct spawned on the construct's own server.
+func (c *Construct) Unfreeze() error {
+	return c.setFrozen(false)
+}
+
+// setFrozen sends freeze_cube for every spawned cube, spread over at most defaultMaxWorkers
+// connections that each work through a shared queue, and reports how many cubes failed along with the first error.
+func (c *Construct) setFrozen(freeze bool) error {
+	op, done := "Unfreeze", "unfrozen"
+	if freeze {
+		op, done = "Freeze", "frozen"
+	}
+	names := c.SpawnedCubes()
+	if len(names) == 0 {
+		return fmt.Errorf("[%s] construct %s has no spawned cubes", op, c.unitName)
+	}
+
+	workers := defaultMaxWorkers
+	if len(names) < workers {
+		workers = len(names)
+	}
+	jobs := make(chan string, len(names))
+	for _, name := range names {
+		jobs <- name
+	}
+	close(jobs)
+	var wg sync.WaitGroup
+	var errMu sync.Mutex
+	var errs []error
+	var connErr error
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			conn, _, err := c.connect()
+			if err != nil {
+				// Leave the cubes to the other workers; any nobody sends are reported below
+				errMu.Lock()
+				connErr = err
+				errMu.Unlock()
+				return
+			}
+			defer conn.Close()
+			for name := range jobs {
+				if err := sendFreeze(conn, name, freeze); err != nil {
+					errMu.Lock()
+					errs = append(errs, fmt.Errorf("cube %s: %v", name, err))
+					errMu.Unlock()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	for name := range jobs {
+		errs = append(errs, fmt.Errorf("cube %s: %v", name, connErr))
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("[%s] %d of %d cubes failed for %s, first: %v", op, len(errs), len(names), c.unitName, errs[0])
+	}
+	c.log().Infof("🧊 Construct %s: %d cubes %s", c.unitName, len(names), done)
+	return nil
+}
+
+// FloatingCubes returns the names of cubes that appear in no chain and so will spawn unattached.
+// A single-cube construct has nothing to link to and never reports its cube as floating.
+func (c *Construct) FloatingCubes() []string {
+	if len(c.Config.Cubes) < 2 {
+		return nil
+	}
+	chained := make(map[string]bool)
+	for _, chain := range c.Config.Chains {
+		for _, name := range chain.Names {
+			chained[name] = true
+		}
+	}
+	var floating []string
+	for _, cube := range c.Config.Cubes {
+		if !chained[cube.Name] {
+			floating = append(floating, cube.Name)
+		}
+	}
+	return floating
+}
+
+// BoundingSphere returns the centroid of the configured cube positions and the largest distance
+// from it to any cube. A construct with no cubes has a zero center and radius.
+func (c *Construct) BoundingSphere() (center [3]float64, radius float64) {
+	if len(c.Config.Cubes) == 0 {
+		return center, 0
+	}
+	for _, cube := range c.Config.Cubes {
+		center[0] += cube.Position[0]
+		center[1] += cube.Position[1]
+		center[2] += cube.Position[2]
+	}
+	count := float64(len(c.Config.Cubes))
+	center[0] /= count
+	center[1] /= count
+	center[2] /= count
+
+	for _, cube := range c.Config.Cubes {
+		if d := distance3(cube.Position, center[:]); d > radius {
+			radius = d
+		}
+	}
+	return center, radius
+}
+
+// SetColor colors every spawned cube of the construct with a "#RRGGBB" hex color over one connection.
+func (c *Construct) SetColor(hex string) error {
+	names := c.SpawnedCubes()
+	if len(names) == 0 {
+		return fmt.Errorf("[SetColor] construct %s has not been spawned", c.unitName)
+	}
+
+	conn, _, err := c.connect()
+	if err != nil {
+		return fmt.Errorf("[SetColor] %v", err)
+	}
+	defer conn.Close()
+
+	for _, name := range names {
+		colorMsg := Message{
+			"type":      "set_color",
+			"cube_name": name,
+			"hex":       hex,
+		}
+		if err := sendJSONMessage(conn, colorMsg); err != nil {
+			return fmt.Errorf("[SetColor] Failed to color cube %s: %v", name, err)
+		}
+	}
+	return nil
+}
+
+// attachToParent creates a fixed joint between the construct's base cube (the first cube in its
+// config) and ParentCube, so the whole construct moves with the parent. The parent must exist on the server.
+func (c *Construct) attachToParent() error {
+	if len(c.Config.Cubes) == 0 {
+		return fmt.Errorf("construct has no base cube")
+	}
+	baseCube := resolveCubeID(c.Config.Cubes[0].Name)
+
+	conn, _, err := c.connect()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	cubes, err := GetCubeList(conn)
+	if err != nil {
+		return err
+	}
+	found := false
+	for _, cube := range cubes {
+		if cube == c.ParentCube {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return fmt.Errorf("parent cube %s does not exist on %s", c.ParentCube, c.constructServerAddr)
+	}
+
+	linkListMutex.Lock()
+	alreadyLinked := isLinked(baseCube, c.ParentCube)
+	linkListMutex.Unlock()
+	if alreadyLinked {
+		return nil
+	}
+
+	jointName := fmt.Sprintf("joint_fixed_%s_%s", baseCube, c.ParentCube)
+	if err := sendJSONMessage(conn, Message{
+		"type":       "create_joint",
+		"cube1":      baseCube,
+		"cube2":      c.ParentCube,
+		"joint_type": "fixed",
+		"joint_name": jointName,
+	}); err != nil {
+		return fmt.Errorf("failed to send create_joint: %v", err)
+	}
+	resp, err := readResponse(conn)
+	if err != nil {
+		return fmt.Errorf("failed to read create_joint response: %v", err)
+	}
+	if err := responseError(resp); err != nil {
+		return err
 	}
-	fmt.Printf("🔗 Construct %s linked\n", c.unitName)
 
+	linkListMutex.Lock()
+	globalCubeLinks = append(globalCubeLinks, CubeLink{
+		JointName: jointName,
+		CubeA:     baseCube,
+		CubeB:     c.ParentCube,
+	})
+	linkListMutex.Unlock()
 	return nil
 }
 
@@ -323,8 +990,43 @@ func SpawnMultipleConstructs(
 	planetCenter []float64,
 	offset []float64,
 ) error {
-	// Clear occupied positions before starting
-	ClearOccupiedPositions()
+	return SpawnMultipleConstructsWithGenerator(numConstructs, role, domain, startGen, startVersion,
+		serverAddr, authPass, delimiter, jsonTemplatePath, planetCenter, offset, nil)
+}
+
+// SpawnMultipleConstructsWithGenerator is SpawnMultipleConstructs with a custom layout. A nil
+// generator packs constructs onto a sphere sized from the template and offset.
+func SpawnMultipleConstructsWithGenerator(
+	numConstructs int,
+	role, domain string,
+	startGen, startVersion int,
+	serverAddr, authPass, delimiter, jsonTemplatePath string,
+	planetCenter []float64,
+	offset []float64,
+	generator PositionGenerator,
+) error {
+	return SpawnMultipleConstructsWithOptions(numConstructs, role, domain, startGen, startVersion,
+		serverAddr, authPass, delimiter, jsonTemplatePath, planetCenter, offset, SpawnOptions{Generator: generator})
+}
+
+// SpawnOptions customizes SpawnMultipleConstructsWithOptions.
+type SpawnOptions struct {
+	Generator     PositionGenerator // Layout of the constructs (nil packs them onto a sphere)
+	ColorByIndex  bool              // Give each construct its own hue from an evenly spaced palette
+	MinSeparation float64           // Minimum distance from every other spawned construct (0 uses twice the construct's diameter)
+}
+
+// SpawnMultipleConstructsWithOptions is SpawnMultipleConstructs with a custom layout and coloring.
+func SpawnMultipleConstructsWithOptions(
+	numConstructs int,
+	role, domain string,
+	startGen, startVersion int,
+	serverAddr, authPass, delimiter, jsonTemplatePath string,
+	planetCenter []float64,
+	offset []float64,
+	opts SpawnOptions,
+) error {
+	generator := opts.Generator
 
 	// Load the JSON template to calculate the construct size
 	construct := NewConstruct(serverAddr, authPass, delimiter)
@@ -332,79 +1034,44 @@ func SpawnMultipleConstructs(
 	if err := construct.LoadConfigFromJSON(jsonTemplatePath, unitName); err != nil {
 		return fmt.Errorf("failed to load JSON template for sizing: %v", err)
 	}
-
-	// Calculate the construct's centroid and bounding sphere radius
-	var centroid [3]float64
-	for _, cube := range construct.Config.Cubes {
-		centroid[0] += cube.Position[0]
-		centroid[1] += cube.Position[1]
-		centroid[2] += cube.Position[2]
+	if len(construct.Config.Cubes) == 0 {
+		return fmt.Errorf("JSON template %s has no cubes", jsonTemplatePath)
+	}
+	templatePositions := make([][]float64, len(construct.Config.Cubes))
+	for i, cube := range construct.Config.Cubes {
+		templatePositions[i] = cube.Position
+	}
+	if err := validatePositions(templatePositions); err != nil {
+		return fmt.Errorf("invalid cube positions in JSON template %s: %v", jsonTemplatePath, err)
+	}
+	if err := validatePositions([][]float64{planetCenter, offset}); err != nil {
+		return fmt.Errorf("invalid planet center or offset: %v", err)
 	}
-	count := float64(len(construct.Config.Cubes))
-	centroid[0] /= count
-	centroid[1] /= count
-	centroid[2] /= count
 
-	maxDistance := 0.0
-	for _, cube := range construct.Config.Cubes {
-		dx := cube.Position[0] - centroid[0]
-		dy := cube.Position[1] - centroid[1]
-		dz := cube.Position[2] - centroid[2]
-		distance := math.Sqrt(dx*dx + dy*dy + dz*dz)
-		if distance > maxDistance {
-			maxDistance = distance
-		}
+	radius, minDistance := orbitLayout(construct, offset)
+	if opts.MinSeparation > 0 {
+		minDistance = opts.MinSeparation
 	}
 
-	// Use the offset magnitude as the base radius of the orbit
-	radius := math.Sqrt(offset[0]*offset[0] + offset[1]*offset[1] + offset[2]*offset[2])
-	if radius == 0 {
-		radius = maxDistance * 2 // Default radius if offset is zero
+	// By default, pack as many non-overlapping positions as possible onto the orbit sphere
+	if generator == nil {
+		generator = PackedGenerator{Radius: radius, MinDist: minDistance}
 	}
-	// Ensure the orbit radius is large enough to accommodate the construct
-	radius += maxDistance
 
-	// Generate positions for all constructs using fibonacciSphere
-	positions := fibonacciSphere(numConstructs, radius, planetCenter)
-	if len(positions) != numConstructs {
-		return fmt.Errorf("fibonacciSphere returned %d positions, expected %d", len(positions), numConstructs)
-	}
-
-	// Define a minimum distance threshold to avoid overlaps (e.g., 2x the construct's diameter)
-	minDistance := maxDistance * 4
-
-	// Validate positions to ensure they are not too close
-	availablePositions := make([][]float64, 0, numConstructs)
-	usedPositions := make([][]float64, 0, numConstructs)
-	for _, pos := range positions {
-		tooClose := false
-		for _, usedPos := range usedPositions {
-			dx := pos[0] - usedPos[0]
-			dy := pos[1] - usedPos[1]
-			dz := pos[2] - usedPos[2]
-			distance := math.Sqrt(dx*dx + dy*dy + dz*dz)
-			if distance < minDistance {
-				tooClose = true
-				break
-			}
-		}
-		if !tooClose {
-			availablePositions = append(availablePositions, pos)
-			usedPositions = append(usedPositions, pos)
-		}
+	unitNames := make([]string, numConstructs)
+	for i := range unitNames {
+		unitNames[i] = generateUnitID(role, domain, startGen+i/100, startVersion+i%100)
 	}
 
-	if len(availablePositions) < numConstructs {
-		return fmt.Errorf("not enough unique positions: got %d, need %d", len(availablePositions), numConstructs)
+	availablePositions, err := reservePositions(generator, planetCenter, minDistance, unitNames)
+	if err != nil {
+		return err
 	}
 
 	// Spawn constructs at the assigned positions
 	var wg sync.WaitGroup
 	wg.Add(numConstructs)
-	unitNames := make([]string, numConstructs)
-
 	for i := 0; i < numConstructs; i++ {
-		unitNames[i] = generateUnitID(role, domain, startGen+i/100, startVersion+i%100)
 		go func(idx int) {
 			defer wg.Done()
 
@@ -413,19 +1080,26 @@ func SpawnMultipleConstructs(
 
 			// Load the JSON template with the unique unitName
 			if err := construct.LoadConfigFromJSON(jsonTemplatePath, unitNames[idx]); err != nil {
-				fmt.Printf("❌ Failed to load config for %s: %v\n", unitNames[idx], err)
+				DefaultLogger.Errorf("❌ Failed to load config for %s: %v", unitNames[idx], err)
 				return
 			}
 
 			// Spawn the construct at the assigned position
 			if err := construct.Spawn(availablePositions[idx], planetCenter); err != nil {
-				fmt.Printf("❌ Failed to spawn construct %s: %v\n", unitNames[idx], err)
+				DefaultLogger.Errorf("❌ Failed to spawn construct %s: %v", unitNames[idx], err)
 				return
 			}
 
+			// Color the construct so it stands out from its neighbours
+			if opts.ColorByIndex {
+				if err := construct.SetColor(paletteColor(idx, numConstructs)); err != nil {
+					DefaultLogger.Warnf("⚠️ Failed to color construct %s: %v", unitNames[idx], err)
+				}
+			}
+
 			// Unfreeze the construct
 			targetedUnfreezeAllCubes(unitNames[idx])
-			fmt.Printf("🌀 Construct %s unfrozen\n", unitNames[idx])
+			DefaultLogger.Infof("🌀 Construct %s unfrozen", unitNames[idx])
 		}(i)
 	}
 
@@ -437,10 +1111,89 @@ func SpawnMultipleConstructs(
 		time.Sleep(500 * time.Millisecond)
 	}
 
-	fmt.Println("🧹 All constructs despawned, simulation complete.")
+	DefaultLogger.Infof("🧹 All constructs despawned, simulation complete.")
 	return nil
 }
 
+// orbitLayout sizes the orbit sphere for copies of a construct from its bounding sphere: the
+// radius is the offset's length (or twice the construct's radius if it is zero) plus room for the
+// construct, and neighbours stay twice the construct's diameter apart.
+func orbitLayout(construct *Construct, offset []float64) (radius, minDistance float64) {
+	_, maxDistance := construct.BoundingSphere()
+
+	// Use the offset magnitude as the base radius of the orbit
+	radius = math.Sqrt(offset[0]*offset[0] + offset[1]*offset[1] + offset[2]*offset[2])
+	if radius == 0 {
+		radius = maxDistance * 2 // Default radius if offset is zero
+	}
+	// Ensure the orbit radius is large enough to accommodate the construct
+	radius += maxDistance
+
+	return radius, maxDistance * 4
+}
+
+// reservePositions picks one position per unit name from generator around center, skipping
+// positions within minDistance of earlier spawns and asking for extra candidates to make up for
+// them. The chosen positions are recorded in occupiedPositions before the lock is released, so
+// concurrent callers cannot take them.
+func reservePositions(generator PositionGenerator, center []float64, minDistance float64, unitNames []string) ([][]float64, error) {
+	positionMutex.Lock()
+	defer positionMutex.Unlock()
+	occupied := make([][]float64, len(occupiedPositions))
+	for i, pos := range occupiedPositions {
+		occupied[i] = pos.Position
+	}
+	candidates := generator.Generate(len(unitNames)+len(occupied), center)
+	if err := validatePositions(candidates); err != nil {
+		return nil, fmt.Errorf("invalid computed orbit positions: %v", err)
+	}
+	positions := freePositions(candidates, occupied, minDistance, len(unitNames))
+	if len(positions) < len(unitNames) {
+		return nil, fmt.Errorf("not enough unique positions: got %d, need %d", len(positions), len(unitNames))
+	}
+	for i, pos := range positions {
+		occupiedPositions = append(occupiedPositions, occupiedPosition{Position: pos, UnitName: unitNames[i]})
+	}
+	return positions, nil
+}
+
+// freePositions returns up to n candidates that are at least minDistance from every occupied
+// position and from each other, in candidate order.
+func freePositions(candidates, occupied [][]float64, minDistance float64, n int) [][]float64 {
+	taken := append([][]float64(nil), occupied...)
+	free := make([][]float64, 0, n)
+	for _, candidate := range candidates {
+		if len(free) == n {
+			break
+		}
+		fits := true
+		for _, pos := range taken {
+			if distance3(candidate, pos) < minDistance {
+				fits = false
+				break
+			}
+		}
+		if fits {
+			free = append(free, candidate)
+			taken = append(taken, candidate)
+		}
+	}
+	return free
+}
+
+// releaseOccupiedPositions frees the positions recorded for a construct so later spawns may reuse them.
+func releaseOccupiedPositions(unitName string) {
+	positionMutex.Lock()
+	defer positionMutex.Unlock()
+	kept := occupiedPositions[:0]
+	for _, pos := range occupiedPositions {
+		if pos.UnitName != unitName {
+			kept = append(kept, pos)
+		}
+	}
+	occupiedPositions = kept
+}
+
 // ClearOccupiedPositions resets the list of occupied positions.
 func ClearOccupiedPositions() {
 	positionMutex.Lock()
@@ -0,0 +1,370 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// testConfigJSON is a small three-cube template used across the construct tests.
+const testConfigJSON = `{
+  "cubes": [
+    {"Name": "head", "Position": [0, 2, 0]},
+    {"Name": "body", "Position": [0, 1, 0]},
+    {"Name": "foot", "Position": [0, 0, 0]}
+  ],
+  "chains": [["head", "body", "foot"]],
+  "joint_type": "hinge",
+  "joint_params": {"motor_enable": 1, "motor_max_impulse": 1000}
+}`
+
+// newTestConstruct loads testConfigJSON as unitName on a construct pointed at addr.
+func newTestConstruct(t *testing.T, addr, unitName string) *Construct {
+	t.Helper()
+	c := NewConstruct(addr, authPass, delimiter)
+	c.Logger = NopLogger{}
+	if err := c.LoadConfigFromJSONString(testConfigJSON, unitName); err != nil {
+		t.Fatalf("LoadConfigFromJSONString: %v", err)
+	}
+	return c
+}
+
+func TestSpawnRejectsZeroCubeConfig(t *testing.T) {
+	quietPackage(t)
+	mock := newMockServer(t, replySuccess)
+	c := NewConstruct(mock.Addr(), authPass, delimiter)
+	c.Logger = NopLogger{}
+	c.Config = ConstructConfig{JointType: "hinge"}
+
+	err := c.Spawn([]float64{10, 0, 0}, []float64{0, 0, 0})
+	if err == nil || !strings.Contains(err.Error(), "no cubes") {
+		t.Fatalf("Spawn err = %v, want a no cubes error", err)
+	}
+	if mock.Dials() != 0 {
+		t.Errorf("Spawn dialed the server %d times, want 0", mock.Dials())
+	}
+}
+
+func TestSpawnRejectsDegeneratePositions(t *testing.T) {
+	quietPackage(t)
+	mock := newMockServer(t, replySuccess)
+	c := newTestConstruct(t, mock.Addr(), "unit")
+
+	for _, tc := range []struct {
+		name          string
+		orbit, center []float64
+	}{
+		{"NaN orbit", []float64{math.NaN(), 0, 0}, []float64{0, 0, 0}},
+		{"Inf center", []float64{10, 0, 0}, []float64{0, math.Inf(1), 0}},
+		{"short orbit", []float64{10, 0}, []float64{0, 0, 0}},
+	} {
+		if err := c.Spawn(tc.orbit, tc.center); err == nil {
+			t.Errorf("%s: Spawn succeeded, want an error", tc.name)
+		}
+	}
+	if mock.Dials() != 0 {
+		t.Errorf("Spawn dialed the server %d times, want 0", mock.Dials())
+	}
+}
+
+func TestSpawnMultipleConstructsRejectsDegenerateOffset(t *testing.T) {
+	quietPackage(t)
+	mock := newMockServer(t, replySuccess)
+
+	err := SpawnMultipleConstructs(2, "TEST", "d", 1, 1, mock.Addr(), authPass, delimiter,
+		"construct_config.json", []float64{0, 0, 0}, []float64{math.NaN(), 0, 0})
+	if err == nil || !strings.Contains(err.Error(), "offset") {
+		t.Fatalf("err = %v, want an invalid offset error", err)
+	}
+	if mock.Dials() != 0 {
+		t.Errorf("SpawnMultipleConstructs dialed the server %d times, want 0", mock.Dials())
+	}
+}
+
+func TestConstructConfigTranslate(t *testing.T) {
+	c := newTestConstruct(t, "127.0.0.1:1", "unit")
+	before := make([][]float64, len(c.Config.Cubes))
+	for i, cube := range c.Config.Cubes {
+		before[i] = append([]float64(nil), cube.Position...)
+	}
+	chains := fmt.Sprint(c.Config.Chains)
+
+	delta := [3]float64{1.5, -2, 10}
+	c.Config.Translate(delta)
+
+	for i, cube := range c.Config.Cubes {
+		for axis := 0; axis < 3; axis++ {
+			if want := before[i][axis] + delta[axis]; cube.Position[axis] != want {
+				t.Errorf("cube %s axis %d = %v, want %v", cube.Name, axis, cube.Position[axis], want)
+			}
+		}
+	}
+	if got := fmt.Sprint(c.Config.Chains); got != chains {
+		t.Errorf("chains changed from %s to %s", chains, got)
+	}
+}
+
+func TestValidateConfig(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		config string
+		want   string // Substring of the expected error, "" for a valid config
+	}{
+		{"valid", testConfigJSON, ""},
+		{"missing cube", `{"cubes":[{"Name":"a","Position":[0,0,0]}],"chains":[["a","ghost"]],"joint_type":"hinge"}`, "undeclared cube"},
+		{"short position", `{"cubes":[{"Name":"a","Position":[0,0]},{"Name":"b","Position":[1,0,0]}],"chains":[["a","b"]],"joint_type":"hinge"}`, "expected 3 components"},
+		{"no joint type", `{"cubes":[{"Name":"a","Position":[0,0,0]}],"chains":[]}`, "joint_type"},
+		{"stray override", `{"cubes":[{"Name":"a","Position":[0,0,0]},{"Name":"b","Position":[1,0,0]}],"chains":[["a"],["b"]],"joint_type":"hinge","joint_overrides":{"a->b":{"motor_enable":0}}}`, "does not match any link"},
+	} {
+		c := NewConstruct("127.0.0.1:1", authPass, delimiter)
+		err := c.LoadConfigFromJSONString(tc.config, "unit")
+		if tc.want == "" {
+			if err != nil {
+				t.Errorf("%s: %v", tc.name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: err = %v, want it to mention %q", tc.name, err, tc.want)
+		}
+	}
+}
+
+func TestValidateConfigAcceptsBaseSuffix(t *testing.T) {
+	c := newTestConstruct(t, "127.0.0.1:1", "unit")
+	c.Config.Chains = []ChainSpec{{Names: []string{"unit_head_BASE", "unit_body"}}}
+	if err := c.ValidateConfig(); err != nil {
+		t.Errorf("ValidateConfig: %v", err)
+	}
+}
+
+func TestLenientConfigSkipsValidation(t *testing.T) {
+	c := NewConstruct("127.0.0.1:1", authPass, delimiter)
+	c.LenientConfig = true
+	if err := c.LoadConfigFromJSONString(`{"cubes":[{"Name":"a","Position":[0,0,0]}],"chains":[["a","ghost"]]}`, "unit"); err != nil {
+		t.Errorf("lenient load: %v", err)
+	}
+}
+
+func TestSaveConfigToJSONRoundTrip(t *testing.T) {
+	const template = `{
+  "cubes": [
+    {"Name": "head", "Position": [0, 2, 0]},
+    {"Name": "body", "Position": [0, 1, 0], "Rotation": [0, 90, 0]},
+    {"Name": "foot", "Position": [0, 0, 0]}
+  ],
+  "chains": [["head", "body"], {"names": ["body", "foot"], "joint_type": "fixed"}],
+  "joint_type": "hinge",
+  "joint_params": {"motor_enable": 1, "motor_max_impulse": 1000},
+  "joint_overrides": {"head->body": {"motor_max_impulse": 50}}
+}`
+	original := NewConstruct("127.0.0.1:1", authPass, delimiter)
+	if err := original.LoadConfigFromJSONString(template, "first"); err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "saved.json")
+	if err := original.SaveConfigToJSON(path); err != nil {
+		t.Fatalf("SaveConfigToJSON: %v", err)
+	}
+
+	// Reloading under the same unit name gives back the same config
+	reloaded := NewConstruct("127.0.0.1:1", authPass, delimiter)
+	if err := reloaded.LoadConfigFromJSON(path, "first"); err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if !reflect.DeepEqual(reloaded.Config, original.Config) {
+		t.Errorf("reloaded config differs:\n got %+v\nwant %+v", reloaded.Config, original.Config)
+	}
+
+	// The saved file carries no unit prefix, so it loads under any unit name
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "first_") {
+		t.Errorf("saved template still contains the unit prefix:\n%s", data)
+	}
+	other := NewConstruct("127.0.0.1:1", authPass, delimiter)
+	if err := other.LoadConfigFromJSON(path, "second"); err != nil {
+		t.Fatalf("load under another unit name: %v", err)
+	}
+	if got := other.Config.Chains[1].Names[0]; got != "second_body" {
+		t.Errorf("chain cube = %s, want second_body", got)
+	}
+}
+
+func TestSpawnBatchesKeepMinSeparation(t *testing.T) {
+	quietPackage(t)
+	mock := newMockServerAt(t, serverAddr, replySuccess)
+	template := filepath.Join(t.TempDir(), "template.json")
+	if err := os.WriteFile(template, []byte(testConfigJSON), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	const minSeparation = 25
+	center, offset := []float64{0, 0, 0}, []float64{60, 0, 0}
+
+	// A construct spawned on its own stays put while both batches are placed around it
+	standalone := newTestConstruct(t, serverAddr, "standalone")
+	if err := standalone.Spawn([]float64{60, 0, 0}, center); err != nil {
+		t.Fatalf("Spawn: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, 2)
+	for i, role := range []string{"ALPHA", "BETA"} {
+		wg.Add(1)
+		go func(i int, role string) {
+			defer wg.Done()
+			errs[i] = SpawnMultipleConstructsWithOptions(3, role, "test.example", 1, 1, serverAddr, authPass, delimiter,
+				template, center, offset, SpawnOptions{MinSeparation: minSeparation})
+		}(i, role)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			t.Fatalf("SpawnMultipleConstructsWithOptions: %v", err)
+		}
+	}
+
+	// Every cube of a construct is shifted by the same amount, so the heads are as far apart as the constructs
+	var heads [][]float64
+	for _, msg := range mock.MessagesOfType("spawn_cube") {
+		if !strings.Contains(fmt.Sprint(msg["cube_name"]), "_head") {
+			continue
+		}
+		raw, _ := msg["position"].([]interface{})
+		pos := make([]float64, len(raw))
+		for i, v := range raw {
+			pos[i], _ = v.(float64)
+		}
+		heads = append(heads, pos)
+	}
+	if len(heads) != 7 {
+		t.Fatalf("spawned %d constructs, want 7", len(heads))
+	}
+	for i := range heads {
+		for j := i + 1; j < len(heads); j++ {
+			if d := distance3(heads[i], heads[j]); d < minSeparation {
+				t.Errorf("constructs at %v and %v are %.2f apart, want at least %d", heads[i], heads[j], d, minSeparation)
+			}
+		}
+	}
+}
+
+func TestRotateToFaceUsesOutwardYaw(t *testing.T) {
+	quietPackage(t)
+	mock := newMockServer(t, replySuccess)
+	c := NewConstruct(mock.Addr(), authPass, delimiter)
+	c.Logger = NopLogger{}
+	if err := c.LoadConfigFromJSONString(`{
+  "cubes": [{"Name": "left", "Position": [-1, 0, 0]}, {"Name": "right", "Position": [1, 0, 0], "Rotation": [0, 15, 0]}],
+  "chains": [["left", "right"]],
+  "joint_type": "fixed"
+}`, "turner"); err != nil {
+		t.Fatal(err)
+	}
+	center, orbit := []float64{0, 0, 0}, []float64{0, 0, 10}
+	if err := c.Spawn(orbit, center); err != nil {
+		t.Fatalf("Spawn: %v", err)
+	}
+
+	centroid, yaw := c.outwardYaw(center)
+	if want := calculateRotationOutward(center, orbit); yaw != want || want != 90 {
+		t.Fatalf("yaw = %v, want calculateRotationOutward's %v (90)", yaw, want)
+	}
+	if centroid != [3]float64{0, 0, 10} {
+		t.Errorf("centroid = %v, want the orbit position", centroid)
+	}
+
+	if err := c.RotateToFace(center); err != nil {
+		t.Fatalf("RotateToFace: %v", err)
+	}
+	want := map[string]struct{ position, rotation []float64 }{
+		"turner_left_BASE":  {[]float64{0, 0, 9}, []float64{0, 90, 0}},
+		"turner_right_BASE": {[]float64{0, 0, 11}, []float64{0, 105, 0}},
+	}
+	transforms := mock.MessagesOfType("set_cube_transform")
+	if len(transforms) != len(want) {
+		t.Fatalf("sent %d transforms, want %d", len(transforms), len(want))
+	}
+	for _, msg := range transforms {
+		w, ok := want[fmt.Sprint(msg["cube_name"])]
+		if !ok {
+			t.Errorf("transformed unexpected cube %v", msg["cube_name"])
+			continue
+		}
+		for field, expected := range map[string][]float64{"position": w.position, "rotation": w.rotation} {
+			got, _ := msg[field].([]interface{})
+			for i := range expected {
+				if v, _ := got[i].(float64); math.Abs(v-expected[i]) > 1e-9 {
+					t.Errorf("%v %s = %v, want %v", msg["cube_name"], field, got, expected)
+					break
+				}
+			}
+		}
+	}
+}
+
+func TestFreezeAndUnfreezeSendPerCubeMessages(t *testing.T) {
+	quietPackage(t)
+	mock := newMockServer(t, replySuccess)
+	c := newTestConstruct(t, mock.Addr(), "icy")
+	other := newTestConstruct(t, mock.Addr(), "warm")
+	for _, construct := range []*Construct{c, other} {
+		if err := construct.Spawn([]float64{100, 0, 0}, []float64{0, 0, 0}); err != nil {
+			t.Fatalf("Spawn: %v", err)
+		}
+	}
+
+	for _, freeze := range []bool{true, false} {
+		op := c.Unfreeze
+		if freeze {
+			op = c.Freeze
+		}
+		before := len(mock.MessagesOfType("freeze_cube"))
+		if err := op(); err != nil {
+			t.Fatalf("freeze=%v: %v", freeze, err)
+		}
+		sent := mock.MessagesOfType("freeze_cube")[before:]
+		names := make(map[string]bool)
+		for _, msg := range sent {
+			if msg["freeze"] != freeze {
+				t.Errorf("freeze=%v: sent %v", freeze, msg)
+			}
+			names[fmt.Sprint(msg["cube_name"])] = true
+		}
+		for _, cube := range c.SpawnedCubes() {
+			if !names[cube] {
+				t.Errorf("freeze=%v: no freeze_cube for %s", freeze, cube)
+			}
+		}
+		if len(sent) != 3 || len(names) != 3 {
+			t.Errorf("freeze=%v: sent %d messages for %d cubes, want one for each of the 3 cubes", freeze, len(sent), len(names))
+		}
+	}
+}
+
+func TestFreezeReportsErrorReplies(t *testing.T) {
+	quietPackage(t)
+	mock := newMockServer(t, func(msg string) string {
+		if messageType(msg) == "freeze_cube" {
+			return `{"type":"error","message":"cube is locked"}`
+		}
+		return replySuccess(msg)
+	})
+	c := newTestConstruct(t, mock.Addr(), "stuck")
+	if err := c.Spawn([]float64{100, 0, 0}, []float64{0, 0, 0}); err != nil {
+		t.Fatalf("Spawn: %v", err)
+	}
+	if err := c.Freeze(); err == nil || !strings.Contains(err.Error(), "3 of 3 cubes failed") {
+		t.Errorf("err = %v, want all 3 cubes reported", err)
+	}
+	if err := (&Construct{unitName: "empty"}).Freeze(); err == nil {
+		t.Error("froze a construct with no spawned cubes")
+	}
+}
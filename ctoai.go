@@ -3,17 +3,19 @@ package main
 import (
 	"fmt"
 	"sort"
+	"strconv"
 	"strings"
 )
 
 // GetChainsJointTable returns the chains, joint type, and joint parameters as a table.
-// Columns are: item1, item2, jointtype, followed by all joint parameters as key:value pairs.
+// Columns are: item1, item2, jointtype, followed by all joint parameters as key:value pairs,
+// with any JointOverrides for the link applied.
 // Each row is an array of strings, e.g., ["head", "body", "hinge", "limit_upper:0.0", ...].
 func (c *Construct) GetChainsJointTable() [][]string {
 	// First, count the total number of pairs in all chains
 	totalPairs := 0
-	for _, chain := range c.Config.Chains {
-		if len(chain) > 1 {
+	for _, spec := range c.Config.Chains {
+		if chain := spec.Names; len(chain) > 1 {
 			totalPairs += len(chain) - 1 // Number of pairs is len(chain) - 1
 		}
 	}
@@ -32,7 +34,8 @@ func (c *Construct) GetChainsJointTable() [][]string {
 
 	// Create the rows
 	rows := make([][]string, 0, totalPairs)
-	for _, chain := range c.Config.Chains {
+	for _, spec := range c.Config.Chains {
+		chain := spec.Names
 		// Skip chains with fewer than 2 elements
 		if len(chain) < 2 {
 			continue
@@ -43,13 +46,27 @@ func (c *Construct) GetChainsJointTable() [][]string {
 			item2 := chain[i+1]
 
 			// Start the row with item1, item2, and jointtype
-			row := []string{item1, item2, c.Config.JointType}
+			row := []string{item1, item2, c.Config.chainJointType(spec)}
 
-			// Append all joint parameters in sorted order
+			// Append all joint parameters in sorted order, then any keys only the override sets
+			override, _ := c.Config.jointOverride(item1, item2)
 			for _, key := range paramKeys {
 				value := c.Config.JointParams[key]
+				if v, ok := override[key]; ok {
+					value = v
+				}
 				row = append(row, fmt.Sprintf("%s:%g", key, value))
 			}
+			var extraKeys []string
+			for key := range override {
+				if _, ok := c.Config.JointParams[key]; !ok {
+					extraKeys = append(extraKeys, key)
+				}
+			}
+			sort.Strings(extraKeys)
+			for _, key := range extraKeys {
+				row = append(row, fmt.Sprintf("%s:%g", key, override[key]))
+			}
 
 			rows = append(rows, row)
 		}
@@ -73,10 +90,16 @@ func (c *Construct) GetCubesTable() [][]string {
 			z = fmt.Sprintf("%g", cube.Position[2])
 		}
 
-		// Default rotation to [0,0,0] since it's not stored in Cube
+		// Extract rotation components (rx, ry, rz), upright when unset
+		rotation := cubeRotation(cube)
 		rx := "0"
 		ry := "0"
 		rz := "0"
+		if len(rotation) == 3 {
+			rx = fmt.Sprintf("%g", rotation[0])
+			ry = fmt.Sprintf("%g", rotation[1])
+			rz = fmt.Sprintf("%g", rotation[2])
+		}
 
 		// Create the row with separate columns for x, y, z, rx, ry, rz
 		rows[i] = []string{cube.Name, x, y, z, rx, ry, rz}
@@ -99,10 +122,16 @@ func (c *Construct) PrintCubesTable() {
 			z = fmt.Sprintf("%g", cube.Position[2])
 		}
 
-		// Default rotation to [0,0,0] since it's not stored in Cube
+		// Extract rotation components (rx, ry, rz), upright when unset
+		rotation := cubeRotation(cube)
 		rx := "0"
 		ry := "0"
 		rz := "0"
+		if len(rotation) == 3 {
+			rx = fmt.Sprintf("%g", rotation[0])
+			ry = fmt.Sprintf("%g", rotation[1])
+			rz = fmt.Sprintf("%g", rotation[2])
+		}
 
 		// Create the row and print it
 		row := []string{cube.Name, x, y, z, rx, ry, rz}
@@ -110,53 +139,112 @@ func (c *Construct) PrintCubesTable() {
 	}
 }
 
-// PrintChainsJointTable prints the chains, joint type, and joint parameters as a table.
+// PrintChainsJointTable prints the rows of GetChainsJointTable, JointOverrides included.
 // Columns are: item1, item2, jointtype, followed by all joint parameters as key:value pairs.
 // Each row is printed as a comma-separated string, e.g., "head,body,hinge,limit_upper:0.0,...".
 func (c *Construct) PrintChainsJointTable() {
-	// Count the total number of pairs in all chains
-	totalPairs := 0
-	for _, chain := range c.Config.Chains {
-		if len(chain) > 1 {
-			totalPairs += len(chain) - 1
-		}
-	}
-
-	// If no pairs, print a message and return
-	if totalPairs == 0 {
+	rows := c.GetChainsJointTable()
+	if len(rows) == 0 {
 		fmt.Printf("Chains/Joint Table for %s: No chains with pairs to display.\n", c.unitName)
 		return
 	}
 
-	// Sort joint parameter keys for consistent ordering
-	paramKeys := make([]string, 0, len(c.Config.JointParams))
-	for key := range c.Config.JointParams {
-		paramKeys = append(paramKeys, key)
+	fmt.Printf("Chains/Joint Table for %s (item1,item2,jointtype,jointparams):\n", c.unitName)
+	for _, row := range rows {
+		fmt.Println(strings.Join(row, ","))
 	}
-	sort.Strings(paramKeys)
+}
 
-	fmt.Printf("Chains/Joint Table for %s (item1,item2,jointtype,jointparams):\n", c.unitName)
-	for _, chain := range c.Config.Chains {
-		// Skip chains with fewer than 2 elements
-		if len(chain) < 2 {
-			continue
+// ConstructFromCubeCSV rebuilds a construct config from the CSV forms of GetCubesTable and
+// GetChainsJointTable. Consecutive links that continue each other with the same joint type are
+// merged back into one chain, the first link's joint type and parameters become the config
+// defaults, parameters that differ on later links become JointOverrides, and an all-zero rotation
+// is left unset. The returned construct has no server; copy its
+// Config into one made with NewConstruct to spawn it.
+func ConstructFromCubeCSV(cubesCSV, chainsCSV string) (*Construct, error) {
+	cubeRows, err := LoadFromCSV(cubesCSV)
+	if err != nil {
+		return nil, fmt.Errorf("[ConstructFromCubeCSV] %v", err)
+	}
+	chainRows, err := LoadFromCSV(chainsCSV)
+	if err != nil {
+		return nil, fmt.Errorf("[ConstructFromCubeCSV] %v", err)
+	}
+
+	c := NewConstruct("", "", "")
+	for i, row := range cubeRows {
+		if len(row) != 7 {
+			return nil, fmt.Errorf("[ConstructFromCubeCSV] %s row %d has %d columns, expected name,x,y,z,rx,ry,rz", cubesCSV, i+1, len(row))
 		}
-		// Generate pairs from the chain
-		for i := 0; i < len(chain)-1; i++ {
-			item1 := chain[i]
-			item2 := chain[i+1]
+		values := make([]float64, 6)
+		for j, field := range row[1:] {
+			if values[j], err = strconv.ParseFloat(strings.TrimSpace(field), 64); err != nil {
+				return nil, fmt.Errorf("[ConstructFromCubeCSV] %s row %d column %d: %v", cubesCSV, i+1, j+2, err)
+			}
+		}
+		cube := Cube{Name: row[0], Position: values[:3]}
+		if values[3] != 0 || values[4] != 0 || values[5] != 0 {
+			cube.Rotation = values[3:]
+		}
+		c.Config.Cubes = append(c.Config.Cubes, cube)
+	}
 
-			// Start the row with item1, item2, and jointtype
-			row := []string{item1, item2, c.Config.JointType}
+	for i, row := range chainRows {
+		if len(row) < 3 {
+			return nil, fmt.Errorf("[ConstructFromCubeCSV] %s row %d has %d columns, expected item1,item2,jointtype,...", chainsCSV, i+1, len(row))
+		}
+		cubeA, cubeB, jointType := row[0], row[1], row[2]
+		params := make(map[string]float64, len(row)-3)
+		for _, param := range row[3:] {
+			key, value, ok := strings.Cut(param, ":")
+			if !ok {
+				return nil, fmt.Errorf("[ConstructFromCubeCSV] %s row %d: joint parameter %q is not key:value", chainsCSV, i+1, param)
+			}
+			if params[key], err = strconv.ParseFloat(value, 64); err != nil {
+				return nil, fmt.Errorf("[ConstructFromCubeCSV] %s row %d: joint parameter %s: %v", chainsCSV, i+1, key, err)
+			}
+		}
+		if i == 0 {
+			c.Config.JointType = jointType
+			c.Config.JointParams = params
+		} else if override, err := jointParamsOverride(c.Config.JointParams, params); err != nil {
+			return nil, fmt.Errorf("[ConstructFromCubeCSV] %s row %d: %v", chainsCSV, i+1, err)
+		} else if len(override) > 0 {
+			if c.Config.JointOverrides == nil {
+				c.Config.JointOverrides = make(map[string]map[string]float64)
+			}
+			c.Config.JointOverrides[jointOverrideKey(cubeA, cubeB)] = override
+		}
+		if jointType == c.Config.JointType {
+			jointType = ""
+		}
 
-			// Append all joint parameters in sorted order
-			for _, key := range paramKeys {
-				value := c.Config.JointParams[key]
-				row = append(row, fmt.Sprintf("%s:%g", key, value))
+		// Extend the previous chain when this link continues it
+		if n := len(c.Config.Chains); n > 0 {
+			last := &c.Config.Chains[n-1]
+			if last.Names[len(last.Names)-1] == cubeA && last.JointType == jointType {
+				last.Names = append(last.Names, cubeB)
+				continue
 			}
+		}
+		c.Config.Chains = append(c.Config.Chains, ChainSpec{Names: []string{cubeA, cubeB}, JointType: jointType})
+	}
+	return c, nil
+}
 
-			// Print the row
-			fmt.Println(strings.Join(row, ","))
+// jointParamsOverride returns the entries of params that differ from defaults. A link cannot drop
+// a default parameter, so one missing from params is an error.
+func jointParamsOverride(defaults, params map[string]float64) (map[string]float64, error) {
+	for key := range defaults {
+		if _, ok := params[key]; !ok {
+			return nil, fmt.Errorf("joint parameter %s is missing, every link must set the first link's parameters", key)
+		}
+	}
+	override := make(map[string]float64)
+	for key, value := range params {
+		if def, ok := defaults[key]; !ok || def != value {
+			override[key] = value
 		}
 	}
+	return override, nil
 }
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// saveTables writes the construct's cube and chain tables to CSV files and returns their paths.
+func saveTables(t *testing.T, c *Construct) (string, string) {
+	t.Helper()
+	dir := t.TempDir()
+	cubes, chains := filepath.Join(dir, "cubes.csv"), filepath.Join(dir, "links.csv")
+	if err := SaveToCSV(c.GetCubesTable(), cubes); err != nil {
+		t.Fatal(err)
+	}
+	if err := SaveToCSV(c.GetChainsJointTable(), chains); err != nil {
+		t.Fatal(err)
+	}
+	return cubes, chains
+}
+
+func TestConstructFromCubeCSVRoundTrip(t *testing.T) {
+	original := NewConstruct("127.0.0.1:1", authPass, delimiter)
+	if err := original.LoadConfigFromJSONString(`{
+  "cubes": [
+    {"Name": "head", "Position": [0, 2.5, 0]},
+    {"Name": "body", "Position": [0, 1, 0], "Rotation": [0, 90, 0]},
+    {"Name": "foot", "Position": [0.25, 0, -1]},
+    {"Name": "tail", "Position": [0, 1, -2]}
+  ],
+  "chains": [["head", "body", "foot"], {"names": ["body", "tail"], "joint_type": "fixed"}],
+  "joint_type": "hinge",
+  "joint_params": {"motor_enable": 1, "motor_max_impulse": 1000},
+  "joint_overrides": {"body->foot": {"motor_max_impulse": 50}}
+}`, "csv"); err != nil {
+		t.Fatal(err)
+	}
+
+	rebuilt, err := ConstructFromCubeCSV(saveTables(t, original))
+	if err != nil {
+		t.Fatalf("ConstructFromCubeCSV: %v", err)
+	}
+	if !reflect.DeepEqual(rebuilt.Config, original.Config) {
+		t.Errorf("rebuilt config differs:\n got %+v\nwant %+v", rebuilt.Config, original.Config)
+	}
+
+	// The rebuilt tables are the same as the originals
+	if !reflect.DeepEqual(rebuilt.GetChainsJointTable(), original.GetChainsJointTable()) {
+		t.Errorf("chain tables differ:\n got %v\nwant %v", rebuilt.GetChainsJointTable(), original.GetChainsJointTable())
+	}
+}
+
+func TestPrintChainsJointTableAppliesOverrides(t *testing.T) {
+	c := NewConstruct("127.0.0.1:1", authPass, delimiter)
+	if err := c.LoadConfigFromJSONString(`{
+  "cubes": [{"Name": "a", "Position": [0, 0, 0]}, {"Name": "b", "Position": [0, 1, 0]}, {"Name": "c", "Position": [0, 2, 0]}],
+  "chains": [["a", "b", "c"]],
+  "joint_type": "hinge",
+  "joint_params": {"motor_max_impulse": 1000},
+  "joint_overrides": {"b->c": {"motor_max_impulse": 50}}
+}`, "print"); err != nil {
+		t.Fatal(err)
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	c.PrintChainsJointTable()
+	os.Stdout = stdout
+	w.Close()
+	out, _ := io.ReadAll(r)
+
+	for _, row := range c.GetChainsJointTable() {
+		if line := strings.Join(row, ","); !strings.Contains(string(out), line+"\n") {
+			t.Errorf("printed %q, want the row %q", out, line)
+		}
+	}
+	if !strings.Contains(string(out), "print_b,print_c,hinge,motor_max_impulse:50\n") {
+		t.Errorf("printed %q, want the b->c override applied", out)
+	}
+}
+
+func TestConstructFromCubeCSVRejectsBadRows(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	cubes := write("cubes.csv", "a,0,0,0,0,0,0\nb,1,0,0,0,0,0\n")
+	for _, tc := range []struct {
+		name, cubes, chains, want string
+	}{
+		{"short cube row", write("short.csv", "a,0,0\n"), write("ok.csv", "a,b,hinge\n"), "columns"},
+		{"bad number", write("nan.csv", "a,x,0,0,0,0,0\n"), write("ok.csv", "a,b,hinge\n"), "column 2"},
+		{"bad param", cubes, write("param.csv", "a,b,hinge,motor_enable\n"), "key:value"},
+		{"dropped param", cubes, write("drop.csv", "a,b,hinge,motor_enable:1\nb,a,hinge\n"), "missing"},
+		{"missing file", cubes, filepath.Join(dir, "absent.csv"), "absent.csv"},
+	} {
+		if _, err := ConstructFromCubeCSV(tc.cubes, tc.chains); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: err = %v, want it to mention %q", tc.name, err, tc.want)
+		}
+	}
+}
@@ -1,7 +1,6 @@
 package main
 
 import (
-	"encoding/json"
 	"fmt"
 	"net"
 	"strings"
@@ -16,28 +15,26 @@ func targetedDespawnAllCubes(unitName string) {
 			wg.Add(1)
 			go func(name string) {
 				defer wg.Done()
-				conn, err := net.Dial("tcp", serverAddr)
+				conn, _, err := dialAndAuth(serverAddr, authPass, delimiter)
 				if err != nil {
-					fmt.Printf("[%s] [Despawn] Connection failed: %v\n", unitName, err)
+					DefaultLogger.Errorf("[%s] [Despawn] Connection failed: %v", unitName, err)
 					return
 				}
 				defer conn.Close()
 
-				if _, err := conn.Write([]byte(authPass + delimiter)); err != nil {
-					return
-				}
-				_, _ = readResponse(conn)
-
 				despawn := Message{
 					"type":      "despawn_cube",
 					"cube_name": name,
 				}
-				sendJSONMessage(conn, despawn)
+				if sendJSONMessage(conn, despawn) == nil {
+					DefaultMetrics.CubesDespawned(1)
+				}
 			}(cube)
 		}
 	}
 	wg.Wait()
-	fmt.Printf("🧹 [%s] All cubes despawned.\n", unitName)
+	releaseOccupiedPositions(unitName)
+	DefaultLogger.Infof("🧹 [%s] All cubes despawned.", unitName)
 }
 
 func despawnAllCubes() {
@@ -46,82 +43,88 @@ func despawnAllCubes() {
 		wg.Add(1)
 		go func(name string) {
 			defer wg.Done()
-			conn, err := net.Dial("tcp", serverAddr)
+			conn, _, err := dialAndAuth(serverAddr, authPass, delimiter)
 			if err != nil {
-				fmt.Println("[Despawn] Failed to connect:", err)
+				DefaultLogger.Errorf("[Despawn] Failed to connect: %v", err)
 				return
 			}
 			defer conn.Close()
 
-			if _, err := conn.Write([]byte(authPass + delimiter)); err != nil {
-				return
-			}
-			_, _ = readResponse(conn)
-
 			despawn := Message{
 				"type":      "despawn_cube",
 				"cube_name": name,
 			}
-			sendJSONMessage(conn, despawn)
+			if sendJSONMessage(conn, despawn) == nil {
+				DefaultMetrics.CubesDespawned(1)
+			}
 		}(cube)
 	}
 	wg.Wait()
 }
 
-// nukeAllCubes asks the server for ALL active cubes and despawns them brutally.
-func nukeAllCubes() {
-	conn, err := net.Dial("tcp", serverAddr)
+// nukeMaxRetries and nukeRetryDelay are the despawn passes and the pause after each pass that
+// callers of nukeAllCubes use unless they need something else.
+const (
+	nukeMaxRetries = 5
+	nukeRetryDelay = 500 * time.Millisecond
+)
+
+// nukeAllCubes asks the server for ALL active cubes and despawns them brutally, making up to
+// maxRetries passes and waiting delay after each so the server can process them. The cube list is
+// read once more after the last pass, and an error is returned with the number of cubes still
+// alive if the world is not empty. remaining is -1 when the cube list could not be read.
+// With DryRun set, nothing is despawned: the cube list is read once (from the server only with
+// DryRunForwardReads) and a despawn is recorded for each cube.
+func nukeAllCubes(maxRetries int, delay time.Duration) (remaining int, err error) {
+	conn, _, err := dialAndAuth(serverAddr, authPass, delimiter)
 	if err != nil {
-		fmt.Println("[Nuke] Failed to connect:", err)
-		return
+		return -1, fmt.Errorf("[Nuke] Failed to connect: %v", err)
 	}
 	defer conn.Close()
 
-	if _, err := conn.Write([]byte(authPass + delimiter)); err != nil {
-		fmt.Println("[Nuke] Failed to auth:", err)
-		return
-	}
-	_, _ = readResponse(conn)
-
-	maxRetries := 5
 	for attempt := 1; attempt <= maxRetries; attempt++ {
 		// Request all cubes
-		if err := sendJSONMessage(conn, Message{"type": "get_cube_list"}); err != nil {
-			fmt.Println("[Nuke] Failed to request cube list:", err)
-			return
-		}
-		raw, err := readResponse(conn)
+		cubes, err := GetCubeList(conn)
 		if err != nil {
-			fmt.Println("[Nuke] Failed to read cube list:", err)
-			return
-		}
-
-		var cubeData map[string]interface{}
-		if err := json.Unmarshal([]byte(raw), &cubeData); err != nil {
-			fmt.Println("[Nuke] JSON unmarshal error:", err)
-			return
+			return -1, fmt.Errorf("[Nuke] %v", err)
 		}
-
-		cubes := toStringArray(cubeData["cubes"])
 		if len(cubes) == 0 {
-			fmt.Println("[Nuke] All cubes cleared.")
-			break
+			DefaultLogger.Infof("[Nuke] All cubes cleared.")
+			return 0, nil
 		}
 
+		sent := 0
 		for _, cube := range cubes {
 			if err := sendJSONMessage(conn, Message{
 				"type":      "despawn_cube",
 				"cube_name": cube,
 			}); err != nil {
-				fmt.Printf("[Nuke] Failed to despawn cube %s: %v\n", cube, err)
+				DefaultLogger.Warnf("[Nuke] Failed to despawn cube %s: %v", cube, err)
+				continue
 			}
+			sent++
+		}
+		if isDryRun(conn) {
+			// Nothing was despawned, so further passes would only repeat the same preview
+			DefaultLogger.Infof("[Nuke] Dry run: would despawn %d cubes", sent)
+			return 0, nil
 		}
+		DefaultMetrics.CubesDespawned(sent)
 
-		fmt.Printf("[Nuke] NUKED %d cubes (pass %d)\n", len(cubes), attempt)
-		time.Sleep(500 * time.Millisecond) // Give server time to process
+		DefaultLogger.Infof("[Nuke] NUKED %d cubes (pass %d)", len(cubes), attempt)
+		time.Sleep(delay) // Give server time to process
 	}
 
-	fmt.Println("[Nuke] Finished.")
+	// Check what the last pass left behind
+	cubes, err := GetCubeList(conn)
+	if err != nil {
+		return -1, fmt.Errorf("[Nuke] %v", err)
+	}
+	if len(cubes) > 0 {
+		return len(cubes), fmt.Errorf("[Nuke] %d cubes remain after %d passes", len(cubes), maxRetries)
+	}
+	DefaultLogger.Infof("[Nuke] All cubes cleared.")
+	return 0, nil
 }
 
 // nukeAllCubes despawns all cubes across all pods.
@@ -134,55 +137,141 @@ func nukeAllCubePods() {
 		wg.Add(1)
 		go func(podHost string, podPort int) {
 			defer wg.Done()
-			serverAddr := fmt.Sprintf("%s:%d", podHost, podPort)
-			conn, err := net.Dial("tcp", serverAddr)
+			serverAddr := podAddr(podHost, podPort)
+			conn, _, err := dialAndAuth(serverAddr, authPass, delimiter)
 			if err != nil {
-				fmt.Printf("[Nuke] Failed to connect to %s: %v\n", serverAddr, err)
+				DefaultLogger.Errorf("[Nuke] Failed to connect to %s: %v", serverAddr, err)
 				return
 			}
 			defer conn.Close()
 
-			if _, err := conn.Write([]byte(authPass + delimiter)); err != nil {
-				fmt.Printf("[Nuke] Failed to auth on %s: %v\n", serverAddr, err)
-				return
-			}
-			_, _ = readResponse(conn)
-
+			batch := true // Cleared once the pod rejects despawn_cubes
 			maxRetries := 5
 			for attempt := 1; attempt <= maxRetries; attempt++ {
 				// Request all cubes
-				if err := sendJSONMessage(conn, Message{"type": "get_cube_list"}); err != nil {
-					fmt.Printf("[Nuke] Failed to request cube list on %s: %v\n", serverAddr, err)
-					return
-				}
-				raw, err := readResponse(conn)
+				cubes, err := GetCubeList(conn)
 				if err != nil {
-					fmt.Printf("[Nuke] Failed to read cube list on %s: %v\n", serverAddr, err)
+					DefaultLogger.Errorf("[Nuke] %v on %s", err, serverAddr)
 					return
 				}
-				var cubeData map[string]interface{}
-				if err := json.Unmarshal([]byte(raw), &cubeData); err != nil {
-					fmt.Printf("[Nuke] JSON unmarshal error on %s: %v\n", serverAddr, err)
-					return
-				}
-				cubes := toStringArray(cubeData["cubes"])
 				if len(cubes) == 0 {
-					fmt.Printf("[Nuke] All cubes cleared on %s.\n", serverAddr)
+					DefaultLogger.Infof("[Nuke] All cubes cleared on %s.", serverAddr)
 					break
 				}
+				if batch {
+					err := despawnCubesBatch(conn, cubes)
+					if err == nil {
+						DefaultMetrics.CubesDespawned(len(cubes))
+						DefaultLogger.Infof("[Nuke] NUKED %d cubes on %s (pass %d)", len(cubes), serverAddr, attempt)
+						continue // Acknowledged, so the next cube list already reflects it
+					}
+					DefaultLogger.Warnf("[Nuke] Batch despawn unavailable on %s (%v), despawning one by one", serverAddr, err)
+					batch = false
+				}
+				sent := 0
 				for _, cube := range cubes {
 					if err := sendJSONMessage(conn, Message{
 						"type":      "despawn_cube",
 						"cube_name": cube,
 					}); err != nil {
-						fmt.Printf("[Nuke] Failed to despawn cube %s on %s: %v\n", cube, serverAddr, err)
+						DefaultLogger.Warnf("[Nuke] Failed to despawn cube %s on %s: %v", cube, serverAddr, err)
+						continue
 					}
+					sent++
 				}
-				fmt.Printf("[Nuke] NUKED %d cubes on %s (pass %d)\n", len(cubes), serverAddr, attempt)
+				DefaultMetrics.CubesDespawned(sent)
+				DefaultLogger.Infof("[Nuke] NUKED %d cubes on %s (pass %d)", len(cubes), serverAddr, attempt)
 				time.Sleep(500 * time.Millisecond) // Give server time to process
 			}
 		}(pod.Host, pod.Port)
 	}
 	wg.Wait()
-	fmt.Println("[Nuke] Finished despawning across all pods.")
+	DefaultLogger.Infof("[Nuke] Finished despawning across all pods.")
+}
+
+// despawnCubesBatch despawns every cube with a single {"type": "despawn_cubes", "cube_names": [...]}
+// message and waits for the server to acknowledge it.
+func despawnCubesBatch(conn net.Conn, cubes []string) error {
+	if err := sendJSONMessage(conn, Message{
+		"type":       "despawn_cubes",
+		"cube_names": cubes,
+	}); err != nil {
+		return err
+	}
+	resp, err := readResponse(conn)
+	if err != nil {
+		return err
+	}
+	return responseError(resp)
+}
+
+// Despawn removes exactly the cubes this construct spawned, using its own server and credentials,
+// and forgets their tracked joints. Cubes that fail to despawn stay tracked so Despawn can be retried.
+func (c *Construct) Despawn() error {
+	names := c.SpawnedCubes()
+	if len(names) == 0 {
+		return fmt.Errorf("[Despawn] construct %s has no spawned cubes", c.unitName)
+	}
+
+	conn, _, err := c.connect()
+	if err != nil {
+		return fmt.Errorf("[Despawn] %v", err)
+	}
+	defer conn.Close()
+
+	removed := make(map[string]bool, len(names))
+	var remaining []string
+	var errs []error
+	for _, name := range names {
+		if err := sendJSONMessage(conn, Message{
+			"type":      "despawn_cube",
+			"cube_name": name,
+		}); err != nil {
+			errs = append(errs, fmt.Errorf("failed to despawn cube %s: %v", name, err))
+			remaining = append(remaining, name)
+			continue
+		}
+		removed[name] = true
+	}
+
+	c.metrics().CubesDespawned(len(removed))
+
+	c.spawnedMu.Lock()
+	c.spawnedCubes = remaining
+	c.spawnedMu.Unlock()
+	var keptSpawned []Cube
+	for _, cube := range c.spawned {
+		if !removed[resolveCubeID(cube.Name)] {
+			keptSpawned = append(keptSpawned, cube)
+		}
+	}
+	c.spawned = keptSpawned
+
+	// Forget the despawned cubes and any joints attached to them
+	cubeListMutex.Lock()
+	kept := globalCubeList[:0]
+	for _, name := range globalCubeList {
+		if !removed[name] {
+			kept = append(kept, name)
+		}
+	}
+	globalCubeList = kept
+	cubeListMutex.Unlock()
+
+	linkListMutex.Lock()
+	keptLinks := globalCubeLinks[:0]
+	for _, link := range globalCubeLinks {
+		if !removed[link.CubeA] && !removed[link.CubeB] {
+			keptLinks = append(keptLinks, link)
+		}
+	}
+	globalCubeLinks = keptLinks
+	linkListMutex.Unlock()
+
+	if len(errs) > 0 {
+		return fmt.Errorf("[Despawn] %d of %d cubes failed for %s, first: %v", len(errs), len(names), c.unitName, errs[0])
+	}
+	releaseOccupiedPositions(c.unitName)
+	c.log().Infof("🧹 Construct %s despawned (%d cubes)", c.unitName, len(removed))
+	return nil
 }
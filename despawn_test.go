@@ -0,0 +1,183 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+// cubeWorld is the cube state of a mock pod that actually removes the cubes it is told to despawn.
+// A pod without batch support rejects despawn_cubes as an unknown command.
+type cubeWorld struct {
+	mu    sync.Mutex
+	cubes map[string]bool
+	batch bool
+}
+
+func newCubeWorld(n int, batch bool) *cubeWorld {
+	w := &cubeWorld{batch: batch}
+	w.fill(n)
+	return w
+}
+
+// fill replaces the world's cubes with n fresh ones.
+func (w *cubeWorld) fill(n int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.cubes = make(map[string]bool, n)
+	for i := 0; i < n; i++ {
+		w.cubes[fmt.Sprintf("cube%04d_BASE", i)] = true
+	}
+}
+
+func (w *cubeWorld) len() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return len(w.cubes)
+}
+
+// reply answers like a pod: despawn_cube is fire-and-forget, despawn_cubes is acknowledged.
+func (w *cubeWorld) reply(raw string) string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	msg := decodeMessage(raw)
+	switch msg["type"] {
+	case "get_cube_list":
+		names := make([]string, 0, len(w.cubes))
+		for name := range w.cubes {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		data, _ := json.Marshal(map[string][]string{"cubes": names})
+		return string(data)
+	case "despawn_cube":
+		delete(w.cubes, fmt.Sprint(msg["cube_name"]))
+		return ""
+	case "despawn_cubes":
+		if !w.batch {
+			return `{"type":"error","message":"unknown command despawn_cubes"}`
+		}
+		names, _ := msg["cube_names"].([]interface{})
+		for _, name := range names {
+			delete(w.cubes, fmt.Sprint(name))
+		}
+		return replySuccess(raw)
+	}
+	return replySuccess(raw)
+}
+
+// useScannerResults points nukeAllCubePods at the given pods until the test ends.
+func useScannerResults(tb testing.TB, pods ...*mockServer) {
+	tb.Helper()
+	previous := scanner.Results
+	scanner.Results = nil
+	for _, m := range pods {
+		host, port := m.HostPort(tb)
+		scanner.Results = append(scanner.Results, PodResult{Host: host, Port: port, Success: true})
+	}
+	tb.Cleanup(func() { scanner.Results = previous })
+}
+
+func TestNukeAllCubePodsClearsEveryPod(t *testing.T) {
+	quietPackage(t)
+	batched, single := newCubeWorld(50, true), newCubeWorld(50, false)
+	batchPod, singlePod := newMockServer(t, batched.reply), newMockServer(t, single.reply)
+	useScannerResults(t, batchPod, singlePod)
+
+	nukeAllCubePods()
+
+	if batched.len() != 0 || single.len() != 0 {
+		t.Fatalf("%d and %d cubes left, want both pods empty", batched.len(), single.len())
+	}
+	if got := len(batchPod.MessagesOfType("despawn_cubes")); got != 1 {
+		t.Errorf("batch pod received %d despawn_cubes messages, want 1", got)
+	}
+	if got := len(batchPod.MessagesOfType("despawn_cube")); got != 0 {
+		t.Errorf("batch pod received %d single despawns, want 0", got)
+	}
+	if got := len(singlePod.MessagesOfType("despawn_cube")); got != 50 {
+		t.Errorf("pod without batch support received %d single despawns, want 50", got)
+	}
+}
+
+// BenchmarkNukeAllCubePods clears a mock pod holding 2000 cubes, with and without despawn_cubes.
+func BenchmarkNukeAllCubePods(b *testing.B) {
+	const cubes = 2000
+	for _, bc := range []struct {
+		name  string
+		batch bool
+	}{{"Batch", true}, {"PerCube", false}} {
+		b.Run(bc.name, func(b *testing.B) {
+			quietPackage(b)
+			world := newCubeWorld(0, bc.batch)
+			useScannerResults(b, newMockServer(b, world.reply))
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				world.fill(cubes)
+				b.StartTimer()
+				nukeAllCubePods()
+				if n := world.len(); n != 0 {
+					b.Fatalf("%d cubes left", n)
+				}
+			}
+		})
+	}
+}
+
+// respawningWorld is a cubeWorld whose server respawns every cube until the given despawn pass,
+// counted by the cube lists it has served.
+func respawningWorld(cubes, clearsOnPass int) (*cubeWorld, func(string) string) {
+	w := newCubeWorld(cubes, false)
+	passes := 0
+	return w, func(raw string) string {
+		switch messageType(raw) {
+		case "get_cube_list":
+			passes++
+		case "despawn_cube":
+			if passes < clearsOnPass {
+				return "" // Ignored; the server puts the cube straight back
+			}
+		}
+		return w.reply(raw)
+	}
+}
+
+func TestNukeAllCubesClearsOnThirdPass(t *testing.T) {
+	quietPackage(t)
+	world, reply := respawningWorld(5, 3)
+	mock := newMockServerAt(t, serverAddr, reply)
+
+	remaining, err := nukeAllCubes(nukeMaxRetries, time.Millisecond)
+	if err != nil || remaining != 0 {
+		t.Fatalf("nukeAllCubes = %d, %v; want 0, nil", remaining, err)
+	}
+	if world.len() != 0 {
+		t.Errorf("%d cubes left on the server", world.len())
+	}
+	// Three despawn passes, then the list that comes back empty
+	if got := len(mock.MessagesOfType("get_cube_list")); got != 4 {
+		t.Errorf("read the cube list %d times, want 4", got)
+	}
+	if got := len(mock.MessagesOfType("despawn_cube")); got != 15 {
+		t.Errorf("sent %d despawns, want 15", got)
+	}
+}
+
+func TestNukeAllCubesReportsCubesLeft(t *testing.T) {
+	quietPackage(t)
+	_, reply := respawningWorld(5, 100)
+	mock := newMockServerAt(t, serverAddr, reply)
+
+	remaining, err := nukeAllCubes(2, time.Millisecond)
+	if err == nil || remaining != 5 {
+		t.Fatalf("nukeAllCubes = %d, %v; want 5 cubes reported left", remaining, err)
+	}
+	// Both passes, then the re-read after the last one
+	if got := len(mock.MessagesOfType("get_cube_list")); got != 3 {
+		t.Errorf("read the cube list %d times, want 3", got)
+	}
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DryRun makes every connection opened by package-level functions (spawnCube, linkCubeChains,
+// nukeAllCubes, SpawnMultipleConstructs, ...) record and log its commands instead of sending them.
+// Every command, read-only queries included, is answered with a synthesized success response
+// and nothing is dialed, so a preview sees an empty world. See DryRunMessages and DryRunForwardReads.
+var DryRun bool
+
+// DryRunForwardReads makes dry runs of package-level functions send read-only queries such as
+// get_cube_list to the real server, so previews see its state. The server is dialed once, and
+// those queries get synthesized replies when it cannot be reached.
+var DryRunForwardReads bool
+
+// dryRunLog collects the commands of package-level functions run with DryRun set.
+var dryRunLog = &DryRunRecorder{}
+
+// DryRunMessages returns the commands package-level functions would have sent, in order.
+func DryRunMessages() []string {
+	return dryRunLog.Messages()
+}
+
+// DryRunRecorder collects the commands written to dry-run connections.
+type DryRunRecorder struct {
+	mu       sync.Mutex
+	messages []string
+}
+
+// Messages returns the recorded commands, without their delimiters, in the order they were written.
+func (r *DryRunRecorder) Messages() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.messages...)
+}
+
+func (r *DryRunRecorder) record(msg string) {
+	r.mu.Lock()
+	r.messages = append(r.messages, msg)
+	r.mu.Unlock()
+}
+
+// dryRunAuthResponse and dryRunResponse are the replies synthesized for the auth message and
+// for every command on a dry-run connection.
+const (
+	dryRunAuthResponse = `{"type":"auth_success"}`
+	dryRunResponse     = `{"status":"success"}`
+)
+
+// readOnlyCommands are the commands a dry-run connection forwards to its upstream server, if it has one.
+// None of them change the world.
+var readOnlyCommands = map[string]bool{
+	"get_cube_list":       true,
+	"get_cube_position":   true,
+	"get_joint_state":     true,
+	"get_joint_states":    true,
+	"get_joints_for_cube": true,
+	"get_all_joints":      true,
+	"get_world_snapshot":  true,
+	"get_version":         true,
+}
+
+// isReadOnlyMessage reports whether a framed JSON message is one of the readOnlyCommands.
+func isReadOnlyMessage(msg string) bool {
+	var cmd struct {
+		Type string `json:"type"`
+	}
+	return json.Unmarshal([]byte(msg), &cmd) == nil && readOnlyCommands[cmd.Type]
+}
+
+// dryRunConn is a connection that never sends a command that changes the world. Each
+// delimiter-framed message written to it is recorded and logged, and a success response is
+// queued for the next read. Read-only commands are instead forwarded to upstream, when set,
+// and its reply is queued. The first message is taken to be the auth message and is answered
+// but not recorded, so passwords never end up in the log.
+type dryRunConn struct {
+	delim    []byte
+	recorder *DryRunRecorder
+	log      Logger
+	upstream net.Conn // Authenticated connection to the real server, or nil
+
+	mu      sync.Mutex
+	authed  bool
+	written []byte       // Bytes of the message currently being written
+	pending bytes.Buffer // Synthesized responses not yet read
+}
+
+// newDryRunConn returns a dry-run connection framed with delim. Set authed when the caller will
+// not send an auth message first. Read-only commands go to upstream unless it is nil.
+func newDryRunConn(delim string, recorder *DryRunRecorder, log Logger, authed bool, upstream net.Conn) net.Conn {
+	if delim == "" {
+		delim = delimiter
+	}
+	return withDelimiter(&dryRunConn{
+		delim:    []byte(delim),
+		recorder: recorder,
+		log:      log,
+		authed:   authed,
+		upstream: upstream,
+	}, delim)
+}
+
+// dialDryRun returns an authenticated dry-run connection for addr. Nothing is dialed unless
+// forwardReads is set, in which case read-only commands go to the real server. If it cannot be
+// reached on the first attempt, those commands get synthesized replies too.
+func dialDryRun(ctx context.Context, addr, pass, delim string, forwardReads bool, recorder *DryRunRecorder, log Logger) (net.Conn, string, error) {
+	var upstream net.Conn
+	if forwardReads {
+		var err error
+		if upstream, _, err = dialAndAuthLive(ctx, addr, pass, delim, 1); err != nil {
+			log.Warnf("⚠️ [DryRun] Read-only queries will not reach %s: %v", addr, err)
+			upstream = nil
+		}
+	}
+	return authenticate(newDryRunConn(delim, recorder, log, false, upstream), addr, pass, delim)
+}
+
+// forward sends a read-only command to upstream and returns its reply.
+func (d *dryRunConn) forward(msg string) (string, error) {
+	if _, err := d.upstream.Write(append([]byte(msg), d.delim...)); err != nil {
+		return "", err
+	}
+	return readResponse(d.upstream)
+}
+
+func (d *dryRunConn) Write(b []byte) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.written = append(d.written, b...)
+	for {
+		idx := bytes.Index(d.written, d.delim)
+		if idx < 0 {
+			return len(b), nil
+		}
+		msg := strings.TrimSpace(string(d.written[:idx]))
+		d.written = d.written[idx+len(d.delim):]
+
+		if !d.authed {
+			d.authed = true
+			d.pending.WriteString(dryRunAuthResponse)
+		} else if d.upstream != nil && isReadOnlyMessage(msg) {
+			reply, err := d.forward(msg)
+			if err != nil {
+				d.log.Warnf("⚠️ [DryRun] Read-only query failed, answering with success: %v", err)
+				reply = dryRunResponse
+			}
+			d.pending.WriteString(reply)
+		} else {
+			d.recorder.record(msg)
+			d.log.Infof("[DryRun] %s", msg)
+			d.pending.WriteString(dryRunResponse)
+		}
+		d.pending.Write(d.delim)
+	}
+}
+
+// Read returns queued responses, or io.EOF when nothing is queued.
+func (d *dryRunConn) Read(b []byte) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.pending.Len() == 0 {
+		return 0, io.EOF
+	}
+	return d.pending.Read(b)
+}
+
+// Close closes the upstream connection, if any.
+func (d *dryRunConn) Close() error {
+	if d.upstream != nil {
+		return d.upstream.Close()
+	}
+	return nil
+}
+
+func (d *dryRunConn) LocalAddr() net.Addr                { return dryRunAddr{} }
+func (d *dryRunConn) RemoteAddr() net.Addr               { return dryRunAddr{} }
+func (d *dryRunConn) SetDeadline(t time.Time) error      { return nil }
+func (d *dryRunConn) SetReadDeadline(t time.Time) error  { return nil }
+func (d *dryRunConn) SetWriteDeadline(t time.Time) error { return nil }
+
+// isDryRun reports whether conn is a dry-run connection. Commands sent on it have no effect,
+// so callers skip tracking the cubes and joints they would have created.
+func isDryRun(conn net.Conn) bool {
+	if dc, ok := conn.(*delimitedConn); ok {
+		conn = dc.Conn
+	}
+	_, ok := conn.(*dryRunConn)
+	return ok
+}
+
+// dryRunAddr is the address of both ends of a dry-run connection.
+type dryRunAddr struct{}
+
+func (dryRunAddr) Network() string { return "dryrun" }
+func (dryRunAddr) String() string  { return "dryrun" }
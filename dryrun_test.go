@@ -0,0 +1,148 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// assertOffline fails the test if the mock pod was dialed or received anything during a dry run.
+func assertOffline(t *testing.T, m *mockServer) {
+	t.Helper()
+	if dials, msgs := m.Dials(), m.Messages(); dials != 0 || len(msgs) != 0 {
+		t.Errorf("server was dialed %d times and received %v during a dry run", dials, msgs)
+	}
+}
+
+// assertOnlyReadOnly fails the test if the mock pod received any command that changes the world.
+func assertOnlyReadOnly(t *testing.T, m *mockServer) {
+	t.Helper()
+	for _, msg := range m.Messages() {
+		if !isReadOnlyMessage(msg) {
+			t.Errorf("server received %s during a dry run", msg)
+		}
+	}
+}
+
+func TestDryRunNukeStaysOffline(t *testing.T) {
+	quietPackage(t)
+	mock := newMockServerAt(t, serverAddr, podReply([]string{"a_BASE", "b_BASE", "c_BASE"}))
+	DryRun = true
+	t.Cleanup(func() { DryRun = false })
+	before := len(DryRunMessages())
+
+	if remaining, err := nukeAllCubes(nukeMaxRetries, time.Millisecond); err != nil || remaining != 0 {
+		t.Fatalf("nukeAllCubes = %d, %v", remaining, err)
+	}
+
+	assertOffline(t, mock)
+	if recorded := DryRunMessages()[before:]; len(recorded) != 1 || messageType(recorded[0]) != "get_cube_list" {
+		t.Errorf("recorded %v, want only the cube list query, answered with an empty list", recorded)
+	}
+}
+
+func TestDryRunForwardReadsNukeReachesServerOnlyForCubeList(t *testing.T) {
+	quietPackage(t)
+	mock := newMockServerAt(t, serverAddr, podReply([]string{"a_BASE", "b_BASE", "c_BASE"}))
+	DryRun, DryRunForwardReads = true, true
+	t.Cleanup(func() { DryRun, DryRunForwardReads = false, false })
+	before := len(DryRunMessages())
+
+	if remaining, err := nukeAllCubes(nukeMaxRetries, time.Millisecond); err != nil || remaining != 0 {
+		t.Fatalf("nukeAllCubes = %d, %v", remaining, err)
+	}
+
+	assertOnlyReadOnly(t, mock)
+	if got := len(mock.MessagesOfType("get_cube_list")); got != 1 {
+		t.Errorf("server received %d get_cube_list messages, want 1", got)
+	}
+	recorded := DryRunMessages()[before:]
+	if len(recorded) != 3 {
+		t.Fatalf("recorded %v, want a despawn for each of the 3 cubes", recorded)
+	}
+	for i, cube := range []string{"a_BASE", "b_BASE", "c_BASE"} {
+		if msg := decodeMessage(recorded[i]); msg["type"] != "despawn_cube" || msg["cube_name"] != cube {
+			t.Errorf("recorded %s, want a despawn of %s", recorded[i], cube)
+		}
+	}
+}
+
+func TestDryRunForwardReadsWithoutServer(t *testing.T) {
+	quietPackage(t)
+	DialAttempts, DialBaseDelay = 4, time.Second
+	start := time.Now()
+	conn, _, err := dialDryRun(t.Context(), podAddr("127.0.0.1", reservePort(t)), authPass, delimiter, true, &DryRunRecorder{}, NopLogger{})
+	if err != nil {
+		t.Fatalf("dialDryRun without a server: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("dialDryRun took %s, want a single attempt without backoff", elapsed)
+	}
+	defer conn.Close()
+	if cubes, err := GetCubeList(conn); err != nil || len(cubes) != 0 {
+		t.Errorf("GetCubeList = %v, %v; want an empty synthesized list", cubes, err)
+	}
+}
+
+func TestConstructDryRun(t *testing.T) {
+	quietPackage(t)
+	mock := newMockServer(t, podReply(nil))
+	c := newTestConstruct(t, mock.Addr(), "dry")
+	c.DryRun = true
+	before := len(DryRunMessages())
+
+	if err := c.Spawn([]float64{100, 0, 0}, []float64{0, 0, 0}); err != nil {
+		t.Fatalf("Spawn: %v", err)
+	}
+
+	assertOffline(t, mock)
+	counts := make(map[string]int)
+	for _, msg := range c.DryRunMessages() {
+		counts[messageType(msg)]++
+		if strings.Contains(msg, authPass) {
+			t.Errorf("recorded the password: %s", msg)
+		}
+	}
+	if counts["spawn_cube"] != 3 || counts["link_cube_chains"] != 1 {
+		t.Errorf("recorded %v, want 3 spawns and one chain link", counts)
+	}
+	if len(DryRunMessages()) != before {
+		t.Error("the construct recorded into the package log instead of its own")
+	}
+}
+
+func TestSessionDryRun(t *testing.T) {
+	quietPackage(t)
+	mock := newMockServer(t, podReply([]string{"live_BASE"}))
+	s, err := NewSession(mock.Addr(), authPass, delimiter)
+	if err != nil {
+		t.Fatalf("NewSession: %v", err)
+	}
+	defer s.Close()
+	s.Logger = NopLogger{}
+	s.DryRun = true
+
+	if err := s.SpawnCube(Cube{Name: "ghost_BASE", Position: []float64{1, 2, 3}}); err != nil {
+		t.Fatalf("SpawnCube: %v", err)
+	}
+	if err := s.SetJointParam("joint_a_b", "motor_enable", 1); err != nil {
+		t.Fatalf("SetJointParam: %v", err)
+	}
+	if cubes, err := s.GetCubeList(); err != nil || len(cubes) != 0 {
+		t.Errorf("GetCubeList = %v, %v; want an empty synthesized list", cubes, err)
+	}
+	if msgs := mock.Messages(); len(msgs) != 0 {
+		t.Errorf("server received %v during a dry run", msgs)
+	}
+
+	s.DryRunForwardReads = true
+	cubes, err := s.GetCubeList()
+	if err != nil || len(cubes) != 1 || cubes[0] != "live_BASE" {
+		t.Errorf("GetCubeList with DryRunForwardReads = %v, %v; want the server's cubes", cubes, err)
+	}
+
+	assertOnlyReadOnly(t, mock)
+	if recorded := s.DryRunMessages(); len(recorded) != 3 {
+		t.Errorf("recorded %v, want the spawn, the joint param and the synthesized cube list query", recorded)
+	}
+}
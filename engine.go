@@ -2,9 +2,13 @@ package main
 
 import (
 	"bufio"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math/rand"
 	"net"
+	"regexp"
 	"strings"
 	"sync"
 	"time"
@@ -22,17 +26,33 @@ var scanner = &SparseScanner{}
 type Message map[string]interface{}
 
 type Cube struct {
-	Name     string
-	Position []float64
-	UnitName string // Optional: metadata tag
+	Name           string
+	Position       []float64
+	Rotation       []float64 // Optional: rotation as x, y, z angles (nil spawns upright)
+	UnitName       string    // Optional: metadata tag
+	CollisionGroup int       // Optional: collision layer bitmask (0 keeps the server default)
+	CollisionMask  int       // Optional: layers this cube collides with (0 keeps the server default)
 }
 
 type CubeLink struct {
 	JointName string
 	CubeA     string
 	CubeB     string
+	Params    map[string]float64 // Joint parameters the link was created or last overridden with, if known
 }
 
+// DuplicateJointPolicy controls what happens when a joint name is already tracked in globalCubeLinks.
+type DuplicateJointPolicy int
+
+const (
+	RejectDuplicateJoints DuplicateJointPolicy = iota // Refuse to create the joint and return an error
+	RenameDuplicateJoints                             // Append a numeric suffix until the name is unique
+)
+
+// DuplicateJoints is the policy applied before sending create_joint. Re-using a name would let the
+// server overwrite the existing joint's parameters, so duplicates are rejected by default.
+var DuplicateJoints = RejectDuplicateJoints
+
 var (
 	globalCubeList    []string
 	cubeListMutex     sync.Mutex
@@ -40,76 +60,474 @@ var (
 	linkListMutex     sync.Mutex
 	occupiedPositions []occupiedPosition // Track positions of spawned constructs
 	positionMutex     sync.Mutex
+	serverCubeIDs     = map[string]string{} // client cube name -> server-assigned cube ID
+	cubeIDMutex       sync.Mutex
 )
 
+// RawResponseHandler, when set, is called with any server response that does not match the
+// schema the caller expected. It is a debugging aid for discovering new server message types.
+var RawResponseHandler func(raw string)
+
+// AuthMessage builds the first message sent on every new connection, before the delimiter.
+// The default sends the bare password; replace it for servers that expect another format,
+// for example a JSON {"type":"auth","password":...} message.
+var AuthMessage = func(pass string) []byte {
+	return []byte(pass)
+}
+
+// authFrame returns the auth message for pass followed by delim, ready to write to a connection.
+func authFrame(pass, delim string) []byte {
+	return append(AuthMessage(pass), delim...)
+}
+
+// reportRawResponse forwards an unexpected response to RawResponseHandler if one is installed.
+func reportRawResponse(raw string) {
+	if RawResponseHandler != nil {
+		RawResponseHandler(raw)
+	}
+}
+
 type occupiedPosition struct {
 	Position []float64
 	UnitName string
 }
 
+// delimitedConn is a connection whose messages are framed with its own delimiter rather than
+// the package default, so servers with different protocols can be used in one process.
+type delimitedConn struct {
+	net.Conn
+	delimiter string
+	timeout   time.Duration // Read timeout used by read(), 0 for the package default
+
+	reader  *bufio.Reader // Shared by every read, so bytes past the end of one message are kept for the next
+	partial []byte        // Start of a message whose read timed out, completed by the next read
+	late    []lateReply   // Replies that timed out and may still arrive, oldest first
+	noAcks  bool          // An optional ack timed out, so awaitAck no longer waits on this connection
+}
+
+// withDelimiter makes sendJSONMessage, readResponse, send, and read frame messages on conn with delim.
+// An empty delim keeps the package default, or the delimiter conn already uses.
+func withDelimiter(conn net.Conn, delim string) net.Conn {
+	if dc, ok := conn.(*delimitedConn); ok {
+		if delim != "" {
+			dc.delimiter = delim
+		}
+		return dc
+	}
+	if delim == "" {
+		delim = delimiter
+	}
+	return &delimitedConn{Conn: conn, delimiter: delim}
+}
+
+// connDelimiter returns the delimiter used on conn.
+func connDelimiter(conn net.Conn) string {
+	if dc, ok := conn.(*delimitedConn); ok {
+		return dc.delimiter
+	}
+	return delimiter
+}
+
+// connReader returns the buffered reader for conn. Connections not wrapped by withDelimiter get a
+// new reader on every call, so any bytes it reads past the current message are lost.
+func connReader(conn net.Conn) *bufio.Reader {
+	dc, ok := conn.(*delimitedConn)
+	if !ok {
+		return bufio.NewReader(conn)
+	}
+	if dc.reader == nil {
+		dc.reader = bufio.NewReader(dc.Conn)
+	}
+	return dc.reader
+}
+
+// withReadTimeout sets the timeout read() waits for a full message on conn.
+func withReadTimeout(conn net.Conn, timeout time.Duration) net.Conn {
+	if dc, ok := conn.(*delimitedConn); ok {
+		dc.timeout = timeout
+		return dc
+	}
+	return &delimitedConn{Conn: conn, delimiter: delimiter, timeout: timeout}
+}
+
+// connReadTimeout returns the read timeout configured on conn, or timeoutSec if none is set.
+func connReadTimeout(conn net.Conn) time.Duration {
+	if dc, ok := conn.(*delimitedConn); ok && dc.timeout > 0 {
+		return dc.timeout
+	}
+	return timeoutSec * time.Second
+}
+
 func sendJSONMessage(conn net.Conn, msg Message) error {
 	data, err := json.Marshal(msg)
 	if err != nil {
 		return err
 	}
-	data = append(data, []byte(delimiter)...)
+	data = append(data, []byte(connDelimiter(conn))...)
 	_, err = conn.Write(data)
 	return err
 }
 
+// readResponse reads until the delimiter ends the accumulated response, waiting at most the read
+// timeout set with withReadTimeout, or 3 seconds if none is set. It returns an error if the deadline
+// expires or the connection closes first, saying whether nothing at all or only part of a response
+// was received.
 func readResponse(conn net.Conn) (string, error) {
-	reader := bufio.NewReader(conn)
-	conn.SetReadDeadline(time.Now().Add(3 * time.Second))
-	var builder strings.Builder
+	timeout := 3 * time.Second
+	if dc, ok := conn.(*delimitedConn); ok && dc.timeout > 0 {
+		timeout = dc.timeout
+	}
+	return readReply(conn, timeout)
+}
+
+// readReply reads the next message on conn, skipping late replies recorded by expectLateReply.
+func readReply(conn net.Conn, timeout time.Duration) (string, error) {
 	for {
-		line, err := reader.ReadString('-')
-		if err != nil {
-			break
+		raw, err := readFrame(conn, timeout)
+		if err != nil || !skipLateReply(conn, raw) {
+			return raw, err
 		}
+	}
+}
+
+// readFrame reads one delimiter-framed message from conn, waiting at most timeout (0 sets no
+// deadline). On a delimitedConn, bytes read past the delimiter stay buffered for the next read,
+// and so does the start of a message cut short by the deadline.
+func readFrame(conn net.Conn, timeout time.Duration) (string, error) {
+	delim := connDelimiter(conn)
+	reader := connReader(conn)
+	if timeout > 0 {
+		conn.SetReadDeadline(time.Now().Add(timeout))
+		defer conn.SetReadDeadline(time.Time{})
+	}
+
+	var builder strings.Builder
+	dc, buffered := conn.(*delimitedConn)
+	if buffered {
+		builder.Write(dc.partial)
+		dc.partial = nil
+	}
+	for {
+		// The delimiter may repeat its last byte, so check the whole buffer, not each chunk
+		line, err := reader.ReadString(delim[len(delim)-1])
 		builder.WriteString(line)
-		if strings.Contains(line, delimiter) {
+		if strings.HasSuffix(builder.String(), delim) {
 			break
 		}
+		if err != nil {
+			if builder.Len() == 0 {
+				return "", fmt.Errorf("no response received: %w", err)
+			}
+			if buffered && isTimeout(err) {
+				dc.partial = []byte(builder.String())
+				return "", fmt.Errorf("incomplete response (%d bytes without delimiter): %w", builder.Len(), err)
+			}
+			return strings.TrimSpace(builder.String()), fmt.Errorf("incomplete response (%d bytes without delimiter): %w", builder.Len(), err)
+		}
 	}
-	full := strings.ReplaceAll(builder.String(), delimiter, "")
+	full := strings.TrimSuffix(builder.String(), delim)
 	return strings.TrimSpace(full), nil
 }
 
-func spawnCube(cube Cube, wg *sync.WaitGroup) {
-	defer wg.Done()
-	conn, err := net.Dial("tcp", serverAddr)
-	if err != nil {
-		fmt.Println("[Spawn] Failed to connect:", err)
-		return
+// AckTimeout is how long commands that not every server acknowledges, such as spawn_cube and
+// freeze_cube, wait for an acknowledgement before carrying on without one.
+var AckTimeout = 500 * time.Millisecond
+
+// awaitAck reads the optional acknowledgement of msg, returning "" with no error if none arrived
+// within AckTimeout. From then on, optional acks on conn are not waited for at all; any that arrive
+// late are skipped by the next read instead of being taken for its reply. key names a field only
+// the acknowledgement carries, if any. Only a failed connection is an error.
+func awaitAck(conn net.Conn, msg Message, key string) (string, error) {
+	dc, buffered := conn.(*delimitedConn)
+	if buffered && dc.noAcks {
+		expectLateReply(conn, msg, key)
+		return "", nil
 	}
-	defer conn.Close()
+	raw, err := readReply(conn, AckTimeout)
+	if err == nil {
+		return raw, nil
+	}
+	if !isTimeout(err) {
+		return "", err
+	}
+	if buffered {
+		dc.noAcks = true
+	}
+	expectLateReply(conn, msg, key)
+	return "", nil
+}
 
-	if _, err := conn.Write([]byte(authPass + delimiter)); err != nil {
-		fmt.Println("[Spawn] Auth write error:", err)
-		return
+// isTimeout reports whether err is a network timeout, as opposed to a closed or broken connection.
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// lateReply is the reply to a command that timed out. It is recognized by carrying key or by
+// having the command's own "type". Replies that only name the same cube or joint are not matched,
+// since the next command about that cube gets one too.
+type lateReply struct {
+	command string
+	key     string
+}
+
+func (l lateReply) matches(raw string) bool {
+	var resp map[string]interface{}
+	if err := json.Unmarshal([]byte(raw), &resp); err != nil {
+		return false
 	}
-	_, err = readResponse(conn)
-	if err != nil {
-		fmt.Println("[Spawn] Failed to read auth response:", err)
+	if t, _ := resp["type"].(string); t != "" && t == l.command {
+		return true
+	}
+	_, ok := resp[l.key]
+	return l.key != "" && ok
+}
+
+// expectLateReply remembers that the reply to msg did not arrive in time, so that if it shows up
+// later it is skipped instead of being taken for the reply to the next command. key names a
+// field that only that reply carries, if any.
+func expectLateReply(conn net.Conn, msg Message, key string) {
+	dc, ok := conn.(*delimitedConn)
+	if !ok {
 		return
 	}
+	late := lateReply{key: key}
+	late.command, _ = msg["type"].(string)
+	dc.late = append(dc.late, late)
+}
 
+// skipLateReply reports whether raw is a late reply recorded by expectLateReply, forgetting it
+// if so. Replies arrive in order, so any other message means the earlier ones are not coming.
+func skipLateReply(conn net.Conn, raw string) bool {
+	dc, ok := conn.(*delimitedConn)
+	if !ok || len(dc.late) == 0 {
+		return false
+	}
+	for i, late := range dc.late {
+		if late.matches(raw) {
+			dc.late = dc.late[i+1:]
+			if err := responseError(raw); err != nil {
+				DefaultLogger.Warnf("⚠️ Late %s reply reported an error: %v", late.command, err)
+			}
+			return true
+		}
+	}
+	dc.late = nil
+	return false
+}
+
+// cubeRotation returns the cube's rotation, defaulting to {0, 0, 0} when none is set.
+func cubeRotation(cube Cube) []float64 {
+	if cube.Rotation == nil {
+		return []float64{0, 0, 0}
+	}
+	return cube.Rotation
+}
+
+// newSpawnMessage builds the spawn_cube command for a cube. Collision settings are only
+// included when set, so cubes without them keep the server's default full collision.
+func newSpawnMessage(cube Cube) Message {
 	spawn := Message{
 		"type":      "spawn_cube",
 		"cube_name": cube.Name,
 		"position":  cube.Position,
-		"rotation":  []float64{0, 0, 0},
+		"rotation":  cubeRotation(cube),
 		"is_base":   true,
 	}
-	if err := sendJSONMessage(conn, spawn); err != nil {
-		fmt.Println("[Spawn] Failed to spawn cube:", err)
+	if cube.CollisionGroup != 0 {
+		spawn["collision_group"] = cube.CollisionGroup
+	}
+	if cube.CollisionMask != 0 {
+		spawn["collision_mask"] = cube.CollisionMask
+	}
+	return spawn
+}
+
+// responseError reports an error if the server's JSON response signals a failure, either through
+// an "error" field or a "type" of "error". Non-JSON responses are not treated as errors here.
+func responseError(raw string) error {
+	var resp map[string]interface{}
+	if err := json.Unmarshal([]byte(raw), &resp); err != nil {
+		return nil
+	}
+	if msg, ok := resp["error"].(string); ok && msg != "" {
+		return fmt.Errorf("server error: %s", msg)
+	}
+	if resp["type"] == "error" {
+		if msg, ok := resp["message"].(string); ok && msg != "" {
+			return fmt.Errorf("server error: %s", msg)
+		}
+		return fmt.Errorf("server error: %s", raw)
+	}
+	return nil
+}
+
+// recordSpawnResponse stores the server-assigned ID from a spawn_cube response, if the server sent one.
+func recordSpawnResponse(cubeName, raw string) {
+	var resp struct {
+		CubeID string `json:"cube_id"`
+	}
+	if err := json.Unmarshal([]byte(raw), &resp); err != nil || resp.CubeID == "" {
 		return
 	}
+	cubeIDMutex.Lock()
+	serverCubeIDs[cubeName] = resp.CubeID
+	cubeIDMutex.Unlock()
+}
 
-	fullCubeName := cube.Name + "_BASE"
+// resolveCubeID returns the identifier the server uses for a spawned cube: the server-assigned
+// cube_id when one was returned at spawn time, otherwise the conventional cubeName + "_BASE".
+func resolveCubeID(cubeName string) string {
+	cubeIDMutex.Lock()
+	defer cubeIDMutex.Unlock()
+	if id, ok := serverCubeIDs[cubeName]; ok {
+		return id
+	}
+	return cubeName + "_BASE"
+}
+
+// forgetCubeIDs drops the server-assigned IDs recorded for cubes that no longer exist.
+func forgetCubeIDs(cubeNames []string) {
+	cubeIDMutex.Lock()
+	defer cubeIDMutex.Unlock()
+	for _, name := range cubeNames {
+		delete(serverCubeIDs, name)
+	}
+}
+
+// resolveChains maps every client cube name in the chains to its server identifier.
+func resolveChains(chains [][]string) [][]string {
+	resolved := make([][]string, len(chains))
+	for i, chain := range chains {
+		resolved[i] = make([]string, len(chain))
+		for j, cubeName := range chain {
+			resolved[i][j] = resolveCubeID(cubeName)
+		}
+	}
+	return resolved
+}
+
+// spawnCube spawns one cube on serverAddr over its own connection.
+func spawnCube(cube Cube) error {
+	conn, _, err := dialAndAuth(serverAddr, authPass, delimiter)
+	if err != nil {
+		return fmt.Errorf("[Spawn] %v", err)
+	}
+	defer conn.Close()
+
+	if err := sendSpawn(conn, cube); err != nil {
+		return fmt.Errorf("[Spawn] %v", err)
+	}
+	return nil
+}
+
+// sendSpawn validates and spawns a cube over an authenticated connection, records its server ID,
+// and tracks it in globalCubeList. A server error in the response fails the spawn; a missing
+// response does not, since not every server acknowledges spawn_cube (see awaitAck).
+func sendSpawn(conn net.Conn, cube Cube) error {
+	if err := validatePositions([][]float64{cube.Position}); err != nil {
+		return fmt.Errorf("invalid position for cube %s: %v", cube.Name, err)
+	}
+	if err := validateRotation(cube.Rotation); err != nil {
+		return fmt.Errorf("invalid rotation for cube %s: %v", cube.Name, err)
+	}
+
+	spawn := newSpawnMessage(cube)
+	if err := sendJSONMessage(conn, spawn); err != nil {
+		return fmt.Errorf("failed to spawn cube %s: %v", cube.Name, err)
+	}
+	if resp, err := awaitAck(conn, spawn, "cube_id"); err == nil && resp != "" {
+		if err := responseError(resp); err != nil {
+			return fmt.Errorf("failed to spawn cube %s: %v", cube.Name, err)
+		}
+		recordSpawnResponse(cube.Name, resp)
+	}
+
+	if isDryRun(conn) {
+		return nil // Nothing was spawned, so there is nothing to track
+	}
+	fullCubeName := resolveCubeID(cube.Name)
 	cubeListMutex.Lock()
 	globalCubeList = append(globalCubeList, fullCubeName)
 	cubeListMutex.Unlock()
+	return nil
+}
+
+// SpawnCubesBatch spawns every cube with a single {"type": "spawn_cubes", "cubes": [...]} message
+// over an authenticated connection and reads one acknowledgement, which may map cube names to
+// server IDs under "cube_ids". If the server rejects the batch command or does not acknowledge it
+// in time, each cube is spawned with its own spawn_cube message on the same connection instead. Spawned cubes are tracked in globalCubeList.
+func SpawnCubesBatch(conn net.Conn, cubes []Cube) error {
+	if len(cubes) == 0 {
+		return nil
+	}
+	entries := make([]Message, len(cubes))
+	for i, cube := range cubes {
+		if err := validatePositions([][]float64{cube.Position}); err != nil {
+			return fmt.Errorf("[SpawnCubesBatch] invalid position for cube %s: %v", cube.Name, err)
+		}
+		if err := validateRotation(cube.Rotation); err != nil {
+			return fmt.Errorf("[SpawnCubesBatch] invalid rotation for cube %s: %v", cube.Name, err)
+		}
+		entries[i] = newSpawnMessage(cube)
+		delete(entries[i], "type")
+	}
+
+	batch := Message{"type": "spawn_cubes", "cubes": entries}
+	if err := sendJSONMessage(conn, batch); err != nil {
+		return fmt.Errorf("[SpawnCubesBatch] Failed to send batch: %v", err)
+	}
+	resp, err := readResponse(conn)
+	if err != nil && isTimeout(err) {
+		// Servers without spawn_cubes may ignore it; skip its ack if it turns up after all
+		expectLateReply(conn, batch, "cube_ids")
+		DefaultLogger.Warnf("⚠️ [SpawnCubesBatch] Batch not acknowledged (%v), spawning %d cubes one by one", err, len(cubes))
+		return spawnCubesSerially(conn, cubes)
+	}
+	if err != nil {
+		return fmt.Errorf("[SpawnCubesBatch] No acknowledgement for %d cubes: %v", len(cubes), err)
+	}
+	if err := responseError(resp); err != nil {
+		DefaultLogger.Warnf("⚠️ [SpawnCubesBatch] Batch rejected (%v), spawning %d cubes one by one", err, len(cubes))
+		return spawnCubesSerially(conn, cubes)
+	}
+
+	var ack struct {
+		CubeIDs map[string]string `json:"cube_ids"`
+	}
+	if json.Unmarshal([]byte(resp), &ack) == nil && len(ack.CubeIDs) > 0 {
+		cubeIDMutex.Lock()
+		for name, id := range ack.CubeIDs {
+			if id != "" {
+				serverCubeIDs[name] = id
+			}
+		}
+		cubeIDMutex.Unlock()
+	}
+
+	if isDryRun(conn) {
+		return nil
+	}
+	cubeListMutex.Lock()
+	for _, cube := range cubes {
+		globalCubeList = append(globalCubeList, resolveCubeID(cube.Name))
+	}
+	cubeListMutex.Unlock()
+	return nil
+}
+
+// spawnCubesSerially spawns each cube with its own spawn_cube message, continuing past failures.
+func spawnCubesSerially(conn net.Conn, cubes []Cube) error {
+	var errs []error
+	for _, cube := range cubes {
+		if err := sendSpawn(conn, cube); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("[SpawnCubesBatch] %d of %d cubes failed to spawn, first: %v", len(errs), len(cubes), errs[0])
+	}
+	return nil
 }
 
 func unfreezeAllCubes() {
@@ -118,18 +536,13 @@ func unfreezeAllCubes() {
 		wg.Add(1)
 		go func(name string) {
 			defer wg.Done()
-			conn, err := net.Dial("tcp", serverAddr)
+			conn, _, err := dialAndAuth(serverAddr, authPass, delimiter)
 			if err != nil {
-				fmt.Println("[Unfreeze] Failed to connect:", err)
+				DefaultLogger.Warnf("[Unfreeze] Failed to connect: %v", err)
 				return
 			}
 			defer conn.Close()
 
-			if _, err := conn.Write([]byte(authPass + delimiter)); err != nil {
-				return
-			}
-			_, _ = readResponse(conn)
-
 			unfreeze := Message{
 				"type":      "freeze_cube",
 				"cube_name": name,
@@ -182,61 +595,684 @@ func setJointParams(conn net.Conn, jointName string, params map[string]float64)
 	fmt.Printf("[setJointParams] %s response: %s\n", jointName, resp)
 }
 
-func linkCubeChains(chains [][]string, jointType string, jointParams map[string]float64) error {
-	// Establish TCP connection
-	conn, err := net.Dial("tcp", serverAddr)
+// SetManyJointParams updates the parameters of many joints (joint name -> param -> value) with a
+// single set_joint_params_bulk message and one response. If the server rejects the bulk command or
+// does not answer it in time, it falls back to one set_joint_params round trip per joint on the same
+// connection. A reply listing the joints that failed under "failed", as names or as a map of name to
+// reason, retries only those joints.
+func SetManyJointParams(conn net.Conn, updates map[string]map[string]float64) error {
+	if len(updates) == 0 {
+		return nil
+	}
+	bulk := Message{
+		"type":   "set_joint_params_bulk",
+		"joints": updates,
+	}
+	if err := sendJSONMessage(conn, bulk); err != nil {
+		return fmt.Errorf("[SetManyJointParams] Failed to send bulk update: %v", err)
+	}
+	retry := updates
+	raw, err := readResponse(conn)
+	switch {
+	case err != nil && isTimeout(err):
+		// Servers without the bulk command may ignore it; skip its reply if it turns up after all
+		expectLateReply(conn, bulk, "")
+	case err != nil:
+		return fmt.Errorf("[SetManyJointParams] Failed to read bulk response: %v", err)
+	default:
+		failed := bulkFailures(raw, updates)
+		if len(failed) == 0 && responseError(raw) == nil {
+			return nil
+		}
+		reportRawResponse(raw)
+		if len(failed) > 0 {
+			retry = failed
+		}
+	}
+
+	// Update each remaining joint individually
+	var errs []error
+	for jointName, params := range retry {
+		if _, err := sendCommand(conn, Message{
+			"type":       "set_joint_params",
+			"joint_name": jointName,
+			"params":     params,
+		}); err != nil {
+			errs = append(errs, fmt.Errorf("joint %s: %v", jointName, err))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("[SetManyJointParams] %d of %d joints failed, first: %v", len(errs), len(updates), errs[0])
+	}
+	return nil
+}
+
+// bulkFailures returns the updates for the joints a set_joint_params_bulk reply lists under "failed".
+func bulkFailures(raw string, updates map[string]map[string]float64) map[string]map[string]float64 {
+	var resp struct {
+		Failed json.RawMessage `json:"failed"`
+	}
+	if err := json.Unmarshal([]byte(raw), &resp); err != nil || len(resp.Failed) == 0 {
+		return nil
+	}
+	var names []string
+	if err := json.Unmarshal(resp.Failed, &names); err != nil {
+		var reasons map[string]interface{}
+		if err := json.Unmarshal(resp.Failed, &reasons); err != nil {
+			return nil
+		}
+		for name := range reasons {
+			names = append(names, name)
+		}
+	}
+	failed := make(map[string]map[string]float64, len(names))
+	for _, name := range names {
+		if params, ok := updates[name]; ok {
+			failed[name] = params
+		}
+	}
+	return failed
+}
+
+// trackedJointUpdates builds a SetManyJointParams update applying params to every tracked joint.
+func trackedJointUpdates(params map[string]float64) map[string]map[string]float64 {
+	linkListMutex.Lock()
+	defer linkListMutex.Unlock()
+	updates := make(map[string]map[string]float64, len(globalCubeLinks))
+	for _, link := range globalCubeLinks {
+		updates[link.JointName] = params
+	}
+	return updates
+}
+
+// sendCommand sends a command, reads the response, and converts a server-reported error into a Go error.
+func sendCommand(conn net.Conn, msg Message) (string, error) {
+	if err := sendJSONMessage(conn, msg); err != nil {
+		return "", fmt.Errorf("failed to send %v: %v", msg["type"], err)
+	}
+	resp, err := readResponse(conn)
 	if err != nil {
-		return fmt.Errorf("[linkCubeChains] Failed to connect: %v", err)
+		return "", fmt.Errorf("failed to read %v response: %v", msg["type"], err)
 	}
-	defer conn.Close()
+	if err := responseError(resp); err != nil {
+		return resp, fmt.Errorf("%v: %v", msg["type"], err)
+	}
+	return resp, nil
+}
 
-	// Authenticate
-	if _, err := conn.Write([]byte(authPass + delimiter)); err != nil {
-		return fmt.Errorf("[linkCubeChains] Auth write error: %v", err)
+// sendFreeze freezes or unfreezes a cube. Not every server acknowledges freeze_cube, so a missing
+// acknowledgement is not an error (see awaitAck), but an error reply is.
+func sendFreeze(conn net.Conn, cubeName string, freeze bool) error {
+	msg := Message{"type": "freeze_cube", "cube_name": cubeName, "freeze": freeze}
+	if err := sendJSONMessage(conn, msg); err != nil {
+		return fmt.Errorf("failed to send freeze_cube: %v", err)
+	}
+	resp, err := awaitAck(conn, msg, "")
+	if err != nil {
+		return fmt.Errorf("failed to read freeze_cube response: %v", err)
+	}
+	if err := responseError(resp); err != nil {
+		return fmt.Errorf("freeze_cube: %v", err)
+	}
+	return nil
+}
+
+// ApplyForce applies a linear force and a rotational torque, each an {x, y, z} vector, to a cube
+// over an authenticated connection and waits for the server to acknowledge it.
+func ApplyForce(conn net.Conn, cubeName string, force []float64, torque []float64) error {
+	if err := validateVector(force); err != nil {
+		return fmt.Errorf("[ApplyForce] invalid force for cube %s: %v", cubeName, err)
+	}
+	if err := validateVector(torque); err != nil {
+		return fmt.Errorf("[ApplyForce] invalid torque for cube %s: %v", cubeName, err)
+	}
+	if _, err := sendCommand(conn, Message{
+		"type":      "apply_force",
+		"cube_name": cubeName,
+		"force":     force,
+		"torque":    torque,
+	}); err != nil {
+		return fmt.Errorf("[ApplyForce] %v", err)
+	}
+	return nil
+}
+
+// GetCubeList requests the names of every cube currently on the server over an authenticated connection.
+func GetCubeList(conn net.Conn) ([]string, error) {
+	if err := sendJSONMessage(conn, Message{"type": "get_cube_list"}); err != nil {
+		return nil, fmt.Errorf("failed to request cube list: %v", err)
+	}
+	raw, err := readResponse(conn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cube list: %v", err)
+	}
+	return parseCubeList(raw)
+}
+
+// parseCubeList extracts the cube names from a get_cube_list response. A response without
+// a "cubes" key is reported to RawResponseHandler and treated as an empty list.
+func parseCubeList(raw string) ([]string, error) {
+	var cubeData map[string]interface{}
+	if err := json.Unmarshal([]byte(raw), &cubeData); err != nil {
+		reportRawResponse(raw)
+		return nil, fmt.Errorf("failed to parse cube list: %v", err)
+	}
+	if _, ok := cubeData["cubes"]; !ok {
+		reportRawResponse(raw)
+	}
+	return toStringArray(cubeData["cubes"]), nil
+}
+
+// ErrCubeNotFound is returned when the server reports that a cube does not exist.
+var ErrCubeNotFound = errors.New("cube not found")
+
+// isNotFound reports whether a server error says the requested object does not exist.
+func isNotFound(err error) bool {
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "not found") || strings.Contains(msg, "does not exist") || strings.Contains(msg, "no such")
+}
+
+// GetCubePosition returns a cube's current world position as {x, y, z}. If the server says the
+// cube does not exist, the error wraps ErrCubeNotFound.
+func GetCubePosition(conn net.Conn, cubeName string) ([]float64, error) {
+	pos, err := getCubePosition(conn, cubeName)
+	if err != nil {
+		return nil, err
+	}
+	return []float64{pos[0], pos[1], pos[2]}, nil
+}
+
+// SetCubePosition teleports an existing cube to pos without despawning it, so its joints are kept.
+// Only the position is sent, leaving the cube's rotation unchanged.
+func SetCubePosition(conn net.Conn, cubeName string, pos []float64) error {
+	if err := validatePositions([][]float64{pos}); err != nil {
+		return fmt.Errorf("invalid position for cube %s: %v", cubeName, err)
+	}
+	if err := sendJSONMessage(conn, Message{
+		"type":      "set_cube_transform",
+		"cube_name": cubeName,
+		"position":  pos,
+	}); err != nil {
+		return fmt.Errorf("failed to move cube %s: %v", cubeName, err)
+	}
+	raw, err := readResponse(conn)
+	if err != nil {
+		return fmt.Errorf("failed to read move response for %s: %v", cubeName, err)
+	}
+	if err := responseError(raw); err != nil {
+		if isNotFound(err) {
+			return fmt.Errorf("cube %s: %w", cubeName, ErrCubeNotFound)
+		}
+		return fmt.Errorf("cube %s: %v", cubeName, err)
+	}
+	return nil
+}
+
+// getCubePosition asks the server for a cube's current world position. The server may reply
+// with either {"position": [x, y, z]} or {"x": ..., "y": ..., "z": ...}.
+func getCubePosition(conn net.Conn, cubeName string) ([3]float64, error) {
+	var pos [3]float64
+	if err := sendJSONMessage(conn, Message{"type": "get_cube_position", "cube_name": cubeName}); err != nil {
+		return pos, fmt.Errorf("failed to request position of %s: %v", cubeName, err)
+	}
+	raw, err := readResponse(conn)
+	if err != nil {
+		return pos, fmt.Errorf("failed to read position of %s: %v", cubeName, err)
+	}
+	if err := responseError(raw); err != nil {
+		if isNotFound(err) {
+			return pos, fmt.Errorf("cube %s: %w", cubeName, ErrCubeNotFound)
+		}
+		return pos, fmt.Errorf("cube %s: %v", cubeName, err)
+	}
+
+	var resp struct {
+		Position []float64 `json:"position"`
+		X        *float64  `json:"x"`
+		Y        *float64  `json:"y"`
+		Z        *float64  `json:"z"`
+	}
+	if err := json.Unmarshal([]byte(raw), &resp); err != nil {
+		reportRawResponse(raw)
+		return pos, fmt.Errorf("failed to parse position of %s: %v", cubeName, err)
+	}
+	switch {
+	case len(resp.Position) == 3:
+		copy(pos[:], resp.Position)
+	case resp.X != nil && resp.Y != nil && resp.Z != nil:
+		pos = [3]float64{*resp.X, *resp.Y, *resp.Z}
+	default:
+		reportRawResponse(raw)
+		return pos, fmt.Errorf("unexpected position response for %s: %s", cubeName, raw)
+	}
+	return pos, nil
+}
+
+// LinkBatchSize is the default maximum number of joints requested in one link_cube_chains message.
+var LinkBatchSize = 64
+
+// LinkBatchResult records the outcome of one link_cube_chains message.
+type LinkBatchResult struct {
+	Chains [][]string // Chain segments sent in this batch
+	Links  int        // Number of joints requested by this batch
+	Err    error      // Non-nil if the batch failed; its links were not tracked
+}
+
+// LinkReport summarizes a chain-linking operation.
+type LinkReport struct {
+	Created int               // Joints requested from the server by successful batches
+	Skipped int               // Joints skipped because the pair was already linked
+	Batches []LinkBatchResult // Per-batch outcome, in send order
+}
+
+// hexColorPattern matches a "#RRGGBB" color.
+var hexColorPattern = regexp.MustCompile(`^#[0-9A-Fa-f]{6}$`)
+
+// SetCubeColor sets a cube's color over an authenticated connection. hex must be "#RRGGBB".
+func SetCubeColor(conn net.Conn, cubeName, hex string) error {
+	if !hexColorPattern.MatchString(hex) {
+		return fmt.Errorf("invalid color %q, expected #RRGGBB", hex)
+	}
+	if _, err := sendCommand(conn, Message{
+		"type":      "set_color",
+		"cube_name": cubeName,
+		"hex":       hex,
+	}); err != nil {
+		return fmt.Errorf("failed to color cube %s: %v", cubeName, err)
+	}
+	return nil
+}
+
+// colorWorkers is the number of connections SetCubesColor uses at once.
+const colorWorkers = 10
+
+// SetCubesColor colors every named cube on serverAddr, spreading the cubes over at most
+// colorWorkers connections. All cubes are attempted; the first error is returned.
+func SetCubesColor(cubeNames []string, hex string) error {
+	if !hexColorPattern.MatchString(hex) {
+		return fmt.Errorf("[SetCubesColor] invalid color %q, expected #RRGGBB", hex)
+	}
+
+	jobs := make(chan string, len(cubeNames))
+	for _, name := range cubeNames {
+		jobs <- name
+	}
+	close(jobs)
+	var wg sync.WaitGroup
+	var errMu sync.Mutex
+	var errs []error
+	var connErr error
+	fail := func(err error) {
+		errMu.Lock()
+		errs = append(errs, err)
+		errMu.Unlock()
+	}
+
+	workers := colorWorkers
+	if len(cubeNames) < workers {
+		workers = len(cubeNames)
+	}
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			conn, _, err := dialAndAuth(serverAddr, authPass, delimiter)
+			if err != nil {
+				// Leave the cubes to the other workers; any nobody colors are reported below
+				errMu.Lock()
+				connErr = err
+				errMu.Unlock()
+				return
+			}
+			defer conn.Close()
+			for name := range jobs {
+				if err := SetCubeColor(conn, name, hex); err != nil {
+					fail(err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	for name := range jobs {
+		errs = append(errs, fmt.Errorf("failed to color cube %s: %v", name, connErr))
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("[SetCubesColor] %d errors coloring %d cubes, first: %v", len(errs), len(cubeNames), errs[0])
+	}
+	return nil
+}
+
+// DialAttempts and DialBaseDelay control how dialWithRetry retries a refused or failed connection,
+// for pods that are still booting. The delay doubles after every failed attempt. DialTimeout bounds
+// each attempt of the package's own dials.
+var (
+	DialAttempts  = 4
+	DialBaseDelay = 250 * time.Millisecond
+	DialTimeout   = 10 * time.Second
+)
+
+// dialTCP dials addr with DialTimeout per attempt, retrying as DialAttempts and DialBaseDelay say.
+func dialTCP(addr string) (net.Conn, error) {
+	return dialWithRetryContext(context.Background(), &net.Dialer{Timeout: DialTimeout}, addr, DialAttempts, DialBaseDelay)
+}
+
+// dialWithRetry dials addr up to attempts times, backing off exponentially with jitter between tries.
+func dialWithRetry(addr string, attempts int, baseDelay time.Duration) (net.Conn, error) {
+	return dialWithRetryContext(context.Background(), &net.Dialer{}, addr, attempts, baseDelay)
+}
+
+// dialWithRetryContext is dialWithRetry with a custom dialer. Cancelling ctx stops retrying.
+func dialWithRetryContext(ctx context.Context, dialer *net.Dialer, addr string, attempts int, baseDelay time.Duration) (net.Conn, error) {
+	if attempts < 1 {
+		attempts = 1
+	}
+	var err error
+	for attempt := 0; attempt < attempts; attempt++ {
+		var conn net.Conn
+		if conn, err = dialer.DialContext(ctx, "tcp", addr); err == nil {
+			return conn, nil
+		}
+		if attempt == attempts-1 {
+			break
+		}
+
+		// Back off baseDelay * 2^attempt, plus up to half that again so callers do not retry in step
+		delay := baseDelay << attempt
+		if delay > 0 {
+			delay += time.Duration(rand.Int63n(int64(delay)/2 + 1))
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, fmt.Errorf("%v (retries cancelled: %v)", err, ctx.Err())
+		case <-timer.C:
+		}
+	}
+	if attempts > 1 {
+		return nil, fmt.Errorf("%v (after %d attempts)", err, attempts)
+	}
+	return nil, err
+}
+
+// dialAndAuth connects to addr and authenticates, returning the connection and the auth response.
+func dialAndAuth(addr, pass, delim string) (net.Conn, string, error) {
+	return dialAndAuthContext(context.Background(), addr, pass, delim)
+}
+
+// dialAndAuthContext is dialAndAuth with a cancellable dial. With DryRun set, the returned
+// connection records every command that would change the world instead of sending it.
+func dialAndAuthContext(ctx context.Context, addr, pass, delim string) (net.Conn, string, error) {
+	if DryRun {
+		return dialDryRun(ctx, addr, pass, delim, DryRunForwardReads, dryRunLog, DefaultLogger)
+	}
+	return dialAndAuthLive(ctx, addr, pass, delim, DialAttempts)
+}
+
+// dialAndAuthLive dials and authenticates against the real server, ignoring DryRun.
+func dialAndAuthLive(ctx context.Context, addr, pass, delim string, attempts int) (net.Conn, string, error) {
+	rawConn, err := dialWithRetryContext(ctx, &net.Dialer{Timeout: DialTimeout}, addr, attempts, DialBaseDelay)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to connect to %s: %v", addr, err)
+	}
+	return authenticate(withDelimiter(rawConn, delim), addr, pass, delim)
+}
+
+// authenticate sends the auth message on a new connection and returns the server's reply.
+// The connection is closed if authentication fails.
+func authenticate(conn net.Conn, addr, pass, delim string) (net.Conn, string, error) {
+	if _, err := conn.Write(authFrame(pass, delim)); err != nil {
+		conn.Close()
+		return nil, "", fmt.Errorf("auth write error to %s: %v", addr, err)
 	}
 	authResp, err := readResponse(conn)
 	if err != nil {
-		return fmt.Errorf("[linkCubeChains] Failed to read auth response: %v", err)
+		conn.Close()
+		return nil, "", fmt.Errorf("failed to read auth response from %s: %v", addr, err)
 	}
-	fmt.Println("[linkCubeChains] Auth response:", authResp)
+	return conn, authResp, nil
+}
 
-	// Construct the command
-	cmd := Message{
-		"type":         "link_cube_chains",
-		"chains":       chains,
-		"joint_type":   jointType,
-		"joint_params": jointParams,
+// linkCubeChainsContext is linkCubeChains with cancellation: cancelling ctx aborts the dial or
+// closes the connection, failing any batch that has not yet been acknowledged.
+func linkCubeChainsContext(ctx context.Context, chains [][]string, jointType string, jointParams map[string]float64) (LinkReport, error) {
+	conn, _, err := dialAndAuthContext(ctx, serverAddr, authPass, delimiter)
+	if err != nil {
+		return LinkReport{}, fmt.Errorf("[linkCubeChains] %v", err)
 	}
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
 
-	// Send the command
-	if err := sendJSONMessage(conn, cmd); err != nil {
-		return fmt.Errorf("[linkCubeChains] Failed to send command: %v", err)
+	report, err := sendLinkChains(conn, chains, jointType, jointParams, LinkBatchSize, DefaultLogger)
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return report, fmt.Errorf("[linkCubeChains] cancelled: %v", ctxErr)
 	}
+	return report, err
+}
 
-	// Read response (optional)
-	resp, err := readResponse(conn)
+func linkCubeChains(chains [][]string, jointType string, jointParams map[string]float64) (LinkReport, error) {
+	// Establish TCP connection and authenticate
+	conn, authResp, err := dialAndAuth(serverAddr, authPass, delimiter)
 	if err != nil {
-		return fmt.Errorf("[linkCubeChains] Error reading response: %v", err)
+		return LinkReport{}, fmt.Errorf("[linkCubeChains] %v", err)
+	}
+	defer conn.Close()
+	DefaultLogger.Debugf("[linkCubeChains] Auth response: %s", authResp)
+
+	return sendLinkChains(conn, chains, jointType, jointParams, LinkBatchSize, DefaultLogger)
+}
+
+// isLinked reports whether globalCubeLinks already holds a joint between the two cubes.
+// The caller must hold linkListMutex.
+func isLinked(cubeA, cubeB string) bool {
+	for _, link := range globalCubeLinks {
+		if (link.CubeA == cubeA && link.CubeB == cubeB) || (link.CubeA == cubeB && link.CubeB == cubeA) {
+			return true
+		}
+	}
+	return false
+}
+
+// linkedJointName returns the tracked joint between two cubes in either order.
+// The caller must hold linkListMutex.
+func linkedJointName(cubeA, cubeB string) (string, bool) {
+	for _, link := range globalCubeLinks {
+		if (link.CubeA == cubeA && link.CubeB == cubeB) || (link.CubeA == cubeB && link.CubeB == cubeA) {
+			return link.JointName, true
+		}
 	}
-	fmt.Println("[linkCubeChains] Server response:", resp)
+	return "", false
+}
 
-	// Update globalCubeLinks for tracking (optional, adjust as needed)
+// trackJointParams records the parameters a tracked joint was last set to.
+func trackJointParams(jointName string, params map[string]float64) {
 	linkListMutex.Lock()
 	defer linkListMutex.Unlock()
+	for i := range globalCubeLinks {
+		if globalCubeLinks[i].JointName == jointName {
+			globalCubeLinks[i].Params = params
+			return
+		}
+	}
+}
+
+// reserveJointName checks jointName against globalCubeLinks under the duplicate policy and, if it
+// can be used, records the link so concurrent callers cannot claim the same name. It returns the
+// name to send, which differs from jointName only when a duplicate was renamed.
+func reserveJointName(jointName, cubeA, cubeB string) (string, error) {
+	linkListMutex.Lock()
+	defer linkListMutex.Unlock()
+
+	name := jointName
+	for suffix := 1; jointNameTaken(name); suffix++ {
+		if DuplicateJoints == RejectDuplicateJoints {
+			return "", fmt.Errorf("joint name %s already in use", jointName)
+		}
+		name = fmt.Sprintf("%s_%d", jointName, suffix)
+	}
+	globalCubeLinks = append(globalCubeLinks, CubeLink{
+		JointName: name,
+		CubeA:     cubeA,
+		CubeB:     cubeB,
+	})
+	return name, nil
+}
+
+// jointNameTaken reports whether globalCubeLinks already holds a joint with this name.
+// The caller must hold linkListMutex.
+func jointNameTaken(jointName string) bool {
+	for _, link := range globalCubeLinks {
+		if link.JointName == jointName {
+			return true
+		}
+	}
+	return false
+}
+
+// releaseJointName drops a reserved joint whose create_joint command was never delivered.
+func releaseJointName(jointName string) {
+	linkListMutex.Lock()
+	defer linkListMutex.Unlock()
+	for i, link := range globalCubeLinks {
+		if link.JointName == jointName {
+			globalCubeLinks = append(globalCubeLinks[:i], globalCubeLinks[i+1:]...)
+			return
+		}
+	}
+}
+
+// sendLinkChains links the chains over an authenticated connection. Pairs that are already linked
+// (according to globalCubeLinks) or repeated within the request are skipped, and chains are split
+// around them, so linking the same chains twice does not create duplicate joints. The remaining
+// links are sent in batches of at most batchSize joints; a failed batch does not stop later ones,
+// and only links from successful batches are tracked.
+func sendLinkChains(conn net.Conn, chains [][]string, jointType string, jointParams map[string]float64, batchSize int, log Logger) (LinkReport, error) {
+	var report LinkReport
+	if batchSize <= 0 {
+		batchSize = LinkBatchSize
+	}
+
+	// Split chains into segments made only of pairs that still need a joint
+	linkListMutex.Lock()
+	seen := make(map[[2]string]bool)
+	var segments [][]string
 	for _, chain := range chains {
+		var segment []string
 		for i := 0; i < len(chain)-1; i++ {
-			cubeA := chain[i]
-			cubeB := chain[i+1]
-			jointName := fmt.Sprintf("joint_%s_%s_%s", jointType, cubeA, cubeB) // Simplified name
-			globalCubeLinks = append(globalCubeLinks, CubeLink{
-				JointName: jointName,
-				CubeA:     cubeA,
-				CubeB:     cubeB,
-			})
+			cubeA, cubeB := chain[i], chain[i+1]
+			if isLinked(cubeA, cubeB) || seen[[2]string{cubeA, cubeB}] || seen[[2]string{cubeB, cubeA}] {
+				report.Skipped++
+				if len(segment) > 1 {
+					segments = append(segments, segment)
+				}
+				segment = nil
+				continue
+			}
+			seen[[2]string{cubeA, cubeB}] = true
+			if len(segment) == 0 {
+				segment = []string{cubeA}
+			}
+			segment = append(segment, cubeB)
+		}
+		if len(segment) > 1 {
+			segments = append(segments, segment)
 		}
 	}
+	linkListMutex.Unlock()
 
-	return nil
+	if len(segments) == 0 {
+		log.Infof("[linkCubeChains] Nothing to link, %d joints already exist", report.Skipped)
+		return report, nil
+	}
+
+	var failed int
+	for _, batch := range splitLinkBatches(segments, batchSize) {
+		result := LinkBatchResult{Chains: batch}
+		for _, chain := range batch {
+			result.Links += len(chain) - 1
+		}
+
+		// Construct the command
+		cmd := Message{
+			"type":         "link_cube_chains",
+			"chains":       batch,
+			"joint_type":   jointType,
+			"joint_params": jointParams,
+		}
+
+		// Send the command and wait for the server to acknowledge it
+		resp, err := sendCommand(conn, cmd)
+		if err != nil {
+			result.Err = fmt.Errorf("[linkCubeChains] %v", err)
+			report.Batches = append(report.Batches, result)
+			failed++
+			continue
+		}
+		log.Debugf("[linkCubeChains] Server response: %s", resp)
+		report.Created += result.Links
+		report.Batches = append(report.Batches, result)
+		if isDryRun(conn) {
+			continue // No joints exist, so none are tracked
+		}
+
+		// Update globalCubeLinks for tracking
+		linkListMutex.Lock()
+		for _, chain := range batch {
+			for i := 0; i < len(chain)-1; i++ {
+				cubeA := chain[i]
+				cubeB := chain[i+1]
+				jointName := fmt.Sprintf("joint_%s_%s_%s", jointType, cubeA, cubeB) // Simplified name
+				globalCubeLinks = append(globalCubeLinks, CubeLink{
+					JointName: jointName,
+					CubeA:     cubeA,
+					CubeB:     cubeB,
+					Params:    jointParams,
+				})
+			}
+		}
+		linkListMutex.Unlock()
+	}
+
+	if failed > 0 {
+		return report, fmt.Errorf("[linkCubeChains] %d of %d batches failed", failed, len(report.Batches))
+	}
+	return report, nil
+}
+
+// splitLinkBatches groups chain segments into batches of at most batchSize links. Segments longer
+// than batchSize are cut into overlapping pieces (the last cube of one piece starts the next),
+// so no link is lost at the cut.
+func splitLinkBatches(segments [][]string, batchSize int) [][][]string {
+	var pieces [][]string
+	for _, segment := range segments {
+		for start := 0; start < len(segment)-1; start += batchSize {
+			end := start + batchSize + 1
+			if end > len(segment) {
+				end = len(segment)
+			}
+			pieces = append(pieces, segment[start:end])
+		}
+	}
+
+	var batches [][][]string
+	var current [][]string
+	links := 0
+	for _, piece := range pieces {
+		n := len(piece) - 1
+		if links+n > batchSize && len(current) > 0 {
+			batches = append(batches, current)
+			current = nil
+			links = 0
+		}
+		current = append(current, piece)
+		links += n
+	}
+	if len(current) > 0 {
+		batches = append(batches, current)
+	}
+	return batches
 }
 
 func targetedUnfreezeAllCubes(unitName string) {
@@ -246,18 +1282,13 @@ func targetedUnfreezeAllCubes(unitName string) {
 			wg.Add(1)
 			go func(name string) {
 				defer wg.Done()
-				conn, err := net.Dial("tcp", serverAddr)
+				conn, _, err := dialAndAuth(serverAddr, authPass, delimiter)
 				if err != nil {
-					fmt.Printf("[%s] [Unfreeze] Connection failed: %v\n", unitName, err)
+					DefaultLogger.Warnf("[%s] [Unfreeze] Connection failed: %v", unitName, err)
 					return
 				}
 				defer conn.Close()
 
-				if _, err := conn.Write([]byte(authPass + delimiter)); err != nil {
-					return
-				}
-				_, _ = readResponse(conn)
-
 				unfreeze := Message{
 					"type":      "freeze_cube",
 					"cube_name": name,
@@ -268,7 +1299,7 @@ func targetedUnfreezeAllCubes(unitName string) {
 		}
 	}
 	wg.Wait()
-	fmt.Printf("🌀 [%s] All cubes unfrozen.\n", unitName)
+	DefaultLogger.Infof("🌀 [%s] All cubes unfrozen.", unitName)
 }
 
 func main() {
@@ -285,10 +1316,12 @@ func main() {
 	scanner.ScanAllPods()
 	scanner.PrintSummary()
 
-	StartEMLst([]string{
+	if err := StartEMLst([]string{
 		"192.168.0.229",
 		"192.168.0.227",
-	}, 10002, authPass, delimiter)
+	}, 10002, authPass, delimiter); err != nil {
+		fmt.Println(err)
+	}
 
 	//singlePod()
 	//centers := scanner.ExtractPlanetCenters()
@@ -305,13 +1338,13 @@ func main() {
 	paddingDegrees := 360.0 / 8 // Evenly spaced around sphere (for 8 constructs)
 	constructsPerPlanet := 10   // How many per planet
 
-	nukeAllCubes()
+	nukeAllCubes(nukeMaxRetries, nukeRetryDelay)
 	// Spawn around all planets
 	spawnConstructsAroundSphere(1, role, domain, planetCenters, radius, paddingDegrees, constructsPerPlanet)
 
 	singlePod()
 
-	nukeAllCubes()*/
+	nukeAllCubes(nukeMaxRetries, nukeRetryDelay)*/
 }
 
 func singlePod() {
@@ -0,0 +1,252 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"reflect"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// pipeConn returns a delimited client connection with a short read timeout, and the server end.
+func pipeConn(t *testing.T) (net.Conn, net.Conn) {
+	t.Helper()
+	client, server := net.Pipe()
+	t.Cleanup(func() {
+		client.Close()
+		server.Close()
+	})
+	return withReadTimeout(withDelimiter(client, delimiter), 50*time.Millisecond), server
+}
+
+func TestReadResponseTimesOutWithoutDelimiter(t *testing.T) {
+	conn, server := pipeConn(t)
+	go server.Write([]byte(`{"status":"succ`))
+
+	_, err := readResponse(conn)
+	if err == nil || !isTimeout(err) {
+		t.Fatalf("err = %v, want a timeout", err)
+	}
+	if !strings.Contains(err.Error(), "incomplete response") {
+		t.Errorf("err = %v, want it to report an incomplete response", err)
+	}
+
+	// The rest of the message completes the partial one instead of starting a new one
+	go server.Write([]byte(`ess"}` + delimiter))
+	resp, err := readResponse(conn)
+	if err != nil || resp != `{"status":"success"}` {
+		t.Fatalf("readResponse = %q, %v; want the completed message", resp, err)
+	}
+}
+
+func TestReadResponseReportsNothingReceived(t *testing.T) {
+	conn, _ := pipeConn(t)
+
+	_, err := readResponse(conn)
+	if err == nil || !isTimeout(err) {
+		t.Fatalf("err = %v, want a timeout", err)
+	}
+	if !strings.Contains(err.Error(), "no response received") {
+		t.Errorf("err = %v, want it to report that nothing was received", err)
+	}
+}
+
+func TestReadResponseReportsEarlyClose(t *testing.T) {
+	conn, server := pipeConn(t)
+	go func() {
+		server.Write([]byte(`{"status":`))
+		server.Close()
+	}()
+
+	resp, err := readResponse(conn)
+	if !errors.Is(err, io.EOF) || isTimeout(err) {
+		t.Fatalf("err = %v, want EOF", err)
+	}
+	if resp != `{"status":` {
+		t.Errorf("resp = %q, want the partial data", resp)
+	}
+}
+
+func TestReadResponseKeepsBytesPastDelimiter(t *testing.T) {
+	conn, server := pipeConn(t)
+	go server.Write([]byte(`first` + delimiter + `second` + delimiter))
+
+	for _, want := range []string{"first", "second"} {
+		resp, err := readResponse(conn)
+		if err != nil || resp != want {
+			t.Fatalf("readResponse = %q, %v; want %q", resp, err, want)
+		}
+	}
+}
+
+func TestGetCubeList(t *testing.T) {
+	quietPackage(t)
+	mock := newMockServer(t, podReply([]string{"head_BASE", "body_BASE"}))
+	conn := dialMock(t, mock)
+
+	cubes, err := GetCubeList(conn)
+	if err != nil {
+		t.Fatalf("GetCubeList: %v", err)
+	}
+	if want := []string{"head_BASE", "body_BASE"}; !reflect.DeepEqual(cubes, want) {
+		t.Errorf("cubes = %v, want %v", cubes, want)
+	}
+	if got := len(mock.MessagesOfType("get_cube_list")); got != 1 {
+		t.Errorf("sent %d get_cube_list messages, want 1", got)
+	}
+}
+
+func TestGetCubeListMalformedReply(t *testing.T) {
+	quietPackage(t)
+	var reported []string
+	RawResponseHandler = func(raw string) { reported = append(reported, raw) }
+	t.Cleanup(func() { RawResponseHandler = nil })
+
+	conn := dialMock(t, newMockServer(t, replySuccess))
+
+	if cubes, err := GetCubeList(conn); err != nil || len(cubes) != 0 {
+		t.Errorf("GetCubeList = %v, %v; want an empty list for a reply without cubes", cubes, err)
+	}
+	if len(reported) != 1 {
+		t.Errorf("reported %d raw responses, want 1", len(reported))
+	}
+	if _, err := parseCubeList("not json"); err == nil {
+		t.Error("parseCubeList accepted invalid JSON")
+	}
+}
+
+func TestGetCubePosition(t *testing.T) {
+	quietPackage(t)
+	mock := newMockServer(t, func(msg string) string {
+		switch decodeMessage(msg)["cube_name"] {
+		case "list_BASE":
+			return `{"type":"cube_position","position":[1.5,2,-3]}`
+		case "fields_BASE":
+			return `{"x":4,"y":5,"z":6}`
+		case "gone_BASE":
+			return `{"type":"error","message":"Cube gone_BASE not found"}`
+		case "broken_BASE":
+			return `{"type":"error","message":"physics server busy"}`
+		}
+		return `{"status":"success"}`
+	})
+	conn := dialMock(t, mock)
+
+	for name, want := range map[string][]float64{
+		"list_BASE":   {1.5, 2, -3},
+		"fields_BASE": {4, 5, 6},
+	} {
+		pos, err := GetCubePosition(conn, name)
+		if err != nil || !reflect.DeepEqual(pos, want) {
+			t.Errorf("GetCubePosition(%s) = %v, %v; want %v", name, pos, err, want)
+		}
+	}
+
+	if _, err := GetCubePosition(conn, "gone_BASE"); !errors.Is(err, ErrCubeNotFound) {
+		t.Errorf("missing cube: err = %v, want ErrCubeNotFound", err)
+	}
+	if _, err := GetCubePosition(conn, "broken_BASE"); err == nil || errors.Is(err, ErrCubeNotFound) {
+		t.Errorf("server error: err = %v, want an error other than ErrCubeNotFound", err)
+	}
+	if _, err := GetCubePosition(conn, "other_BASE"); err == nil {
+		t.Error("a reply without a position was accepted")
+	}
+	if got := mock.MessagesOfType("get_cube_position"); len(got) != 5 || got[0]["cube_name"] == nil {
+		t.Errorf("sent %v, want 5 get_cube_position messages naming the cube", got)
+	}
+}
+
+// refusingDialer fails its first refusals dial attempts before connecting, as if the pod were
+// still booting, and counts every attempt.
+func refusingDialer(refusals int, attempts *int) *net.Dialer {
+	return &net.Dialer{Control: func(network, address string, c syscall.RawConn) error {
+		*attempts++
+		if *attempts <= refusals {
+			return syscall.ECONNREFUSED
+		}
+		return nil
+	}}
+}
+
+func TestDialWithRetryRecoversAfterRefusals(t *testing.T) {
+	mock := newMockServer(t, nil)
+	attempts := 0
+	conn, err := dialWithRetryContext(context.Background(), refusingDialer(2, &attempts), mock.Addr(), 3, time.Millisecond)
+	if err != nil {
+		t.Fatalf("dialWithRetryContext: %v", err)
+	}
+	conn.Close()
+	if attempts != 3 {
+		t.Errorf("dialed %d times, want 3", attempts)
+	}
+}
+
+func TestDialWithRetryGivesUp(t *testing.T) {
+	mock := newMockServer(t, nil)
+	attempts := 0
+	_, err := dialWithRetryContext(context.Background(), refusingDialer(5, &attempts), mock.Addr(), 3, time.Millisecond)
+	if err == nil || !strings.Contains(err.Error(), "after 3 attempts") {
+		t.Fatalf("err = %v, want a failure after 3 attempts", err)
+	}
+	if attempts != 3 {
+		t.Errorf("dialed %d times, want 3", attempts)
+	}
+}
+
+func TestDialWithRetryStopsOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	attempts := 0
+	dialer := refusingDialer(100, &attempts)
+	dialer.Control = func(network, address string, c syscall.RawConn) error {
+		attempts++
+		cancel()
+		return syscall.ECONNREFUSED
+	}
+	_, err := dialWithRetryContext(ctx, dialer, "127.0.0.1:1", 10, time.Hour)
+	if err == nil || !strings.Contains(err.Error(), "retries cancelled") {
+		t.Fatalf("err = %v, want the retries to be cancelled", err)
+	}
+	if attempts != 1 {
+		t.Errorf("dialed %d times, want 1", attempts)
+	}
+}
+
+func TestLateAckDoesNotSwallowNextReplyAboutSameCube(t *testing.T) {
+	quietPackage(t)
+	AckTimeout = 20 * time.Millisecond
+	mock := newMockServer(t, func(msg string) string {
+		switch m := decodeMessage(msg); m["type"] {
+		case "freeze_cube":
+			return "" // This server never acknowledges freeze_cube
+		case "get_cube_position":
+			return fmt.Sprintf(`{"type":"cube_position","cube_name":%q,"position":[1,2,3]}`, m["cube_name"])
+		}
+		return replySuccess(msg)
+	})
+	conn := dialMock(t, mock)
+
+	for i := 0; i < 2; i++ {
+		if err := sendFreeze(conn, "a_BASE", true); err != nil {
+			t.Fatalf("sendFreeze: %v", err)
+		}
+	}
+	pos, err := GetCubePosition(conn, "a_BASE")
+	if err != nil || !reflect.DeepEqual(pos, []float64{1, 2, 3}) {
+		t.Fatalf("GetCubePosition after unacknowledged freezes = %v, %v", pos, err)
+	}
+}
+
+func TestLateReplyOfSameTypeIsSkipped(t *testing.T) {
+	conn, server := pipeConn(t)
+	expectLateReply(conn, Message{"type": "get_version"}, "")
+	go server.Write([]byte(`{"type":"get_version","version":"1.0"}` + delimiter + `{"type":"cube_list","cubes":[]}` + delimiter))
+	resp, err := readResponse(conn)
+	if err != nil || !strings.Contains(resp, "cube_list") {
+		t.Fatalf("readResponse = %q, %v; want the late version reply skipped", resp, err)
+	}
+}
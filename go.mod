@@ -1,5 +1,5 @@
-module main
+module github.com/OpenFluke/sparse
 
 go 1.24.1
 
-require github.com/OpenFluke/PARAGON v0.0.0-20250412035249-d301ceaa46fb // indirect
+require github.com/OpenFluke/PARAGON v0.0.0-20250412035249-d301ceaa46fb
@@ -4,7 +4,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"math"
+	"math/rand"
 	"os"
+	"regexp"
 	"strings"
 )
 
@@ -15,6 +17,29 @@ func calculateRotationOutward(center, position []float64) float64 {
 	return angle
 }
 
+// validatePositions checks that every position has exactly 3 finite coordinates.
+func validatePositions(positions [][]float64) error {
+	for i, pos := range positions {
+		if len(pos) != 3 {
+			return fmt.Errorf("position %d has %d coordinates, expected 3", i, len(pos))
+		}
+		for j, coord := range pos {
+			if math.IsNaN(coord) || math.IsInf(coord, 0) {
+				return fmt.Errorf("position %d coordinate %d is %v", i, j, coord)
+			}
+		}
+	}
+	return nil
+}
+
+// distance3 returns the Euclidean distance between two 3D points.
+func distance3(a, b []float64) float64 {
+	dx := a[0] - b[0]
+	dy := a[1] - b[1]
+	dz := a[2] - b[2]
+	return math.Sqrt(dx*dx + dy*dy + dz*dz)
+}
+
 func normalize(vec []float64) []float64 {
 	mag := math.Sqrt(vec[0]*vec[0] + vec[1]*vec[1] + vec[2]*vec[2])
 	if mag == 0 {
@@ -23,10 +48,41 @@ func normalize(vec []float64) []float64 {
 	return []float64{vec[0] / mag, vec[1] / mag, vec[2] / mag}
 }
 
-func appendUnitSafely(slice []string, unitName string) []string {
+// validateRotation checks that a cube rotation is either unset or three finite angles.
+func validateRotation(rotation []float64) error {
+	if rotation == nil {
+		return nil
+	}
+	if len(rotation) != 3 {
+		return fmt.Errorf("rotation has %d components, expected 3", len(rotation))
+	}
+	for i, angle := range rotation {
+		if math.IsNaN(angle) || math.IsInf(angle, 0) {
+			return fmt.Errorf("rotation component %d is %v", i, angle)
+		}
+	}
+	return nil
+}
+
+// validateVector checks that v has exactly three finite components.
+func validateVector(v []float64) error {
+	if len(v) != 3 {
+		return fmt.Errorf("vector has %d components, expected 3", len(v))
+	}
+	for i, c := range v {
+		if math.IsNaN(c) || math.IsInf(c, 0) {
+			return fmt.Errorf("vector component %d is %v", i, c)
+		}
+	}
+	return nil
+}
+
+// appendUnitSafely appends unitName to *slice while holding allUnitsMutex, so both the append
+// and the assignment of the new slice header happen under the lock.
+func appendUnitSafely(slice *[]string, unitName string) {
 	allUnitsMutex.Lock()
 	defer allUnitsMutex.Unlock()
-	return append(slice, unitName)
+	*slice = append(*slice, unitName)
 }
 
 func toStringArray(v interface{}) []string {
@@ -78,6 +134,140 @@ func fibonacciSphere(n int, radius float64, center []float64) [][]float64 {
 	return points
 }
 
+// fibonacciSphereOffset is fibonacciSphere with the index shifted by epsilon, so y = 1 - 2*(i+epsilon)/n.
+// The standard epsilon of 0.5 centers the points in equal-area bands, so none sits on a pole and
+// small n spread more evenly. Epsilon outside [0, 1) uses 0.5.
+func fibonacciSphereOffset(n int, radius float64, center []float64, epsilon float64) [][]float64 {
+	points := make([][]float64, n)
+	if epsilon < 0 || epsilon >= 1 {
+		epsilon = 0.5
+	}
+
+	phi := math.Pi * (3 - math.Sqrt(5)) // Golden angle in radians
+	for i := 0; i < n; i++ {
+		y := 1 - 2*(float64(i)+epsilon)/float64(n)
+		r := math.Sqrt(1 - y*y)
+		theta := phi * float64(i)
+		points[i] = []float64{
+			center[0] + math.Cos(theta)*r*radius,
+			center[1] + y*radius,
+			center[2] + math.Sin(theta)*r*radius,
+		}
+	}
+	return points
+}
+
+// packCandidatesPerSlot controls how many fibonacci candidate points packConstructs
+// considers for each requested construct.
+const packCandidatesPerSlot = 32
+
+// packConstructs places up to count positions on a sphere of the given radius around center so that
+// no two positions are closer than minDist. It draws from a dense fibonacci candidate set and accepts
+// candidates greedily (Poisson-disk style), so fewer than count positions are returned when the sphere
+// is full. An error is returned only if not even one position can be placed.
+func packConstructs(count int, radius float64, minDist float64, center []float64) ([][]float64, error) {
+	if count <= 0 {
+		return [][]float64{}, nil
+	}
+	if len(center) != 3 {
+		return nil, fmt.Errorf("center must have 3 coordinates, got %d", len(center))
+	}
+	if math.IsNaN(radius) || math.IsInf(radius, 0) || radius < 0 {
+		return nil, fmt.Errorf("invalid packing radius %v", radius)
+	}
+	if math.IsNaN(minDist) || minDist < 0 {
+		return nil, fmt.Errorf("invalid minimum distance %v", minDist)
+	}
+	if radius == 0 {
+		// Every candidate collapses onto the center, so only one construct fits
+		return [][]float64{{center[0], center[1], center[2]}}, nil
+	}
+
+	numCandidates := count * packCandidatesPerSlot
+	if numCandidates < 256 {
+		numCandidates = 256
+	}
+	candidates := fibonacciSphere(numCandidates, radius, center)
+
+	placed := make([][]float64, 0, count)
+	for _, candidate := range candidates {
+		fits := true
+		for _, p := range placed {
+			dx := candidate[0] - p[0]
+			dy := candidate[1] - p[1]
+			dz := candidate[2] - p[2]
+			if math.Sqrt(dx*dx+dy*dy+dz*dz) < minDist {
+				fits = false
+				break
+			}
+		}
+		if fits {
+			placed = append(placed, candidate)
+			if len(placed) == count {
+				break
+			}
+		}
+	}
+
+	if len(placed) == 0 {
+		return nil, fmt.Errorf("no position fits on a sphere of radius %.2f with minimum distance %.2f", radius, minDist)
+	}
+	return placed, nil
+}
+
+// seededSpherePositions returns n points uniformly distributed on a sphere, drawn from an RNG
+// seeded with seed, so the same seed always yields the same layout.
+func seededSpherePositions(seed int64, n int, radius float64, center []float64) [][]float64 {
+	rng := rand.New(rand.NewSource(seed))
+	points := make([][]float64, n)
+	for i := 0; i < n; i++ {
+		y := rng.Float64()*2 - 1 // Uniform in [-1, 1] gives a uniform distribution over the surface
+		theta := rng.Float64() * 2 * math.Pi
+		r := math.Sqrt(1 - y*y)
+		points[i] = []float64{
+			center[0] + math.Cos(theta)*r*radius,
+			center[1] + y*radius,
+			center[2] + math.Sin(theta)*r*radius,
+		}
+	}
+	return points
+}
+
+// paletteColor returns the color of entry idx in a palette of n evenly spaced hues.
+func paletteColor(idx, n int) string {
+	if n <= 0 {
+		n = 1
+	}
+	return hsvToHex(360*float64(idx%n)/float64(n), 0.8, 0.95)
+}
+
+// hsvToHex converts a hue in [0, 360) with saturation and value in [0, 1] to a "#RRGGBB" string.
+func hsvToHex(h, s, v float64) string {
+	c := v * s
+	x := c * (1 - math.Abs(math.Mod(h/60, 2)-1))
+	m := v - c
+	var r, g, b float64
+	switch {
+	case h < 60:
+		r, g, b = c, x, 0
+	case h < 120:
+		r, g, b = x, c, 0
+	case h < 180:
+		r, g, b = 0, c, x
+	case h < 240:
+		r, g, b = 0, x, c
+	case h < 300:
+		r, g, b = x, 0, c
+	default:
+		r, g, b = c, 0, x
+	}
+	return fmt.Sprintf("#%02X%02X%02X",
+		int(math.Round((r+m)*255)),
+		int(math.Round((g+m)*255)),
+		int(math.Round((b+m)*255)),
+	)
+}
+
 func generateUnitID(role string, domain string, gen int, version int) string {
 	domainParts := strings.Split(domain, ".")
 	projectCode := ""
@@ -89,6 +279,20 @@ func generateUnitID(role string, domain string, gen int, version int) string {
 	return fmt.Sprintf("[%s]-%s-gen%d-v%d", strings.ToUpper(role), projectCode, gen, version)
 }
 
+// unitIDPattern matches the unit name produced by generateUnitID at the start of a cube name,
+// including the optional "-POD_<host>_<port>" suffix that StartEMLst appends.
+var unitIDPattern = regexp.MustCompile(`^(\[[^\]]+\]-[A-Z0-9]*-gen\d+-v\d+(?:-POD_[^_]+_\d+)?)_`)
+
+// parseUnitID extracts the unit name prefix from a cube name such as "[ARC]-OC-gen1-v3_head_BASE".
+// It returns false if the cube name does not follow the generateUnitID format.
+func parseUnitID(cubeName string) (string, bool) {
+	match := unitIDPattern.FindStringSubmatch(cubeName)
+	if match == nil {
+		return "", false
+	}
+	return match[1], true
+}
+
 // LoadJSONFileToString reads a JSON file and returns its contents as a string after validating it.
 func LoadJSONFileToString(filename string) (string, error) {
 	// Read the file contents
@@ -106,3 +310,50 @@ func LoadJSONFileToString(filename string) (string, error) {
 	// Return the JSON content as a string
 	return string(data), nil
 }
+
+// resolveTemplateVars replaces every JSON string value of the form "$name" with the number
+// vars[name]. It returns an error naming the first variable that has no value.
+func resolveTemplateVars(data []byte, vars map[string]float64) ([]byte, error) {
+	var doc interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+
+	resolved, err := resolveTemplateValue(doc, vars)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(resolved)
+}
+
+func resolveTemplateValue(v interface{}, vars map[string]float64) (interface{}, error) {
+	switch vv := v.(type) {
+	case string:
+		if !strings.HasPrefix(vv, "$") {
+			return vv, nil
+		}
+		name := strings.TrimPrefix(vv, "$")
+		value, ok := vars[name]
+		if !ok {
+			return nil, fmt.Errorf("unresolved template variable $%s", name)
+		}
+		return value, nil
+	case []interface{}:
+		for i, item := range vv {
+			r, err := resolveTemplateValue(item, vars)
+			if err != nil {
+				return nil, err
+			}
+			vv[i] = r
+		}
+	case map[string]interface{}:
+		for key, item := range vv {
+			r, err := resolveTemplateValue(item, vars)
+			if err != nil {
+				return nil, err
+			}
+			vv[key] = r
+		}
+	}
+	return v, nil
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"testing"
+)
+
+// Run with -race: every goroutine appends through appendUnitSafely, as spawnConstructsAroundSphere does.
+func TestAppendUnitSafelyConcurrent(t *testing.T) {
+	const units = 500
+	var allUnits []string
+	var wg sync.WaitGroup
+	for i := 0; i < units; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			appendUnitSafely(&allUnits, generateUnitID("TEST", "example.com", 1, i))
+		}(i)
+	}
+	wg.Wait()
+
+	if len(allUnits) != units {
+		t.Fatalf("got %d units, want %d", len(allUnits), units)
+	}
+	sort.Strings(allUnits)
+	for i := 1; i < len(allUnits); i++ {
+		if allUnits[i] == allUnits[i-1] {
+			t.Fatalf("unit %s appended twice", allUnits[i])
+		}
+	}
+}
+
+// minAngularSeparation returns the smallest angle, in radians, between two points as seen from center.
+func minAngularSeparation(points [][]float64, center []float64) float64 {
+	smallest := math.Pi
+	for i := range points {
+		for j := i + 1; j < len(points); j++ {
+			var dot, ni, nj float64
+			for k := 0; k < 3; k++ {
+				a, b := points[i][k]-center[k], points[j][k]-center[k]
+				dot += a * b
+				ni += a * a
+				nj += b * b
+			}
+			cos := math.Max(-1, math.Min(1, dot/math.Sqrt(ni*nj)))
+			smallest = math.Min(smallest, math.Acos(cos))
+		}
+	}
+	return smallest
+}
+
+func TestFibonacciSphereOffsetSpreadsSmallN(t *testing.T) {
+	center := []float64{5, -3, 12}
+	const radius = 40
+	original := minAngularSeparation(fibonacciSphere(4, radius, center), center)
+	offset := fibonacciSphereOffset(4, radius, center, 0.5)
+	improved := minAngularSeparation(offset, center)
+	if improved <= original {
+		t.Errorf("min separation for n=4 is %.1f° with the offset, %.1f° without; want an improvement",
+			improved*180/math.Pi, original*180/math.Pi)
+	}
+
+	for i, p := range offset {
+		if d := distance3(p, center); math.Abs(d-radius) > 1e-9 {
+			t.Errorf("point %d is %.6f from the center, want %d", i, d, radius)
+		}
+		if math.Abs(p[1]-center[1]) >= radius-1e-9 {
+			t.Errorf("point %d %v sits on a pole", i, p)
+		}
+	}
+}
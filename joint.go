@@ -0,0 +1,359 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"sync"
+	"time"
+)
+
+// JointController keeps an authenticated connection open and streams joint targets to the server.
+// SetTarget may be called at a high rate from a control loop; updates are coalesced so that only
+// the latest value per joint is sent on each tick.
+type JointController struct {
+	Param  string // Joint parameter driven by SetTarget (defaults to "motor_target_velocity")
+	Logger Logger // Receives flush failures (nil uses DefaultLogger)
+
+	conn    net.Conn
+	addr    string
+	mu      sync.Mutex
+	pending map[string]float64
+	stop    chan struct{}
+	done    chan struct{}
+
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// NewJointController connects to the server, authenticates, and starts flushing targets every
+// interval. Messages are framed with delim (empty uses the package default).
+func NewJointController(addr, pass, delim string, interval time.Duration) (*JointController, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("[JointController] interval must be positive, got %v", interval)
+	}
+
+	conn, _, err := dialAndAuth(addr, pass, delim)
+	if err != nil {
+		return nil, fmt.Errorf("[JointController] %v", err)
+	}
+
+	jc := &JointController{
+		Param:   "motor_target_velocity",
+		conn:    conn,
+		addr:    addr,
+		pending: make(map[string]float64),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go jc.run(interval)
+	return jc, nil
+}
+
+// SetTarget records the latest target for a joint. It never blocks on the network;
+// the value is sent on the next tick, replacing any unsent value for the same joint.
+func (jc *JointController) SetTarget(jointName string, value float64) {
+	jc.mu.Lock()
+	jc.pending[jointName] = value
+	jc.mu.Unlock()
+}
+
+// Close stops the flush loop, sends any remaining targets, and closes the connection.
+// Calling it again returns the first call's result.
+func (jc *JointController) Close() error {
+	jc.closeOnce.Do(func() {
+		close(jc.stop)
+		<-jc.done
+		jc.closeErr = jc.conn.Close()
+	})
+	return jc.closeErr
+}
+
+func (jc *JointController) run(interval time.Duration) {
+	defer close(jc.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			jc.flush()
+		case <-jc.stop:
+			jc.flush()
+			return
+		}
+	}
+}
+
+// flush sends the coalesced targets accumulated since the previous tick.
+func (jc *JointController) flush() {
+	jc.mu.Lock()
+	if len(jc.pending) == 0 {
+		jc.mu.Unlock()
+		return
+	}
+	targets := jc.pending
+	jc.pending = make(map[string]float64)
+	jc.mu.Unlock()
+
+	for jointName, value := range targets {
+		cmd := Message{
+			"type":       "set_joint_params",
+			"joint_name": jointName,
+			"params":     map[string]float64{jc.Param: value},
+		}
+		if err := sendJSONMessage(jc.conn, cmd); err != nil {
+			loggerOrDefault(jc.Logger).Warnf("[JointController] Failed to send target for joint %s to %s: %v", jointName, jc.addr, err)
+			continue
+		}
+		if _, err := readResponse(jc.conn); err != nil {
+			loggerOrDefault(jc.Logger).Warnf("[JointController] Error reading response for joint %s from %s: %v", jointName, jc.addr, err)
+		}
+	}
+}
+
+// getJointState queries the current state of a single joint (angle, velocity, motor settings, ...).
+// The server is expected to reply with {"joint_name": ..., "state": {...}}.
+func getJointState(conn net.Conn, jointName string) (map[string]float64, error) {
+	cmd := Message{
+		"type":       "get_joint_state",
+		"joint_name": jointName,
+	}
+	if err := sendJSONMessage(conn, cmd); err != nil {
+		return nil, fmt.Errorf("[getJointState] Failed to send command for joint %s: %v", jointName, err)
+	}
+	raw, err := readResponse(conn)
+	if err != nil {
+		return nil, fmt.Errorf("[getJointState] Failed to read response for joint %s: %v", jointName, err)
+	}
+	if err := responseError(raw); err != nil {
+		return nil, fmt.Errorf("[getJointState] Joint %s: %v", jointName, err)
+	}
+
+	var resp struct {
+		State map[string]float64 `json:"state"`
+	}
+	if err := json.Unmarshal([]byte(raw), &resp); err != nil {
+		reportRawResponse(raw)
+		return nil, fmt.Errorf("[getJointState] JSON unmarshal failed for joint %s: %v", jointName, err)
+	}
+	if resp.State == nil {
+		reportRawResponse(raw)
+		return nil, fmt.Errorf("[getJointState] No state returned for joint %s: %s", jointName, raw)
+	}
+	return resp.State, nil
+}
+
+// ErrJointNotFound is returned when the server reports that a joint does not exist.
+var ErrJointNotFound = errors.New("joint not found")
+
+// JointState is the feedback a joint reports: its current angle (radians), angular velocity,
+// and whether its motor is enabled.
+type JointState struct {
+	Name         string
+	Angle        float64
+	Velocity     float64
+	MotorEnabled bool
+}
+
+// GetJointState reads a joint's current angle, velocity, and motor state with get_joint_state.
+// The server replies with {"joint_name": ..., "state": {"angle": ..., "velocity": ...,
+// "motor_enabled": ...}}; motor_enabled may be a bool or 0/1. If the server says the joint does
+// not exist, the error wraps ErrJointNotFound.
+func GetJointState(conn net.Conn, jointName string) (JointState, error) {
+	state := JointState{Name: jointName}
+	if err := sendJSONMessage(conn, Message{"type": "get_joint_state", "joint_name": jointName}); err != nil {
+		return state, fmt.Errorf("[GetJointState] Failed to send command for joint %s: %v", jointName, err)
+	}
+	raw, err := readResponse(conn)
+	if err != nil {
+		return state, fmt.Errorf("[GetJointState] Failed to read response for joint %s: %v", jointName, err)
+	}
+	if err := responseError(raw); err != nil {
+		if isNotFound(err) {
+			return state, fmt.Errorf("[GetJointState] joint %s: %w", jointName, ErrJointNotFound)
+		}
+		return state, fmt.Errorf("[GetJointState] Joint %s: %v", jointName, err)
+	}
+
+	var resp struct {
+		State struct {
+			Angle        *float64        `json:"angle"`
+			Velocity     float64         `json:"velocity"`
+			MotorEnabled json.RawMessage `json:"motor_enabled"`
+		} `json:"state"`
+	}
+	if err := json.Unmarshal([]byte(raw), &resp); err != nil {
+		reportRawResponse(raw)
+		return state, fmt.Errorf("[GetJointState] JSON unmarshal failed for joint %s: %v", jointName, err)
+	}
+	if resp.State.Angle == nil {
+		reportRawResponse(raw)
+		return state, fmt.Errorf("[GetJointState] No angle returned for joint %s: %s", jointName, raw)
+	}
+	state.Angle = *resp.State.Angle
+	state.Velocity = resp.State.Velocity
+
+	// Older servers send motor_enabled as 0/1 rather than a bool
+	if len(resp.State.MotorEnabled) > 0 {
+		var enabled bool
+		var flag float64
+		if err := json.Unmarshal(resp.State.MotorEnabled, &enabled); err == nil {
+			state.MotorEnabled = enabled
+		} else if err := json.Unmarshal(resp.State.MotorEnabled, &flag); err == nil {
+			state.MotorEnabled = flag != 0
+		} else {
+			return state, fmt.Errorf("[GetJointState] Invalid motor_enabled for joint %s: %s", jointName, resp.State.MotorEnabled)
+		}
+	}
+	return state, nil
+}
+
+// getJointStatesBatch reads the state of several joints in a single round trip using get_joint_states.
+// If the server does not support the batch command, it falls back to one get_joint_state per joint.
+func getJointStatesBatch(conn net.Conn, jointNames []string) (map[string]map[string]float64, error) {
+	cmd := Message{
+		"type":        "get_joint_states",
+		"joint_names": jointNames,
+	}
+	if err := sendJSONMessage(conn, cmd); err != nil {
+		return nil, fmt.Errorf("[getJointStatesBatch] Failed to send command: %v", err)
+	}
+	raw, err := readResponse(conn)
+	if err != nil {
+		return nil, fmt.Errorf("[getJointStatesBatch] Failed to read response: %v", err)
+	}
+
+	var resp struct {
+		States map[string]map[string]float64 `json:"states"`
+	}
+	if responseError(raw) == nil && json.Unmarshal([]byte(raw), &resp) == nil && resp.States != nil {
+		return resp.States, nil
+	}
+
+	// Batch form unsupported: query each joint individually on the same connection
+	reportRawResponse(raw)
+	states := make(map[string]map[string]float64, len(jointNames))
+	for _, jointName := range jointNames {
+		state, err := getJointState(conn, jointName)
+		if err != nil {
+			return states, err
+		}
+		states[jointName] = state
+	}
+	return states, nil
+}
+
+// jointCube returns one of the cubes a tracked joint connects, used to find the pod that owns it.
+func jointCube(jointName string) (string, error) {
+	linkListMutex.Lock()
+	defer linkListMutex.Unlock()
+	for _, link := range globalCubeLinks {
+		if link.JointName == jointName {
+			return link.CubeA, nil
+		}
+	}
+	return "", fmt.Errorf("joint %s is not tracked", jointName)
+}
+
+// queryJoint sends a command to the pod that owns the joint's cubes and returns the raw response.
+func (s *SparseScanner) queryJoint(jointName string, msg Message) (string, error) {
+	cube, err := jointCube(jointName)
+	if err != nil {
+		return "", err
+	}
+	return s.queryCube(cube, msg)
+}
+
+// SetJointTorque applies a torque directly to a joint, as output by torque-control policies.
+// This is a separate control mode from driving motor_target_velocity. An error is returned
+// if the server reports that torque control is unsupported.
+func (s *SparseScanner) SetJointTorque(jointName string, torque float64) error {
+	if math.IsNaN(torque) || math.IsInf(torque, 0) {
+		return fmt.Errorf("[SetJointTorque] Invalid torque for joint %s: %v", jointName, torque)
+	}
+
+	raw, err := s.queryJoint(jointName, Message{
+		"type":       "set_joint_torque",
+		"joint_name": jointName,
+		"torque":     torque,
+	})
+	if err != nil {
+		return fmt.Errorf("[SetJointTorque] %v", err)
+	}
+	if err := responseError(raw); err != nil {
+		return fmt.Errorf("[SetJointTorque] Joint %s: torque control unsupported or rejected: %v", jointName, err)
+	}
+	return nil
+}
+
+// rangeOfMotionParams converts a range of motion in degrees to the joint limit parameters.
+// The server expects hinge limits in radians, with limit_lower below limit_upper.
+func rangeOfMotionParams(minDeg, maxDeg float64) (map[string]float64, error) {
+	for _, deg := range []float64{minDeg, maxDeg} {
+		if math.IsNaN(deg) || math.IsInf(deg, 0) {
+			return nil, fmt.Errorf("invalid angle %v", deg)
+		}
+	}
+	if minDeg >= maxDeg {
+		return nil, fmt.Errorf("minimum angle %.2f must be less than maximum angle %.2f", minDeg, maxDeg)
+	}
+	return map[string]float64{
+		"limit_lower": minDeg * math.Pi / 180.0,
+		"limit_upper": maxDeg * math.Pi / 180.0,
+	}, nil
+}
+
+// SetRangeOfMotion limits a joint to swing between minDeg and maxDeg (in degrees),
+// sending both limits in a single set_joint_params command.
+func (s *SparseScanner) SetRangeOfMotion(jointName string, minDeg, maxDeg float64) error {
+	params, err := rangeOfMotionParams(minDeg, maxDeg)
+	if err != nil {
+		return fmt.Errorf("[SetRangeOfMotion] Joint %s: %v", jointName, err)
+	}
+
+	raw, err := s.queryJoint(jointName, Message{
+		"type":       "set_joint_params",
+		"joint_name": jointName,
+		"params":     params,
+	})
+	if err != nil {
+		return fmt.Errorf("[SetRangeOfMotion] %v", err)
+	}
+	if err := responseError(raw); err != nil {
+		return fmt.Errorf("[SetRangeOfMotion] Joint %s: %v", jointName, err)
+	}
+	return nil
+}
+
+// SetRangesOfMotion applies a {minDeg, maxDeg} range to each joint in ranges over one connection
+// to the construct's server. All ranges are validated before anything is sent.
+func (c *Construct) SetRangesOfMotion(ranges map[string][2]float64) error {
+	paramsByJoint := make(map[string]map[string]float64, len(ranges))
+	for jointName, r := range ranges {
+		params, err := rangeOfMotionParams(r[0], r[1])
+		if err != nil {
+			return fmt.Errorf("[SetRangesOfMotion] Joint %s: %v", jointName, err)
+		}
+		paramsByJoint[jointName] = params
+	}
+
+	conn, _, err := c.connect()
+	if err != nil {
+		return fmt.Errorf("[SetRangesOfMotion] %v", err)
+	}
+	defer conn.Close()
+
+	for jointName, params := range paramsByJoint {
+		if _, err := sendCommand(conn, Message{
+			"type":       "set_joint_params",
+			"joint_name": jointName,
+			"params":     params,
+		}); err != nil {
+			return fmt.Errorf("[SetRangesOfMotion] Joint %s: %v", jointName, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"math"
+)
+
+// PositionGenerator produces n spawn positions around center. Implementations that cannot fit
+// n positions return as many as they can; callers must check the length of the result.
+type PositionGenerator interface {
+	Generate(n int, center []float64) [][]float64
+}
+
+// FibonacciGenerator spreads positions evenly over a sphere. It always returns n positions.
+type FibonacciGenerator struct {
+	Radius float64
+}
+
+// Generate returns n points on a Fibonacci sphere of Radius around center.
+func (g FibonacciGenerator) Generate(n int, center []float64) [][]float64 {
+	return fibonacciSphere(n, g.Radius, center)
+}
+
+// PackedGenerator places positions on a sphere no closer than MinDist to each other.
+// It returns fewer than n positions (possibly none) when the sphere is full.
+type PackedGenerator struct {
+	Radius  float64
+	MinDist float64
+}
+
+// Generate returns up to n points on the sphere, at least MinDist apart.
+func (g PackedGenerator) Generate(n int, center []float64) [][]float64 {
+	positions, err := packConstructs(n, g.Radius, g.MinDist, center)
+	if err != nil {
+		return [][]float64{}
+	}
+	return positions
+}
+
+// SeededGenerator places positions randomly on a sphere, reproducibly for a given Seed.
+// It always returns n positions.
+type SeededGenerator struct {
+	Seed   int64
+	Radius float64
+}
+
+// Generate returns n random points on the sphere, drawn from Seed.
+func (g SeededGenerator) Generate(n int, center []float64) [][]float64 {
+	return seededSpherePositions(g.Seed, n, g.Radius, center)
+}
+
+// GridGenerator lays positions out row by row on the XZ plane, centered on center.
+// Columns defaults to the smallest square grid that holds n. It always returns n positions.
+type GridGenerator struct {
+	Spacing float64
+	Columns int
+}
+
+// Generate returns n points on a grid of Columns columns spaced Spacing apart.
+func (g GridGenerator) Generate(n int, center []float64) [][]float64 {
+	points := make([][]float64, n)
+	if n == 0 {
+		return points
+	}
+	cols := g.Columns
+	if cols <= 0 {
+		cols = int(math.Ceil(math.Sqrt(float64(n))))
+	}
+	rows := (n + cols - 1) / cols
+
+	// Shift the grid so its middle sits on the center
+	offsetX := float64(cols-1) * g.Spacing / 2
+	offsetZ := float64(rows-1) * g.Spacing / 2
+	for i := 0; i < n; i++ {
+		points[i] = []float64{
+			center[0] + float64(i%cols)*g.Spacing - offsetX,
+			center[1],
+			center[2] + float64(i/cols)*g.Spacing - offsetZ,
+		}
+	}
+	return points
+}
+
+// RingGenerator spaces positions evenly on a circle of Radius in the XZ plane.
+// It always returns n positions.
+type RingGenerator struct {
+	Radius float64
+}
+
+// Generate returns n points on a ring of Radius around center.
+func (g RingGenerator) Generate(n int, center []float64) [][]float64 {
+	points := make([][]float64, n)
+	for i := 0; i < n; i++ {
+		theta := 2 * math.Pi * float64(i) / float64(n)
+		points[i] = []float64{
+			center[0] + math.Cos(theta)*g.Radius,
+			center[1],
+			center[2] + math.Sin(theta)*g.Radius,
+		}
+	}
+	return points
+}
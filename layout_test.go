@@ -0,0 +1,53 @@
+package main
+
+import "testing"
+
+func TestGeneratorsReturnNPositions(t *testing.T) {
+	center := []float64{10, 20, 30}
+	generators := map[string]PositionGenerator{
+		"fibonacci": FibonacciGenerator{Radius: 50},
+		"packed":    PackedGenerator{Radius: 50, MinDist: 1},
+		"seeded":    SeededGenerator{Seed: 42, Radius: 50},
+		"grid":      GridGenerator{Spacing: 5},
+		"grid cols": GridGenerator{Spacing: 5, Columns: 3},
+		"ring":      RingGenerator{Radius: 50},
+	}
+	for name, g := range generators {
+		for _, n := range []int{0, 1, 7, 25} {
+			positions := g.Generate(n, center)
+			if len(positions) != n {
+				t.Errorf("%s: Generate(%d) returned %d positions", name, n, len(positions))
+			}
+			if err := validatePositions(positions); err != nil {
+				t.Errorf("%s: Generate(%d): %v", name, n, err)
+			}
+		}
+	}
+}
+
+func TestPackedGeneratorReturnsFewerWhenFull(t *testing.T) {
+	// A sphere of radius 1 cannot hold 100 points that are 1.5 apart
+	positions := PackedGenerator{Radius: 1, MinDist: 1.5}.Generate(100, []float64{0, 0, 0})
+	if len(positions) >= 100 {
+		t.Fatalf("got %d positions, want fewer than 100", len(positions))
+	}
+	for i := range positions {
+		for j := i + 1; j < len(positions); j++ {
+			if d := distance3(positions[i], positions[j]); d < 1.5 {
+				t.Errorf("positions %d and %d are %.3f apart, want at least 1.5", i, j, d)
+			}
+		}
+	}
+}
+
+func TestSeededGeneratorIsReproducible(t *testing.T) {
+	a := SeededGenerator{Seed: 7, Radius: 10}.Generate(5, []float64{0, 0, 0})
+	b := SeededGenerator{Seed: 7, Radius: 10}.Generate(5, []float64{0, 0, 0})
+	for i := range a {
+		for j := range a[i] {
+			if a[i][j] != b[i][j] {
+				t.Fatalf("position %d differs between runs: %v vs %v", i, a[i], b[i])
+			}
+		}
+	}
+}
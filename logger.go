@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+)
+
+// Logger receives the package's diagnostics. Implement it to route them to slog, zap, or any
+// other logging library; set it on a SparseScanner, Construct, or Session, or replace DefaultLogger.
+type Logger interface {
+	Debugf(format string, args ...interface{})
+	Infof(format string, args ...interface{})
+	Warnf(format string, args ...interface{})
+	Errorf(format string, args ...interface{})
+}
+
+// DefaultLogger is used by package-level functions and by values whose Logger is nil.
+var DefaultLogger Logger = StdoutLogger{}
+
+// StdoutLogger prints each message on its own line. Debug messages are only printed when Debug is set.
+type StdoutLogger struct {
+	Debug bool
+}
+
+func (l StdoutLogger) Debugf(format string, args ...interface{}) {
+	if l.Debug {
+		fmt.Println(fmt.Sprintf(format, args...))
+	}
+}
+
+func (l StdoutLogger) Infof(format string, args ...interface{}) {
+	fmt.Println(fmt.Sprintf(format, args...))
+}
+
+func (l StdoutLogger) Warnf(format string, args ...interface{}) {
+	fmt.Println(fmt.Sprintf(format, args...))
+}
+
+func (l StdoutLogger) Errorf(format string, args ...interface{}) {
+	fmt.Println(fmt.Sprintf(format, args...))
+}
+
+// NopLogger discards every message.
+type NopLogger struct{}
+
+func (NopLogger) Debugf(format string, args ...interface{}) {}
+func (NopLogger) Infof(format string, args ...interface{})  {}
+func (NopLogger) Warnf(format string, args ...interface{})  {}
+func (NopLogger) Errorf(format string, args ...interface{}) {}
+
+// loggerOrDefault returns l, or DefaultLogger if l is nil.
+func loggerOrDefault(l Logger) Logger {
+	if l == nil {
+		return DefaultLogger
+	}
+	return l
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// Metrics receives counters and durations from scans, spawns, and despawns. Implement it to back
+// them with prometheus/client_golang or any other metrics library; set it on a SparseScanner or
+// Construct, or replace DefaultMetrics.
+type Metrics interface {
+	PodScanned(success bool)             // A pod answered (or failed) a scan
+	ObserveScanDuration(d time.Duration) // Time taken to scan one pod
+	CubeSpawned()                        // A cube was spawned
+	CubeSpawnFailed()                    // A cube failed to spawn
+	ObserveSpawnDuration(d time.Duration)
+	CubesDespawned(n int) // n despawn commands were sent
+}
+
+// DefaultMetrics is used by package-level functions and by values whose Metrics is nil.
+var DefaultMetrics Metrics = NopMetrics{}
+
+// NopMetrics discards every measurement.
+type NopMetrics struct{}
+
+func (NopMetrics) PodScanned(success bool)              {}
+func (NopMetrics) ObserveScanDuration(d time.Duration)  {}
+func (NopMetrics) CubeSpawned()                         {}
+func (NopMetrics) CubeSpawnFailed()                     {}
+func (NopMetrics) ObserveSpawnDuration(d time.Duration) {}
+func (NopMetrics) CubesDespawned(n int)                 {}
+
+// MemoryMetrics keeps every measurement in memory. It is safe for concurrent use and is meant
+// for tests and quick inspection; read it with Snapshot.
+type MemoryMetrics struct {
+	mu   sync.Mutex
+	snap MetricsSnapshot
+}
+
+// MetricsSnapshot is a copy of the measurements recorded by a MemoryMetrics.
+type MetricsSnapshot struct {
+	PodsScanned    int
+	PodsSucceeded  int
+	PodsFailed     int
+	CubesSpawned   int
+	SpawnFailures  int
+	CubesDespawned int
+	ScanDurations  []time.Duration
+	SpawnDurations []time.Duration
+}
+
+func (m *MemoryMetrics) PodScanned(success bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.snap.PodsScanned++
+	if success {
+		m.snap.PodsSucceeded++
+	} else {
+		m.snap.PodsFailed++
+	}
+}
+
+func (m *MemoryMetrics) ObserveScanDuration(d time.Duration) {
+	m.mu.Lock()
+	m.snap.ScanDurations = append(m.snap.ScanDurations, d)
+	m.mu.Unlock()
+}
+
+func (m *MemoryMetrics) CubeSpawned() {
+	m.mu.Lock()
+	m.snap.CubesSpawned++
+	m.mu.Unlock()
+}
+
+func (m *MemoryMetrics) CubeSpawnFailed() {
+	m.mu.Lock()
+	m.snap.SpawnFailures++
+	m.mu.Unlock()
+}
+
+func (m *MemoryMetrics) ObserveSpawnDuration(d time.Duration) {
+	m.mu.Lock()
+	m.snap.SpawnDurations = append(m.snap.SpawnDurations, d)
+	m.mu.Unlock()
+}
+
+func (m *MemoryMetrics) CubesDespawned(n int) {
+	m.mu.Lock()
+	m.snap.CubesDespawned += n
+	m.mu.Unlock()
+}
+
+// Snapshot returns a copy of everything recorded so far.
+func (m *MemoryMetrics) Snapshot() MetricsSnapshot {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	snap := m.snap
+	snap.ScanDurations = append([]time.Duration(nil), m.snap.ScanDurations...)
+	snap.SpawnDurations = append([]time.Duration(nil), m.snap.SpawnDurations...)
+	return snap
+}
+
+// metricsOrDefault returns m, or DefaultMetrics if m is nil.
+func metricsOrDefault(m Metrics) Metrics {
+	if m == nil {
+		return DefaultMetrics
+	}
+	return m
+}
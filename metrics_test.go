@@ -0,0 +1,63 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemoryMetricsRecordsScan(t *testing.T) {
+	ports := append(mockPodPorts(t, 2, podReply([]string{"a_BASE"})), reservePort(t))
+	metrics := &MemoryMetrics{}
+	s := newTestScanner(ports...)
+	s.Metrics = metrics
+	s.ScanAllPods()
+
+	snap := metrics.Snapshot()
+	if snap.PodsScanned != 3 || snap.PodsSucceeded != 2 || snap.PodsFailed != 1 {
+		t.Errorf("pods scanned/succeeded/failed = %d/%d/%d, want 3/2/1", snap.PodsScanned, snap.PodsSucceeded, snap.PodsFailed)
+	}
+	if len(snap.ScanDurations) != 3 {
+		t.Errorf("recorded %d scan durations, want 3", len(snap.ScanDurations))
+	}
+}
+
+func TestMemoryMetricsRecordsSpawnAndDespawn(t *testing.T) {
+	quietPackage(t)
+	metrics := &MemoryMetrics{}
+	c := newTestConstruct(t, newMockServer(t, podReply(nil)).Addr(), "metered")
+	c.Metrics = metrics
+	if err := c.Spawn([]float64{100, 0, 0}, []float64{0, 0, 0}); err != nil {
+		t.Fatalf("Spawn: %v", err)
+	}
+	if err := c.Despawn(); err != nil {
+		t.Fatalf("Despawn: %v", err)
+	}
+
+	snap := metrics.Snapshot()
+	if snap.CubesSpawned != 3 || snap.SpawnFailures != 0 || len(snap.SpawnDurations) != 3 {
+		t.Errorf("spawned %d, failed %d, %d durations; want 3, 0, 3", snap.CubesSpawned, snap.SpawnFailures, len(snap.SpawnDurations))
+	}
+	if snap.CubesDespawned != 3 {
+		t.Errorf("despawned %d cubes, want 3", snap.CubesDespawned)
+	}
+
+	unreachable := newTestConstruct(t, podAddr("127.0.0.1", reservePort(t)), "offline")
+	unreachable.Metrics = metrics
+	if err := unreachable.Spawn([]float64{100, 0, 0}, []float64{0, 0, 0}); err == nil {
+		t.Fatal("Spawn reached a server that is not listening")
+	}
+	if metrics.Snapshot().SpawnFailures == 0 {
+		t.Error("the failed spawn was not recorded")
+	}
+}
+
+func TestMemoryMetricsSnapshotIsACopy(t *testing.T) {
+	metrics := &MemoryMetrics{}
+	metrics.ObserveScanDuration(time.Second)
+	snap := metrics.Snapshot()
+	snap.ScanDurations[0] = 0
+	metrics.ObserveScanDuration(2 * time.Second)
+	if got := metrics.Snapshot().ScanDurations; got[0] != time.Second || len(got) != 2 {
+		t.Errorf("ScanDurations = %v, want [1s 2s]", got)
+	}
+}
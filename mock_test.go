@@ -0,0 +1,303 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// mockServer is an in-process pod for tests. The first message on every connection is taken to be
+// the auth message and answered with auth_success; every later message is recorded and passed to
+// reply, whose result is sent back with the delimiter. An empty reply sends nothing.
+type mockServer struct {
+	ln    net.Listener
+	reply func(msg string) string
+
+	mu    sync.Mutex
+	msgs  []string
+	dials int
+	conns []net.Conn
+}
+
+// newMockServer starts a mock pod on a free loopback port. It is closed when the test ends.
+func newMockServer(t testing.TB, reply func(msg string) string) *mockServer {
+	return newMockServerAt(t, "127.0.0.1:0", reply)
+}
+
+// newMockServerAt starts a mock pod on addr, for package-level functions that dial serverAddr.
+func newMockServerAt(t testing.TB, addr string, reply func(msg string) string) *mockServer {
+	t.Helper()
+	m, err := startMockServer(addr, reply)
+	if err != nil {
+		t.Fatalf("failed to listen on %s: %v", addr, err)
+	}
+	t.Cleanup(m.Close)
+	return m
+}
+
+// startMockServer starts a mock pod on addr. The caller must close it.
+func startMockServer(addr string, reply func(msg string) string) (*mockServer, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	m := &mockServer{ln: ln, reply: reply}
+	go m.serve()
+	return m, nil
+}
+
+func (m *mockServer) serve() {
+	for {
+		conn, err := m.ln.Accept()
+		if err != nil {
+			return
+		}
+		m.mu.Lock()
+		m.dials++
+		m.conns = append(m.conns, conn)
+		m.mu.Unlock()
+		go m.handle(conn)
+	}
+}
+
+func (m *mockServer) handle(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	delim := []byte(delimiter)
+	var frame []byte
+	authed := false
+	for {
+		b, err := reader.ReadByte()
+		if err != nil {
+			return
+		}
+		frame = append(frame, b)
+		if !bytes.HasSuffix(frame, delim) {
+			continue
+		}
+		msg := string(bytes.TrimSpace(frame[:len(frame)-len(delim)]))
+		frame = frame[:0]
+		if !authed {
+			authed = true
+			conn.Write([]byte(`{"type":"auth_success"}` + delimiter))
+			continue
+		}
+		m.mu.Lock()
+		m.msgs = append(m.msgs, msg)
+		m.mu.Unlock()
+		if m.reply == nil {
+			continue
+		}
+		if out := m.reply(msg); out != "" {
+			conn.Write([]byte(out + delimiter))
+		}
+	}
+}
+
+// newMockPods starts n mock pods on loopback ports step apart, as a scanner with StartPort, PortStep,
+// and NumPods expects them, and returns the first port.
+func newMockPods(t testing.TB, n, step int, reply func(msg string) string) (int, []*mockServer) {
+	t.Helper()
+	for attempt := 0; attempt < 50; attempt++ {
+		first := newMockServer(t, reply)
+		_, base := first.HostPort(t)
+		pods := []*mockServer{first}
+		for i := 1; i < n; i++ {
+			m, err := startMockServer(podAddr("127.0.0.1", base+i*step), reply)
+			if err != nil {
+				break
+			}
+			t.Cleanup(m.Close)
+			pods = append(pods, m)
+		}
+		if len(pods) == n {
+			return base, pods
+		}
+		for _, m := range pods {
+			m.Close()
+		}
+	}
+	t.Fatalf("no run of %d free ports %d apart", n, step)
+	return 0, nil
+}
+
+// podReply answers a scan the way a pod holding cubes and planets does. Planets are given as
+// name:biome pairs and are placed at increasing x coordinates. Other commands succeed.
+func podReply(cubes []string, planets ...string) func(string) string {
+	cubeList, _ := json.Marshal(map[string][]string{"cubes": cubes})
+	var records []string
+	for i, planet := range planets {
+		name, biome, _ := strings.Cut(planet, ":")
+		if biome == "" {
+			biome = "0"
+		}
+		records = append(records, fmt.Sprintf(`{"Name":%q,"Position":{"x":%d,"y":0,"z":0},"Seed":%d,"BiomeType":%s}`, name, 1000*(i+1), i+1, biome))
+	}
+	planetList := `{"planets":[` + strings.Join(records, ",") + `]}`
+	return func(msg string) string {
+		switch messageType(msg) {
+		case "get_cube_list":
+			return string(cubeList)
+		case "get_planets":
+			return planetList
+		}
+		return replySuccess(msg)
+	}
+}
+
+// Addr returns the host:port the mock pod listens on.
+func (m *mockServer) Addr() string {
+	return m.ln.Addr().String()
+}
+
+// HostPort splits Addr for APIs that take the host and port separately.
+func (m *mockServer) HostPort(t testing.TB) (string, int) {
+	t.Helper()
+	tcp := m.ln.Addr().(*net.TCPAddr)
+	return tcp.IP.String(), tcp.Port
+}
+
+// Messages returns every message received after auth, in order.
+func (m *mockServer) Messages() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]string(nil), m.msgs...)
+}
+
+// MessagesOfType returns the decoded messages whose "type" is typ.
+func (m *mockServer) MessagesOfType(typ string) []Message {
+	var out []Message
+	for _, raw := range m.Messages() {
+		if msg := decodeMessage(raw); msg["type"] == typ {
+			out = append(out, msg)
+		}
+	}
+	return out
+}
+
+// waitForMessages waits up to two seconds for the mock pod to receive n messages of type typ.
+// Use it for commands that are sent without waiting for a reply.
+func waitForMessages(t testing.TB, m *mockServer, typ string, n int) []Message {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		msgs := m.MessagesOfType(typ)
+		if len(msgs) >= n {
+			return msgs
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("received %d %s messages, want %d", len(msgs), typ, n)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// Dials returns how many connections the mock pod has accepted.
+func (m *mockServer) Dials() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.dials
+}
+
+// Close stops the listener and drops every open connection.
+func (m *mockServer) Close() {
+	m.ln.Close()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, conn := range m.conns {
+		conn.Close()
+	}
+}
+
+// decodeMessage decodes a JSON message, returning nil if it is not a JSON object.
+func decodeMessage(raw string) Message {
+	var msg Message
+	if err := json.Unmarshal([]byte(raw), &msg); err != nil {
+		return nil
+	}
+	return msg
+}
+
+// messageType returns the "type" of a JSON message, or "" if it has none.
+func messageType(raw string) string {
+	typ, _ := decodeMessage(raw)["type"].(string)
+	return typ
+}
+
+// messagePosition returns a message's "position" array, or nil if it has none.
+func messagePosition(msg Message) []float64 {
+	raw, _ := msg["position"].([]interface{})
+	var pos []float64
+	for _, v := range raw {
+		f, _ := v.(float64)
+		pos = append(pos, f)
+	}
+	return pos
+}
+
+// replySuccess answers every message with a plain success status.
+func replySuccess(string) string {
+	return `{"status":"success"}`
+}
+
+// dialMock connects and authenticates to a mock pod.
+func dialMock(t testing.TB, m *mockServer) net.Conn {
+	t.Helper()
+	conn, _, err := dialAndAuth(m.Addr(), authPass, delimiter)
+	if err != nil {
+		t.Fatalf("failed to dial mock server: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// quietPackage silences the package logger, makes dials fail fast, and clears the tracked cubes,
+// joints, and positions, restoring everything when the test ends.
+func quietPackage(t testing.TB) {
+	t.Helper()
+	logger, attempts, delay, ackTimeout := DefaultLogger, DialAttempts, DialBaseDelay, AckTimeout
+	DefaultLogger, DialAttempts, DialBaseDelay = NopLogger{}, 1, time.Millisecond
+	resetTracking()
+	t.Cleanup(func() {
+		DefaultLogger, DialAttempts, DialBaseDelay, AckTimeout = logger, attempts, delay, ackTimeout
+		resetTracking()
+	})
+}
+
+// discardStdout sends the package's fmt.Print output to /dev/null until the test ends.
+func discardStdout(t testing.TB) {
+	t.Helper()
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatalf("failed to open %s: %v", os.DevNull, err)
+	}
+	stdout := os.Stdout
+	os.Stdout = devNull
+	t.Cleanup(func() {
+		os.Stdout = stdout
+		devNull.Close()
+	})
+}
+
+// resetTracking clears the package-level cube, joint, and position tracking.
+func resetTracking() {
+	cubeListMutex.Lock()
+	globalCubeList = nil
+	cubeListMutex.Unlock()
+	linkListMutex.Lock()
+	globalCubeLinks = nil
+	linkListMutex.Unlock()
+	positionMutex.Lock()
+	occupiedPositions = nil
+	positionMutex.Unlock()
+	cubeIDMutex.Lock()
+	serverCubeIDs = map[string]string{}
+	cubeIDMutex.Unlock()
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+// PodDiff kinds reported by Rescan and Monitor.
+const (
+	PodAppeared    = "appeared"    // Pod answered this scan but not the previous one
+	PodDisappeared = "disappeared" // Pod answered the previous scan but not this one
+	PodChanged     = "changed"     // Pod answered both scans with different cubes or planets
+)
+
+// PodDiff describes how one pod changed between two scans.
+type PodDiff struct {
+	Host           string
+	Port           int
+	Kind           string
+	AddedCubes     []string
+	RemovedCubes   []string
+	AddedPlanets   []string
+	RemovedPlanets []string
+}
+
+// Rescan clears the previous results, scans all pods again, and returns what changed
+// since the previous scan. Pods that were unreachable in both scans are not reported.
+func (s *SparseScanner) Rescan() []PodDiff {
+	previous := s.Results
+
+	s.resultsMu.Lock()
+	s.Results = nil
+	s.resultsMu.Unlock()
+	s.mapsMu.Lock()
+	s.PlanetsMap = make(map[string]PlanetRecord)
+	s.CubesMap = make(map[string]string)
+	s.cubeAddrs = make(map[string]string)
+	s.mapsMu.Unlock()
+	s.ScanAllPods()
+
+	return diffResults(previous, s.Results)
+}
+
+// Monitor rescans every interval and sends the non-empty diffs on the returned channel until ctx
+// is cancelled, at which point the channel is closed. The scanner must not be used by other
+// goroutines while it is being monitored.
+func (s *SparseScanner) Monitor(ctx context.Context, interval time.Duration) <-chan []PodDiff {
+	diffs := make(chan []PodDiff)
+	go func() {
+		defer close(diffs)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			changes := s.Rescan()
+			if len(changes) == 0 {
+				continue
+			}
+			select {
+			case diffs <- changes:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return diffs
+}
+
+// diffResults compares two sets of scan results pod by pod, ordered by host and port.
+func diffResults(before, after []PodResult) []PodDiff {
+	old := make(map[string]PodResult)
+	for _, res := range before {
+		if res.Success {
+			old[podAddr(res.Host, res.Port)] = res
+		}
+	}
+	current := make(map[string]PodResult)
+	for _, res := range after {
+		if res.Success {
+			current[podAddr(res.Host, res.Port)] = res
+		}
+	}
+
+	var diffs []PodDiff
+	for addr, res := range current {
+		prev, existed := old[addr]
+		if !existed {
+			diffs = append(diffs, PodDiff{
+				Host:         res.Host,
+				Port:         res.Port,
+				Kind:         PodAppeared,
+				AddedCubes:   res.Cubes,
+				AddedPlanets: planetNames(res.Planets),
+			})
+			continue
+		}
+
+		diff := PodDiff{Host: res.Host, Port: res.Port, Kind: PodChanged}
+		diff.AddedCubes, diff.RemovedCubes = diffNames(prev.Cubes, res.Cubes)
+		diff.AddedPlanets, diff.RemovedPlanets = diffNames(planetNames(prev.Planets), planetNames(res.Planets))
+		if len(diff.AddedCubes)+len(diff.RemovedCubes)+len(diff.AddedPlanets)+len(diff.RemovedPlanets) > 0 {
+			diffs = append(diffs, diff)
+		}
+	}
+	for addr, prev := range old {
+		if _, ok := current[addr]; !ok {
+			diffs = append(diffs, PodDiff{
+				Host:           prev.Host,
+				Port:           prev.Port,
+				Kind:           PodDisappeared,
+				RemovedCubes:   prev.Cubes,
+				RemovedPlanets: planetNames(prev.Planets),
+			})
+		}
+	}
+
+	sort.Slice(diffs, func(i, j int) bool {
+		if diffs[i].Host != diffs[j].Host {
+			return diffs[i].Host < diffs[j].Host
+		}
+		return diffs[i].Port < diffs[j].Port
+	})
+	return diffs
+}
+
+func planetNames(planets []Planet) []string {
+	names := make([]string, len(planets))
+	for i, planet := range planets {
+		names[i] = planet.Name
+	}
+	return names
+}
+
+// diffNames returns the names only in after (added) and only in before (removed).
+func diffNames(before, after []string) (added, removed []string) {
+	inBefore := make(map[string]bool, len(before))
+	for _, name := range before {
+		inBefore[name] = true
+	}
+	inAfter := make(map[string]bool, len(after))
+	for _, name := range after {
+		inAfter[name] = true
+		if !inBefore[name] {
+			added = append(added, name)
+		}
+	}
+	for _, name := range before {
+		if !inAfter[name] {
+			removed = append(removed, name)
+		}
+	}
+	return added, removed
+}
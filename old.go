@@ -3,7 +3,6 @@ package main
 import (
 	"encoding/json"
 	"fmt"
-	"net"
 	"sync"
 	"time"
 )
@@ -48,7 +47,12 @@ func staticBuilder(unitName string, offset []float64) {
 	var wg sync.WaitGroup
 	for _, cube := range cubes {
 		wg.Add(1)
-		go spawnCube(cube, &wg)
+		go func(cube Cube) {
+			defer wg.Done()
+			if err := spawnCube(cube); err != nil {
+				fmt.Println(err)
+			}
+		}(cube)
 	}
 	wg.Wait()
 	fmt.Println("✅ Humanoid Cubes Spawned")
@@ -64,12 +68,12 @@ func staticBuilder(unitName string, offset []float64) {
 
 	// Connect all body parts
 	chains := [][]string{
-		{unitName + "_head_BASE", unitName + "_body_BASE"},
-		{unitName + "_body_BASE", unitName + "_left_arm_BASE", unitName + "_left_leg_BASE", unitName + "_left_foot_BASE"},
-		{unitName + "_body_BASE", unitName + "_right_arm_BASE", unitName + "_right_leg_BASE", unitName + "_right_foot_BASE"},
+		{unitName + "_head", unitName + "_body"},
+		{unitName + "_body", unitName + "_left_arm", unitName + "_left_leg", unitName + "_left_foot"},
+		{unitName + "_body", unitName + "_right_arm", unitName + "_right_leg", unitName + "_right_foot"},
 	}
 
-	if err := linkCubeChains(chains, "hinge", jointParams); err != nil {
+	if _, err := linkCubeChains(resolveChains(chains), "hinge", jointParams); err != nil {
 		fmt.Println("❌ Error linking cubes:", err)
 	}
 
@@ -91,14 +95,14 @@ func findClosestJoint(targetCube string) string {
 }
 
 func rotateLegDemo(jointName string) {
-	conn, err := net.Dial("tcp", serverAddr)
+	conn, err := dialTCP(serverAddr)
 	if err != nil {
 		fmt.Printf("[rotateLegDemo] Failed to connect: %v\n", err)
 		return
 	}
 	defer conn.Close()
 
-	if _, err := conn.Write([]byte(authPass + delimiter)); err != nil {
+	if _, err := conn.Write(authFrame(authPass, delimiter)); err != nil {
 		fmt.Println("[rotateLegDemo] Auth write error:", err)
 		return
 	}
@@ -127,40 +131,18 @@ func rotateLegDemo(jointName string) {
 }
 
 func rotateCube(cubeName string, rotationDelta []float64) {
-	conn, err := net.Dial("tcp", serverAddr)
+	conn, _, err := dialAndAuth(serverAddr, authPass, delimiter)
 	if err != nil {
-		fmt.Printf("[rotateCube] Failed to connect: %v\n", err)
+		fmt.Printf("[rotateCube] %v\n", err)
 		return
 	}
 	defer conn.Close()
 
-	if _, err := conn.Write([]byte(authPass + delimiter)); err != nil {
-		fmt.Println("[rotateCube] Auth write error:", err)
-		return
-	}
-	if _, err := readResponse(conn); err != nil {
-		fmt.Println("[rotateCube] Auth response error:", err)
-		return
-	}
-
-	cmd := Message{
-		"type":   "apply_force",
-		"rotate": rotationDelta, // in degrees
-		"target": cubeName,      // optional, in case your server supports targeted cube
-	}
-
-	if err := sendJSONMessage(conn, cmd); err != nil {
-		fmt.Printf("[rotateCube] Failed to send rotate command: %v\n", err)
+	if err := ApplyForce(conn, cubeName, []float64{0, 0, 0}, rotationDelta); err != nil {
+		fmt.Printf("[rotateCube] Failed to rotate %s: %v\n", cubeName, err)
 		return
 	}
-
-	resp, err := readResponse(conn)
-	if err != nil {
-		fmt.Printf("[rotateCube] Failed to read rotate response: %v\n", err)
-		return
-	}
-
-	fmt.Printf("[rotateCube] Server response: %s\n", resp)
+	fmt.Printf("[rotateCube] Applied torque %v to %s\n", rotationDelta, cubeName)
 }
 
 func rotateAllJointsForCube(targetCube string) {
@@ -170,13 +152,13 @@ func rotateAllJointsForCube(targetCube string) {
 		if link.CubeA == targetCube || link.CubeB == targetCube {
 			fmt.Printf("➡️ Rotating joint: %s (%s <-> %s)\n", link.JointName, link.CubeA, link.CubeB)
 
-			conn, err := net.Dial("tcp", serverAddr)
+			conn, err := dialTCP(serverAddr)
 			if err != nil {
 				fmt.Printf("[rotateAllJointsForCube] Failed to connect for joint %s: %v\n", link.JointName, err)
 				continue
 			}
 
-			if _, err := conn.Write([]byte(authPass + delimiter)); err != nil {
+			if _, err := conn.Write(authFrame(authPass, delimiter)); err != nil {
 				conn.Close()
 				continue
 			}
@@ -201,7 +183,7 @@ func rotateAllJointsForCube(targetCube string) {
 }
 
 func getJointsForCube(cubeName string) []string {
-	conn, err := net.Dial("tcp", serverAddr)
+	conn, err := dialTCP(serverAddr)
 	if err != nil {
 		fmt.Println("[getJointsForCube] Failed to connect:", err)
 		return nil
@@ -209,7 +191,7 @@ func getJointsForCube(cubeName string) []string {
 	defer conn.Close()
 
 	// Authenticate
-	if _, err := conn.Write([]byte(authPass + delimiter)); err != nil {
+	if _, err := conn.Write(authFrame(authPass, delimiter)); err != nil {
 		fmt.Println("[getJointsForCube] Auth write error:", err)
 		return nil
 	}
@@ -234,17 +216,26 @@ func getJointsForCube(cubeName string) []string {
 		return nil
 	}
 
+	joints, err := parseJointsForCube(respRaw)
+	if err != nil {
+		fmt.Println("[getJointsForCube]", err)
+		return nil
+	}
+	return joints
+}
+
+// parseJointsForCube decodes a get_joints_for_cube response into its joint names.
+func parseJointsForCube(raw string) ([]string, error) {
 	var resp struct {
 		Type     string   `json:"type"`
 		CubeName string   `json:"cube_name"`
 		Joints   []string `json:"joints"`
 	}
-	if err := json.Unmarshal([]byte(respRaw), &resp); err != nil {
-		fmt.Println("[getJointsForCube] JSON unmarshal failed:", err)
-		return nil
+	if err := json.Unmarshal([]byte(raw), &resp); err != nil {
+		reportRawResponse(raw)
+		return nil, fmt.Errorf("JSON unmarshal failed: %v", err)
 	}
-
-	return resp.Joints
+	return resp.Joints, nil
 }
 
 func rotateCubeJoints(cubeName string, velocity float64, duration time.Duration) {
@@ -258,7 +249,7 @@ func rotateCubeJoints(cubeName string, velocity float64, duration time.Duration)
 
 	for _, joint := range joints {
 		go func(jn string) {
-			conn, err := net.Dial("tcp", serverAddr)
+			conn, err := dialTCP(serverAddr)
 			if err != nil {
 				fmt.Printf("[rotateCubeJoints] Connect failed: %v\n", err)
 				return
@@ -266,7 +257,7 @@ func rotateCubeJoints(cubeName string, velocity float64, duration time.Duration)
 			defer conn.Close()
 
 			// Authenticate
-			conn.Write([]byte(authPass + delimiter))
+			conn.Write(authFrame(authPass, delimiter))
 			readResponse(conn)
 
 			// Enable motor
@@ -306,7 +297,7 @@ func rotateCubeJoints(cubeName string, velocity float64, duration time.Duration)
 // It prints both the authentication response and the command response.
 func testLinkBodyCubes(prefix string, jointType string, jointParams map[string]float64) {
 	// Connect to the server.
-	conn, err := net.Dial("tcp", serverAddr)
+	conn, err := dialTCP(serverAddr)
 	if err != nil {
 		fmt.Println("[testLinkBodyCubes] Error connecting:", err)
 		return
@@ -314,7 +305,7 @@ func testLinkBodyCubes(prefix string, jointType string, jointParams map[string]f
 	defer conn.Close()
 
 	// Authenticate with the server.
-	if _, err := conn.Write([]byte(authPass + delimiter)); err != nil {
+	if _, err := conn.Write(authFrame(authPass, delimiter)); err != nil {
 		fmt.Println("[testLinkBodyCubes] Auth write error:", err)
 		return
 	}
@@ -363,14 +354,14 @@ func stiffenAllJointsBULK() {
 		go func(joint CubeLink) {
 			defer wg.Done()
 
-			conn, err := net.Dial("tcp", serverAddr)
+			conn, err := dialTCP(serverAddr)
 			if err != nil {
 				fmt.Printf("[stiffenAllJoints] Failed to connect for joint %s: %v\n", joint.JointName, err)
 				return
 			}
 			defer conn.Close()
 
-			if _, err := conn.Write([]byte(authPass + delimiter)); err != nil {
+			if _, err := conn.Write(authFrame(authPass, delimiter)); err != nil {
 				fmt.Printf("[stiffenAllJoints] Auth write error for joint %s: %v\n", joint.JointName, err)
 				return
 			}
@@ -397,39 +388,9 @@ func setMouthColorYellow() {
 		"body2_BASE",
 	}
 
-	var wg sync.WaitGroup
-	for _, cubeName := range mouthCubes {
-		wg.Add(1)
-		go func(name string) {
-			defer wg.Done()
-			conn, err := net.Dial("tcp", serverAddr)
-			if err != nil {
-				fmt.Printf("[Color] Failed to connect for cube %s: %v\n", name, err)
-				return
-			}
-			defer conn.Close()
-
-			if _, err := conn.Write([]byte(authPass + delimiter)); err != nil {
-				fmt.Printf("[Color] Auth write error for cube %s: %v\n", name, err)
-				return
-			}
-			if _, err := readResponse(conn); err != nil {
-				fmt.Printf("[Color] Auth response error for cube %s: %v\n", name, err)
-				return
-			}
-
-			colorMsg := Message{
-				"type":      "set_color",
-				"cube_name": name,
-				"hex":       "#FFFF00", // Yellow
-			}
-
-			if err := sendJSONMessage(conn, colorMsg); err != nil {
-				fmt.Printf("[Color] Failed to send color change for cube %s: %v\n", name, err)
-			}
-		}(cubeName)
+	if err := SetCubesColor(mouthCubes, "#FFFF00"); err != nil { // Yellow
+		fmt.Println("[Color]", err)
 	}
-	wg.Wait()
 }
 
 func stiffenAllJoints() {
@@ -442,39 +403,43 @@ func stiffenAllJoints() {
 		"motor_max_impulse":     1000.0,
 	}
 
-	// We'll spawn one goroutine per joint in globalCubeLinks.
+	// Split the joints into one bulk update per connection.
+	updates := trackedJointUpdates(params)
+	workers := defaultMaxWorkers
+	if len(updates) < workers {
+		workers = len(updates)
+	}
+	batches := make([]map[string]map[string]float64, workers)
+	i := 0
+	for jointName, jointParams := range updates {
+		if batches[i%workers] == nil {
+			batches[i%workers] = make(map[string]map[string]float64)
+		}
+		batches[i%workers][jointName] = jointParams
+		i++
+	}
+
 	var wg sync.WaitGroup
-	for _, link := range globalCubeLinks {
+	for _, batch := range batches {
 		wg.Add(1)
-		go func(joint CubeLink) {
+		go func(batch map[string]map[string]float64) {
 			defer wg.Done()
 
-			// Open a fresh TCP connection for this joint.
-			conn, err := net.Dial("tcp", serverAddr)
+			conn, _, err := dialAndAuth(serverAddr, authPass, delimiter)
 			if err != nil {
-				fmt.Printf("[stiffenAllJoints] Failed to connect for joint %s: %v\n", joint.JointName, err)
+				fmt.Printf("[stiffenAllJoints] Failed to connect for %d joints: %v\n", len(batch), err)
 				return
 			}
 			defer conn.Close()
 
-			// Authenticate
-			if _, err := conn.Write([]byte(authPass + delimiter)); err != nil {
-				fmt.Printf("[stiffenAllJoints] Auth write error for joint %s: %v\n", joint.JointName, err)
-				return
+			// Set every parameter on every joint of this batch in one message.
+			if err := SetManyJointParams(conn, batch); err != nil {
+				fmt.Printf("[stiffenAllJoints] %v\n", err)
 			}
-			if _, err := readResponse(conn); err != nil {
-				fmt.Printf("[stiffenAllJoints] Auth response error for joint %s: %v\n", joint.JointName, err)
-				return
-			}
-
-			// For each parameter, set it on this joint.
-			for paramName, val := range params {
-				setJointParam(conn, joint.JointName, paramName, val)
-			}
-		}(link)
+		}(batch)
 	}
 
-	// Wait for all joint goroutines to finish.
+	// Wait for all batches to finish.
 	wg.Wait()
 	fmt.Println("[stiffenAllJoints] All joints have been stiffened.")
 }
@@ -483,7 +448,7 @@ func stiffenAllJoints() {
 // (stored in globalCubeLinks) to apply a set of stiffening parameters.
 func SingleThreadedstiffenAllJoints() {
 	// Open a connection.
-	conn, err := net.Dial("tcp", serverAddr)
+	conn, err := dialTCP(serverAddr)
 	if err != nil {
 		fmt.Println("[stiffenAllJoints] Failed to connect:", err)
 		return
@@ -491,7 +456,7 @@ func SingleThreadedstiffenAllJoints() {
 	defer conn.Close()
 
 	// Authenticate.
-	if _, err := conn.Write([]byte(authPass + delimiter)); err != nil {
+	if _, err := conn.Write(authFrame(authPass, delimiter)); err != nil {
 		fmt.Println("[stiffenAllJoints] Auth write error:", err)
 		return
 	}
@@ -521,11 +486,9 @@ func SingleThreadedstiffenAllJoints() {
 		"motor_max_impulse":     1000.0,
 	}
 
-	// Loop over each joint stored in globalCubeLinks.
-	for _, link := range globalCubeLinks {
-		for param, value := range params {
-			setJointParam(conn, link.JointName, param, value)
-		}
+	// Update every joint stored in globalCubeLinks in one batch.
+	if err := SetManyJointParams(conn, trackedJointUpdates(params)); err != nil {
+		fmt.Println("[stiffenAllJoints]", err)
 	}
 }
 
@@ -540,7 +503,7 @@ func SingleTCPConnectionExamplestiffenAllJoints() {
 	}
 
 	// 1) Open ONE TCP connection for all joints.
-	conn, err := net.Dial("tcp", serverAddr)
+	conn, err := dialTCP(serverAddr)
 	if err != nil {
 		fmt.Println("[stiffenAllJoints] Failed to connect:", err)
 		return
@@ -548,7 +511,7 @@ func SingleTCPConnectionExamplestiffenAllJoints() {
 	defer conn.Close()
 
 	// Authenticate once.
-	if _, err := conn.Write([]byte(authPass + delimiter)); err != nil {
+	if _, err := conn.Write(authFrame(authPass, delimiter)); err != nil {
 		fmt.Println("[stiffenAllJoints] Auth write error:", err)
 		return
 	}
@@ -557,35 +520,30 @@ func SingleTCPConnectionExamplestiffenAllJoints() {
 		return
 	}
 
-	// 2) For each joint in globalCubeLinks...
-	for _, link := range globalCubeLinks {
-		// 3) For each parameter, send the command via setJointParam.
-		for paramName, val := range params {
-			setJointParam(conn, link.JointName, paramName, val)
-
-			// (Optional) read server confirmation if your setJointParam
-			// doesn't already do that internally.
-			// resp, err := readResponse(conn)
-			// if err != nil {
-			//     fmt.Printf("[stiffenAllJoints] Error reading response for joint %s: %v\n", link.JointName, err)
-			// }
-		}
+	// 2) Send every joint in globalCubeLinks with all its parameters in one batch.
+	if err := SetManyJointParams(conn, trackedJointUpdates(params)); err != nil {
+		fmt.Println("[stiffenAllJoints]", err)
 	}
 
 	fmt.Println("[stiffenAllJoints] All joints have been stiffened using a single connection.")
 }
 
-func linkCubes(cubeA, cubeB, jointType, jointName string) {
-	conn, err := net.Dial("tcp", serverAddr)
+func linkCubes(cubeA, cubeB, jointType, jointName string) error {
+	name, err := reserveJointName(jointName, cubeA, cubeB)
 	if err != nil {
-		fmt.Println("[Link] Failed to connect:", err)
-		return
+		return fmt.Errorf("[Link] Cannot link %s <--> %s: %v", cubeA, cubeB, err)
+	}
+
+	conn, err := dialTCP(serverAddr)
+	if err != nil {
+		releaseJointName(name)
+		return fmt.Errorf("[Link] Failed to connect: %v", err)
 	}
 	defer conn.Close()
 
-	if _, err := conn.Write([]byte(authPass + delimiter)); err != nil {
-		fmt.Println("[Link] Auth write error:", err)
-		return
+	if _, err := conn.Write(authFrame(authPass, delimiter)); err != nil {
+		releaseJointName(name)
+		return fmt.Errorf("[Link] Auth write error: %v", err)
 	}
 	_, _ = readResponse(conn)
 
@@ -594,21 +552,17 @@ func linkCubes(cubeA, cubeB, jointType, jointName string) {
 		"cube1":      cubeA,
 		"cube2":      cubeB,
 		"joint_type": jointType,
-		"joint_name": jointName,
+		"joint_name": name,
 	}
 
 	if err := sendJSONMessage(conn, link); err != nil {
-		fmt.Println("[Link] Failed to send link command:", err)
-		return
+		releaseJointName(name)
+		return fmt.Errorf("[Link] Failed to send link command: %v", err)
 	}
 
-	linkListMutex.Lock()
-	globalCubeLinks = append(globalCubeLinks, CubeLink{
-		JointName: jointName,
-		CubeA:     cubeA,
-		CubeB:     cubeB,
-	})
-	linkListMutex.Unlock()
-
-	fmt.Printf("🔗 Linked %s <--> %s with joint '%s' (%s)\n", cubeA, cubeB, jointName, jointType)
+	if name != jointName {
+		fmt.Printf("[Link] Joint name %s already in use, renamed to %s\n", jointName, name)
+	}
+	fmt.Printf("🔗 Linked %s <--> %s with joint '%s' (%s)\n", cubeA, cubeB, name, jointType)
+	return nil
 }
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+// trackTestJoints replaces the tracked links with n joints named joint_hinge_<i>.
+func trackTestJoints(n int) {
+	linkListMutex.Lock()
+	defer linkListMutex.Unlock()
+	globalCubeLinks = make([]CubeLink, n)
+	for i := range globalCubeLinks {
+		globalCubeLinks[i] = CubeLink{
+			JointName: fmt.Sprintf("joint_hinge_%d", i),
+			CubeA:     fmt.Sprintf("cube%d_BASE", i),
+			CubeB:     fmt.Sprintf("cube%d_BASE", i+1),
+		}
+	}
+}
+
+// jointsUpdated counts the joints covered by the set_joint_params and set_joint_params_bulk
+// messages a mock pod received.
+func jointsUpdated(m *mockServer) int {
+	updated := len(m.MessagesOfType("set_joint_params"))
+	for _, msg := range m.MessagesOfType("set_joint_params_bulk") {
+		joints, _ := msg["joints"].(map[string]interface{})
+		updated += len(joints)
+	}
+	return updated
+}
+
+// stiffenStrategies are the ways old.go stiffens every tracked joint.
+var stiffenStrategies = []struct {
+	name    string
+	stiffen func()
+}{
+	{"PerJoint", stiffenAllJointsBULK},             // One connection and one set_joint_params per joint
+	{"Batched", stiffenAllJoints},                  // One bulk message per worker connection
+	{"SingleConn", SingleThreadedstiffenAllJoints}, // One bulk message on one connection
+}
+
+func TestStiffenStrategiesUpdateEveryJoint(t *testing.T) {
+	for _, strategy := range stiffenStrategies {
+		t.Run(strategy.name, func(t *testing.T) {
+			quietPackage(t)
+			discardStdout(t)
+			mock := newMockServerAt(t, serverAddr, replySuccess)
+			trackTestJoints(25)
+
+			strategy.stiffen()
+
+			if got := jointsUpdated(mock); got != 25 {
+				t.Errorf("updated %d joints, want 25", got)
+			}
+		})
+	}
+}
+
+func BenchmarkStiffenAllJoints(b *testing.B) {
+	for _, strategy := range stiffenStrategies {
+		for _, joints := range []int{10, 100, 1000} {
+			b.Run(fmt.Sprintf("%s/%d", strategy.name, joints), func(b *testing.B) {
+				quietPackage(b)
+				discardStdout(b)
+				mock := newMockServerAt(b, serverAddr, replySuccess)
+				trackTestJoints(joints)
+
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					strategy.stiffen()
+				}
+				b.StopTimer()
+
+				if got, want := jointsUpdated(mock), joints*b.N; got != want {
+					b.Fatalf("mock server saw %d joint updates, want %d", got, want)
+				}
+				b.ReportMetric(float64(mock.Dials())/float64(b.N), "conns/op")
+				b.ReportMetric(float64(len(mock.Messages()))/float64(b.N), "msgs/op")
+			})
+		}
+	}
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"sync"
+)
+
+// virtualNodesPerPod is the number of points each pod occupies on the consistent-hash ring.
+// More points spread units more evenly across pods.
+const virtualNodesPerPod = 64
+
+type ringNode struct {
+	hash uint64
+	host string
+	port int
+}
+
+func hashKey(key string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	return h.Sum64()
+}
+
+// PodForUnit picks a pod for a unit name using consistent hashing over the successful pods.
+// The same unit name always maps to the same pod, and adding or removing a pod only moves
+// the units that hashed to that pod.
+func (s *SparseScanner) PodForUnit(unitName string) (host string, port int, ok bool) {
+	results := s.resultsCopy()
+	ring := make([]ringNode, 0, len(results)*virtualNodesPerPod)
+	for _, res := range results {
+		if !res.Success {
+			continue
+		}
+		for v := 0; v < virtualNodesPerPod; v++ {
+			ring = append(ring, ringNode{
+				hash: hashKey(fmt.Sprintf("%s:%d#%d", res.Host, res.Port, v)),
+				host: res.Host,
+				port: res.Port,
+			})
+		}
+	}
+	if len(ring) == 0 {
+		return "", 0, false
+	}
+
+	sort.Slice(ring, func(i, j int) bool { return ring[i].hash < ring[j].hash })
+
+	// Walk clockwise to the first node at or after the unit's hash, wrapping around
+	h := hashKey(unitName)
+	idx := sort.Search(len(ring), func(i int) bool { return ring[i].hash >= h })
+	if idx == len(ring) {
+		idx = 0
+	}
+	return ring[idx].host, ring[idx].port, true
+}
+
+// BestPod returns the successful pod holding the fewest cubes, skipping any pod whose host:port
+// is in exclude. Ties go to the pod that sorts first by address, so the choice is deterministic.
+func (s *SparseScanner) BestPod(exclude map[string]bool) (host string, port int, ok bool) {
+	return bestPod(s.resultsCopy(), exclude, nil)
+}
+
+// bestPod is BestPod over results, counting extra[addr] more cubes on each pod than it held when scanned.
+func bestPod(results []PodResult, exclude map[string]bool, extra map[string]int) (host string, port int, ok bool) {
+	bestAddr := ""
+	bestCubes := 0
+	for _, res := range results {
+		addr := podAddr(res.Host, res.Port)
+		if !res.Success || exclude[addr] {
+			continue
+		}
+		cubes := len(res.Cubes) + extra[addr]
+		if !ok || cubes < bestCubes || (cubes == bestCubes && addr < bestAddr) {
+			host, port, ok = res.Host, res.Port, true
+			bestAddr, bestCubes = addr, cubes
+		}
+	}
+	return host, port, ok
+}
+
+// podLoad counts the cubes one SpawnAcrossPods call has sent to each pod, including spawns still in
+// flight, so concurrent fallbacks spread out instead of all picking the pod that was emptiest when scanned.
+type podLoad struct {
+	mu           sync.Mutex
+	results      []PodResult
+	cubesPerUnit int
+	placed       map[string]int // Cubes assigned per pod address
+}
+
+// assign counts one construct against addr.
+func (l *podLoad) assign(addr string) {
+	l.mu.Lock()
+	l.placed[addr] += l.cubesPerUnit
+	l.mu.Unlock()
+}
+
+// release takes back a construct that failed on addr.
+func (l *podLoad) release(addr string) {
+	l.mu.Lock()
+	l.placed[addr] -= l.cubesPerUnit
+	l.mu.Unlock()
+}
+
+// next picks the least loaded pod not in exclude and assigns a construct to it.
+func (l *podLoad) next(exclude map[string]bool) (host string, port int, ok bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	host, port, ok = bestPod(l.results, exclude, l.placed)
+	if ok {
+		l.placed[podAddr(host, port)] += l.cubesPerUnit
+	}
+	return host, port, ok
+}
+
+// spawnRetryLimit is the number of pods SpawnAcrossPods tries for one construct before giving up.
+const spawnRetryLimit = 3
+
+// SpawnPlacement records where one construct ended up.
+type SpawnPlacement struct {
+	UnitName string
+	Addr     string   // Pod the construct was spawned on, or the last pod tried if it failed
+	Tried    []string // Every pod tried, in order
+	Err      error    // Last error if the construct could not be spawned on any pod
+}
+
+// SpawnReport lists the outcome of every construct in a SpawnAcrossPods call.
+type SpawnReport struct {
+	Placements []SpawnPlacement
+}
+
+// Failed returns the number of constructs that could not be spawned.
+func (r SpawnReport) Failed() int {
+	failed := 0
+	for _, p := range r.Placements {
+		if p.Err != nil {
+			failed++
+		}
+	}
+	return failed
+}
+
+// podPlanetCenter returns the center of a planet hosted on the pod, or the origin if it has none.
+func (s *SparseScanner) podPlanetCenter(host string, port int) []float64 {
+	s.mapsMu.RLock()
+	defer s.mapsMu.RUnlock()
+	var names []string
+	for name, planet := range s.PlanetsMap {
+		if planet.Host == host && planet.Port == port {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return []float64{0, 0, 0}
+	}
+	sort.Strings(names)
+	c := s.PlanetsMap[names[0]].Coordinates
+	return []float64{c[0], c[1], c[2]}
+}
+
+// SpawnAcrossPods spawns count constructs from the JSON template, each on the pod PodForUnit
+// assigns to its unit name, in a free slot on a sphere around that pod's planet whose radius is set
+// by offset, as in SpawnMultipleConstructs. If a pod is unreachable or the spawn fails, the
+// construct's slot is released and it is retried on the least loaded other pod, counting the
+// constructs already sent there, up to spawnRetryLimit pods. The report records which pod each
+// construct landed on; an error is returned if any were lost.
+func (s *SparseScanner) SpawnAcrossPods(count int, role, domain string, startGen, startVersion int, jsonTemplatePath string, offset []float64) (SpawnReport, error) {
+	report := SpawnReport{Placements: make([]SpawnPlacement, count)}
+	if err := validatePositions([][]float64{offset}); err != nil {
+		return report, fmt.Errorf("[SpawnAcrossPods] invalid offset: %v", err)
+	}
+	template := NewConstruct("", s.AuthPass, s.EndMarker)
+	if err := template.LoadConfigFromJSON(jsonTemplatePath, generateUnitID(role, domain, startGen, startVersion)); err != nil {
+		return report, fmt.Errorf("[SpawnAcrossPods] %v", err)
+	}
+	load := &podLoad{results: s.resultsCopy(), cubesPerUnit: len(template.Config.Cubes), placed: make(map[string]int)}
+
+	var wg sync.WaitGroup
+	for i := 0; i < count; i++ {
+		wg.Add(1)
+		go func(idx int) {
+			defer wg.Done()
+			unitName := generateUnitID(role, domain, startGen+idx/100, startVersion+idx%100)
+			report.Placements[idx] = s.spawnWithFallback(unitName, jsonTemplatePath, offset, load)
+		}(i)
+	}
+	wg.Wait()
+
+	if failed := report.Failed(); failed > 0 {
+		return report, fmt.Errorf("[SpawnAcrossPods] %d of %d constructs could not be spawned", failed, count)
+	}
+	return report, nil
+}
+
+// spawnWithFallback spawns one construct, moving on to another pod each time a pod fails.
+func (s *SparseScanner) spawnWithFallback(unitName, jsonTemplatePath string, offset []float64, load *podLoad) SpawnPlacement {
+	placement := SpawnPlacement{UnitName: unitName}
+	tried := make(map[string]bool)
+
+	host, port, ok := s.PodForUnit(unitName)
+	if ok {
+		load.assign(podAddr(host, port))
+	}
+	for attempt := 0; attempt < spawnRetryLimit && ok; attempt++ {
+		addr := podAddr(host, port)
+		placement.Addr = addr
+		placement.Tried = append(placement.Tried, addr)
+		tried[addr] = true
+
+		placement.Err = s.spawnOnPod(unitName, jsonTemplatePath, host, port, offset)
+		if placement.Err == nil {
+			s.log().Infof("✅ Construct %s spawned on %s", unitName, addr)
+			return placement
+		}
+		s.log().Warnf("⚠️ Construct %s failed on %s: %v", unitName, addr, placement.Err)
+		load.release(addr)
+		host, port, ok = load.next(tried)
+	}
+
+	if placement.Err == nil {
+		placement.Err = fmt.Errorf("no available pod for %s", unitName)
+	}
+	s.log().Errorf("❌ Construct %s lost after trying %v", unitName, placement.Tried)
+	return placement
+}
+
+// spawnOnPod checks that the pod is reachable, reserves a free slot around its planet, and spawns
+// the construct there. A spawn that fails part way is despawned and its slot released before the
+// error is returned.
+func (s *SparseScanner) spawnOnPod(unitName, jsonTemplatePath, host string, port int, offset []float64) error {
+	addr := podAddr(host, port)
+	conn, err := s.dialPod(addr)
+	if err != nil {
+		return err
+	}
+	conn.Close()
+
+	construct := NewConstruct(addr, s.AuthPass, s.EndMarker)
+	construct.Logger = s.Logger
+	if err := construct.LoadConfigFromJSON(jsonTemplatePath, unitName); err != nil {
+		return err
+	}
+	center := s.podPlanetCenter(host, port)
+	radius, minDistance := orbitLayout(construct, offset)
+	slot, err := reservePositions(PackedGenerator{Radius: radius, MinDist: minDistance}, center, minDistance, []string{unitName})
+	if err != nil {
+		return err
+	}
+	if err := construct.Spawn(slot[0], center); err != nil {
+		// Clean up a partial spawn so retrying on another pod does not leave orphans here
+		if len(construct.SpawnedCubes()) > 0 {
+			if despawnErr := construct.Despawn(); despawnErr != nil {
+				s.log().Warnf("⚠️ Construct %s left cubes on %s: %v", unitName, addr, despawnErr)
+			}
+		}
+		releaseOccupiedPositions(unitName)
+		return err
+	}
+	return nil
+}
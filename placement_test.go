@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSpawnAcrossPodsFallsBackFromRefusingPod(t *testing.T) {
+	quietPackage(t)
+	template := filepath.Join(t.TempDir(), "template.json")
+	if err := os.WriteFile(template, []byte(testConfigJSON), 0644); err != nil {
+		t.Fatal(err)
+	}
+	pods := make(map[string]*mockServer)
+	var ports []int
+	for i := 0; i < 3; i++ {
+		m := newMockServer(t, podReply([]string{fmt.Sprintf("existing%d_BASE", i)}))
+		_, port := m.HostPort(t)
+		ports = append(ports, port)
+		pods[m.Addr()] = m
+	}
+	s := newTestScanner(ports...)
+	s.ScanAllPods()
+
+	// Refuse connections on the pod most of the units hash to, after the scan found it
+	const count = 12
+	assigned := make(map[string]int)
+	for i := 0; i < count; i++ {
+		host, port, _ := s.PodForUnit(generateUnitID("PLACE", "test.example", 1, 1+i))
+		assigned[podAddr(host, port)]++
+	}
+	refused := ""
+	for addr, n := range assigned {
+		if refused == "" || n > assigned[refused] {
+			refused = addr
+		}
+	}
+	pods[refused].Close()
+
+	report, err := s.SpawnAcrossPods(count, "PLACE", "test.example", 1, 1, template, []float64{40, 0, 0})
+	if err != nil {
+		t.Fatalf("SpawnAcrossPods: %v", err)
+	}
+	fallbacks := 0
+	for _, p := range report.Placements {
+		host, port, _ := s.PodForUnit(p.UnitName)
+		hashed := podAddr(host, port)
+		if p.Err != nil || len(p.Tried) == 0 || p.Tried[0] != hashed || p.Addr != p.Tried[len(p.Tried)-1] {
+			t.Errorf("placement %+v, want a success that first tried %s", p, hashed)
+			continue
+		}
+		if hashed == refused {
+			fallbacks++
+			if len(p.Tried) != 2 || p.Addr == refused {
+				t.Errorf("%s: tried %v and landed on %s, want one fallback from %s", p.UnitName, p.Tried, p.Addr, refused)
+			}
+		} else if len(p.Tried) != 1 {
+			t.Errorf("%s: tried %v, want only its own pod", p.UnitName, p.Tried)
+		}
+	}
+	if fallbacks != assigned[refused] {
+		t.Errorf("%d constructs fell back, want %d", fallbacks, assigned[refused])
+	}
+
+	// Constructs sharing a pod each get their own slot
+	for addr, m := range pods {
+		if addr == refused {
+			continue
+		}
+		var heads [][]float64
+		for _, msg := range m.MessagesOfType("spawn_cube") {
+			if strings.Contains(fmt.Sprint(msg["cube_name"]), "_head") {
+				heads = append(heads, messagePosition(msg))
+			}
+		}
+		for i := range heads {
+			for j := i + 1; j < len(heads); j++ {
+				if distance3(heads[i], heads[j]) < 1 {
+					t.Errorf("%s: constructs stacked at %v and %v", addr, heads[i], heads[j])
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	paragon "github.com/OpenFluke/PARAGON"
+)
+
+// modelInputSize returns the number of values the network's input layer takes.
+func modelInputSize(model *paragon.Network) int {
+	in := model.Layers[model.InputLayer]
+	return in.Width * in.Height
+}
+
+// modelOutputSize returns the number of values GetOutput returns: one row of the output layer.
+func modelOutputSize(model *paragon.Network) int {
+	return model.Layers[model.OutputLayer].Width
+}
+
+// RunPolicyStep runs one forward pass of model on obs and returns the output. obs fills the input
+// layer row by row and must have exactly as many values as the layer holds.
+func RunPolicyStep(model *paragon.Network, obs []float64) (out []float64, err error) {
+	if model == nil || len(model.Layers) == 0 {
+		return nil, fmt.Errorf("[RunPolicyStep] model is not initialized")
+	}
+	if want := modelInputSize(model); len(obs) != want {
+		return nil, fmt.Errorf("[RunPolicyStep] observation has %d values, model input takes %d", len(obs), want)
+	}
+
+	in := model.Layers[model.InputLayer]
+	inputs := make([][]float64, in.Height)
+	for y := range inputs {
+		inputs[y] = obs[y*in.Width : (y+1)*in.Width]
+	}
+
+	// PARAGON panics on malformed networks; report that as an error instead
+	defer func() {
+		if r := recover(); r != nil {
+			out, err = nil, fmt.Errorf("[RunPolicyStep] forward pass failed: %v", r)
+		}
+	}()
+	model.Forward(inputs)
+	return model.GetOutput(), nil
+}
+
+// DriveConstruct runs model as a controller for a spawned construct for the given number of steps.
+// Each step reads every joint with GetJointState, builds the observation [angle, velocity] per
+// joint in joint-name order, runs the model, and sets each joint's motor_target_velocity to the
+// matching output. The model must take 2 inputs and produce 1 output per joint.
+func DriveConstruct(construct *Construct, model *paragon.Network, steps int) error {
+	if model == nil || len(model.Layers) == 0 {
+		return fmt.Errorf("[DriveConstruct] model is not initialized")
+	}
+	joints := construct.jointNames()
+	if len(joints) == 0 {
+		return fmt.Errorf("[DriveConstruct] construct %s has no tracked joints", construct.unitName)
+	}
+	if got := modelInputSize(model); got != 2*len(joints) {
+		return fmt.Errorf("[DriveConstruct] model input takes %d values, construct %s needs %d (2 per joint for %d joints)",
+			got, construct.unitName, 2*len(joints), len(joints))
+	}
+	if got := modelOutputSize(model); got != len(joints) {
+		return fmt.Errorf("[DriveConstruct] model outputs %d values, construct %s has %d joints",
+			got, construct.unitName, len(joints))
+	}
+
+	conn, _, err := construct.connect()
+	if err != nil {
+		return fmt.Errorf("[DriveConstruct] %v", err)
+	}
+	defer conn.Close()
+
+	obs := make([]float64, 2*len(joints))
+	for step := 0; step < steps; step++ {
+		for i, joint := range joints {
+			state, err := GetJointState(conn, joint)
+			if err != nil {
+				return fmt.Errorf("[DriveConstruct] step %d: %v", step, err)
+			}
+			obs[2*i] = state.Angle
+			obs[2*i+1] = state.Velocity
+		}
+
+		out, err := RunPolicyStep(model, obs)
+		if err != nil {
+			return fmt.Errorf("[DriveConstruct] step %d: %v", step, err)
+		}
+
+		for i, joint := range joints {
+			if _, err := sendCommand(conn, Message{
+				"type":       "set_joint_params",
+				"joint_name": joint,
+				"params":     map[string]float64{"motor_target_velocity": out[i]},
+			}); err != nil {
+				return fmt.Errorf("[DriveConstruct] step %d: joint %s: %v", step, joint, err)
+			}
+		}
+	}
+	return nil
+}
+
+// ObservationSpec fixes the layout of EncodeObservation's output: MaxCubes cube slots of
+// 3 values followed by MaxJoints joint slots of 2 values.
+type ObservationSpec struct {
+	MaxCubes  int
+	MaxJoints int
+}
+
+// Length returns the number of values EncodeObservation produces for the spec.
+func (spec ObservationSpec) Length() int {
+	return 3*spec.MaxCubes + 2*spec.MaxJoints
+}
+
+// EncodeObservation flattens cube and joint state into a vector of spec.Length() values:
+// the x, y, z position of each cube sorted by name, then the angle and velocity of each joint
+// sorted by name. Unused slots are zero, and entries beyond MaxCubes or MaxJoints are dropped,
+// so the same state always encodes the same way whatever order it was gathered in.
+func EncodeObservation(cubes []CubeState, joints []JointState, spec ObservationSpec) []float64 {
+	obs := make([]float64, spec.Length())
+
+	sortedCubes := append([]CubeState(nil), cubes...)
+	sort.SliceStable(sortedCubes, func(i, j int) bool { return sortedCubes[i].Name < sortedCubes[j].Name })
+	for i, cube := range sortedCubes {
+		if i == spec.MaxCubes {
+			break
+		}
+		copy(obs[3*i:3*i+3], cube.Position)
+	}
+
+	offset := 3 * spec.MaxCubes
+	sortedJoints := append([]JointState(nil), joints...)
+	sort.SliceStable(sortedJoints, func(i, j int) bool { return sortedJoints[i].Name < sortedJoints[j].Name })
+	for i, joint := range sortedJoints {
+		if i == spec.MaxJoints {
+			break
+		}
+		obs[offset+2*i] = joint.Angle
+		obs[offset+2*i+1] = joint.Velocity
+	}
+	return obs
+}
+
+// StabilityWeights weights the terms of StabilityRewardWeighted.
+type StabilityWeights struct {
+	Upright   float64   // Weight of the uprightness term, which ranges from -1 (upside down) to 1 (upright)
+	Drift     float64   // Penalty per unit of horizontal distance between the centroid and Reference
+	Reference []float64 // x, y, z the construct should stay over; nil disables the drift penalty
+}
+
+// DefaultStabilityWeights rewards uprightness, with a light drift penalty once a Reference is set.
+var DefaultStabilityWeights = StabilityWeights{Upright: 1, Drift: 0.1}
+
+// StabilityReward scores how well the construct whose cubes start with prefix is standing,
+// using DefaultStabilityWeights. See StabilityRewardWeighted.
+func StabilityReward(snapshot WorldSnapshot, prefix string) float64 {
+	return StabilityRewardWeighted(snapshot, prefix, DefaultStabilityWeights)
+}
+
+// StabilityRewardWeighted scores how well the construct whose cubes start with prefix is standing.
+// Uprightness is the cosine of the angle between the +Y axis and the line from the mean of the
+// "foot" cubes to the "head" cube, or 0 if either is missing. The drift penalty is the horizontal
+// (XZ) distance from the centroid of the construct's cubes to w.Reference. The reward is
+// w.Upright*uprightness - w.Drift*drift, and 0 if no cube with a position matches prefix.
+func StabilityRewardWeighted(snapshot WorldSnapshot, prefix string, w StabilityWeights) float64 {
+	var centroid, feet [3]float64
+	var head []float64
+	count, feetCount := 0, 0
+	for _, cube := range snapshot.Cubes {
+		if !strings.HasPrefix(cube.Name, prefix) || len(cube.Position) != 3 {
+			continue
+		}
+		for i := range centroid {
+			centroid[i] += cube.Position[i]
+		}
+		count++
+		part := strings.TrimPrefix(cube.Name, prefix)
+		switch {
+		case strings.Contains(part, "head"):
+			head = cube.Position
+		case strings.Contains(part, "foot"):
+			for i := range feet {
+				feet[i] += cube.Position[i]
+			}
+			feetCount++
+		}
+	}
+	if count == 0 {
+		return 0
+	}
+
+	upright := 0.0
+	if head != nil && feetCount > 0 {
+		for i := range feet {
+			feet[i] /= float64(feetCount)
+		}
+		if length := distance3(head, feet[:]); length > 0 {
+			upright = (head[1] - feet[1]) / length
+		}
+	}
+
+	drift := 0.0
+	if len(w.Reference) == 3 {
+		dx := centroid[0]/float64(count) - w.Reference[0]
+		dz := centroid[2]/float64(count) - w.Reference[2]
+		drift = math.Sqrt(dx*dx + dz*dz)
+	}
+	return w.Upright*upright - w.Drift*drift
+}
+
+// jointNames returns the sorted names of the tracked joints attached to the construct's cubes.
+func (c *Construct) jointNames() []string {
+	cubes := make(map[string]bool)
+	for _, name := range c.SpawnedCubes() {
+		cubes[name] = true
+	}
+	linkListMutex.Lock()
+	var joints []string
+	for _, link := range globalCubeLinks {
+		if cubes[link.CubeA] || cubes[link.CubeB] {
+			joints = append(joints, link.JointName)
+		}
+	}
+	linkListMutex.Unlock()
+	sort.Strings(joints)
+	return joints
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestEncodeObservationIgnoresInputOrder(t *testing.T) {
+	spec := ObservationSpec{MaxCubes: 4, MaxJoints: 3}
+	cubesByName := map[string][]float64{
+		"u_head_BASE": {0, 3, 0},
+		"u_body_BASE": {0, 2, 0},
+		"u_foot_BASE": {0, 1, 0},
+	}
+	jointsByName := map[string]JointState{
+		"joint_a": {Name: "joint_a", Angle: 0.1, Velocity: 1},
+		"joint_b": {Name: "joint_b", Angle: 0.2, Velocity: 2},
+	}
+
+	// Gather the state from the maps many times, as a caller would, in whatever order they iterate
+	var first []float64
+	for run := 0; run < 50; run++ {
+		var cubes []CubeState
+		for name, pos := range cubesByName {
+			cubes = append(cubes, CubeState{Name: name, Position: pos})
+		}
+		var joints []JointState
+		for _, joint := range jointsByName {
+			joints = append(joints, joint)
+		}
+		obs := EncodeObservation(cubes, joints, spec)
+		if first == nil {
+			first = obs
+		} else if !reflect.DeepEqual(obs, first) {
+			t.Fatalf("run %d encoded %v, want %v", run, obs, first)
+		}
+	}
+
+	// body, foot, head, one empty cube slot, then joint_a, joint_b, one empty joint slot
+	want := []float64{0, 2, 0, 0, 1, 0, 0, 3, 0, 0, 0, 0, 0.1, 1, 0.2, 2, 0, 0}
+	if !reflect.DeepEqual(first, want) {
+		t.Errorf("EncodeObservation = %v, want %v", first, want)
+	}
+}
+
+func TestEncodeObservationTruncatesToSpec(t *testing.T) {
+	spec := ObservationSpec{MaxCubes: 1, MaxJoints: 1}
+	obs := EncodeObservation(
+		[]CubeState{{Name: "b", Position: []float64{4, 5, 6}}, {Name: "a", Position: []float64{1, 2, 3}}},
+		[]JointState{{Name: "y", Angle: 9}, {Name: "x", Angle: 7, Velocity: 8}},
+		spec)
+	if want := []float64{1, 2, 3, 7, 8}; !reflect.DeepEqual(obs, want) {
+		t.Errorf("EncodeObservation = %v, want %v", obs, want)
+	}
+	if len(EncodeObservation(nil, nil, spec)) != spec.Length() {
+		t.Error("an empty state does not fill the spec's length")
+	}
+}
+
+// humanoidSnapshot returns a snapshot of a head, body, and two feet, standing upright or lying
+// along the x axis, with their feet around (x, 0, z), plus a cube of another construct.
+func humanoidSnapshot(prefix string, upright bool, x, z float64) WorldSnapshot {
+	at := func(height, side float64) []float64 {
+		if upright {
+			return []float64{x + side, height, z}
+		}
+		return []float64{x + height, 0, z + side}
+	}
+	return WorldSnapshot{Cubes: []SnapshotCube{
+		{Name: prefix + "head_BASE", Position: at(2, 0)},
+		{Name: prefix + "body_BASE", Position: at(1, 0)},
+		{Name: prefix + "foot_l_BASE", Position: at(0, -0.5)},
+		{Name: prefix + "foot_r_BASE", Position: at(0, 0.5)},
+		{Name: "other_head_BASE", Position: []float64{50, -10, 50}},
+		{Name: prefix + "sensor_BASE"}, // No position reported
+	}}
+}
+
+func TestStabilityRewardUprightVsToppled(t *testing.T) {
+	upright := StabilityReward(humanoidSnapshot("bot_", true, 0, 0), "bot_")
+	toppled := StabilityReward(humanoidSnapshot("bot_", false, 0, 0), "bot_")
+	if math.Abs(upright-1) > 1e-9 {
+		t.Errorf("upright reward = %v, want 1", upright)
+	}
+	if math.Abs(toppled) > 1e-9 {
+		t.Errorf("toppled reward = %v, want 0", toppled)
+	}
+
+	upsideDown := humanoidSnapshot("bot_", true, 0, 0)
+	upsideDown.Cubes[0].Position = []float64{0, -2, 0}
+	if got := StabilityReward(upsideDown, "bot_"); got >= toppled {
+		t.Errorf("upside-down reward %v is not below the toppled %v", got, toppled)
+	}
+	if got := StabilityReward(humanoidSnapshot("bot_", true, 0, 0), "missing_"); got != 0 {
+		t.Errorf("reward for an absent construct = %v, want 0", got)
+	}
+}
+
+func TestStabilityRewardWeightedDrift(t *testing.T) {
+	w := StabilityWeights{Upright: 2, Drift: 0.5, Reference: []float64{0, 100, 0}}
+	centered := StabilityRewardWeighted(humanoidSnapshot("bot_", true, 0, 0), "bot_", w)
+	drifted := StabilityRewardWeighted(humanoidSnapshot("bot_", true, 3, 4), "bot_", w)
+	if math.Abs(centered-2) > 1e-9 {
+		t.Errorf("centered reward = %v, want 2 (height is ignored)", centered)
+	}
+	if want := 2 - 0.5*5; math.Abs(drifted-want) > 1e-9 {
+		t.Errorf("drifted reward = %v, want %v", drifted, want)
+	}
+	if got := StabilityReward(humanoidSnapshot("bot_", true, 3, 4), "bot_"); math.Abs(got-1) > 1e-9 {
+		t.Errorf("default weights without a Reference = %v, want no drift penalty", got)
+	}
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+// ErrPoolClosed is returned by Get after the pool has been closed.
+var ErrPoolClosed = errors.New("pool closed")
+
+// defaultMaxPerAddr is the number of connections a Pool opens per address when MaxPerAddr is not set.
+const defaultMaxPerAddr = 4
+
+// livenessProbe is how long Get waits for a pooled connection to report EOF before reusing it.
+const livenessProbe = time.Millisecond
+
+// Pool lends out authenticated connections keyed by address, so repeated commands to the same
+// server skip the dial and auth handshake. At most MaxPerAddr connections per address are open
+// at once, counting both idle and lent ones; Get blocks until one is returned when the cap is hit.
+type Pool struct {
+	authPass   string
+	delimiter  string
+	MaxPerAddr int // Connections per address (0 uses defaultMaxPerAddr)
+
+	mu     sync.Mutex
+	cond   *sync.Cond
+	idle   map[string][]net.Conn
+	open   map[string]int // Idle plus lent connections per address
+	closed bool
+}
+
+// NewPool returns an empty pool whose connections authenticate with authPass and frame
+// messages with delimiter.
+func NewPool(authPass, delimiter string, maxPerAddr int) *Pool {
+	p := &Pool{
+		authPass:   authPass,
+		delimiter:  delimiter,
+		MaxPerAddr: maxPerAddr,
+		idle:       make(map[string][]net.Conn),
+		open:       make(map[string]int),
+	}
+	p.cond = sync.NewCond(&p.mu)
+	return p
+}
+
+// Get returns an authenticated connection to addr, reusing an idle one that is still alive or
+// dialing a new one. Return it with Put when done, or Discard if it is no longer usable.
+func (p *Pool) Get(addr string) (net.Conn, error) {
+	p.mu.Lock()
+	for {
+		if p.closed {
+			p.mu.Unlock()
+			return nil, ErrPoolClosed
+		}
+		if idle := p.idle[addr]; len(idle) > 0 {
+			conn := idle[len(idle)-1]
+			p.idle[addr] = idle[:len(idle)-1]
+			p.mu.Unlock()
+			if connAlive(conn) {
+				return conn, nil
+			}
+			conn.Close()
+			p.mu.Lock()
+			p.open[addr]--
+			continue
+		}
+		if p.open[addr] < p.maxPerAddr() {
+			break
+		}
+		p.cond.Wait()
+	}
+	p.open[addr]++
+	p.mu.Unlock()
+
+	conn, _, err := dialAndAuth(addr, p.authPass, p.delimiter)
+	if err != nil {
+		p.release(addr)
+		return nil, err
+	}
+	return conn, nil
+}
+
+// Put returns a connection obtained from Get to the pool. After Close it closes the connection instead.
+func (p *Pool) Put(addr string, conn net.Conn) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		conn.Close()
+		p.open[addr]--
+		return
+	}
+	p.idle[addr] = append(p.idle[addr], conn)
+	p.cond.Broadcast() // Waiters for every address share cond, so wake them all
+}
+
+// Discard closes a connection obtained from Get instead of returning it, freeing its slot.
+func (p *Pool) Discard(addr string, conn net.Conn) {
+	conn.Close()
+	p.release(addr)
+}
+
+// Close closes every idle connection and makes Get fail. Lent connections are closed when they are put back.
+func (p *Pool) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closed = true
+	for addr, idle := range p.idle {
+		for _, conn := range idle {
+			conn.Close()
+		}
+		p.open[addr] -= len(idle)
+		delete(p.idle, addr)
+	}
+	p.cond.Broadcast()
+	return nil
+}
+
+// release frees one connection slot for addr.
+func (p *Pool) release(addr string) {
+	p.mu.Lock()
+	p.open[addr]--
+	p.cond.Broadcast()
+	p.mu.Unlock()
+}
+
+func (p *Pool) maxPerAddr() int {
+	if p.MaxPerAddr > 0 {
+		return p.MaxPerAddr
+	}
+	return defaultMaxPerAddr
+}
+
+// connAlive reports whether an idle connection is still open. A closed connection reads EOF
+// at once, while a healthy one times out; unread data left on an idle connection also counts
+// as unusable, since it would be mistaken for the reply to the next command.
+func connAlive(conn net.Conn) bool {
+	if isDryRun(conn) {
+		return true
+	}
+	if dc, ok := conn.(*delimitedConn); ok && (len(dc.partial) > 0 || (dc.reader != nil && dc.reader.Buffered() > 0)) {
+		return false
+	}
+	conn.SetReadDeadline(time.Now().Add(livenessProbe))
+	defer conn.SetReadDeadline(time.Time{})
+	var one [1]byte
+	_, err := conn.Read(one[:])
+	return isTimeout(err)
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Run with -race: many goroutines share a pool capped at three connections.
+func TestPoolConcurrentUse(t *testing.T) {
+	quietPackage(t)
+	mock := newMockServer(t, podReply([]string{"a_BASE"}))
+	pool := NewPool(authPass, delimiter, 3)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 40; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				conn, err := pool.Get(mock.Addr())
+				if err != nil {
+					t.Errorf("Get: %v", err)
+					return
+				}
+				if cubes, err := GetCubeList(conn); err != nil || len(cubes) != 1 {
+					t.Errorf("GetCubeList over a pooled connection = %v, %v", cubes, err)
+					pool.Discard(mock.Addr(), conn)
+					continue
+				}
+				if (i+j)%7 == 0 {
+					pool.Discard(mock.Addr(), conn)
+				} else {
+					pool.Put(mock.Addr(), conn)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	pool.mu.Lock()
+	open, idle := pool.open[mock.Addr()], len(pool.idle[mock.Addr()])
+	pool.mu.Unlock()
+	if open > 3 || open != idle {
+		t.Errorf("%d connections open and %d idle after every one was returned, want the same number, at most 3", open, idle)
+	}
+	if got := len(mock.MessagesOfType("get_cube_list")); got != 400 {
+		t.Errorf("server answered %d cube lists, want 400", got)
+	}
+
+	if err := pool.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if _, err := pool.Get(mock.Addr()); !errors.Is(err, ErrPoolClosed) {
+		t.Errorf("Get after Close: err = %v, want ErrPoolClosed", err)
+	}
+}
+
+func TestPoolCapsConnectionsPerAddress(t *testing.T) {
+	quietPackage(t)
+	mock := newMockServer(t, nil)
+	pool := NewPool(authPass, delimiter, 2)
+	defer pool.Close()
+
+	a, err := pool.Get(mock.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := pool.Get(mock.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(chan struct{})
+	go func() {
+		conn, err := pool.Get(mock.Addr())
+		if err == nil {
+			pool.Put(mock.Addr(), conn)
+		}
+		close(got)
+	}()
+	select {
+	case <-got:
+		t.Fatal("a third Get succeeded while two connections were lent out")
+	case <-time.After(50 * time.Millisecond):
+	}
+	pool.Put(mock.Addr(), a)
+	select {
+	case <-got:
+	case <-time.After(2 * time.Second):
+		t.Fatal("the blocked Get did not get the returned connection")
+	}
+	pool.Put(mock.Addr(), b)
+	if mock.Dials() != 2 {
+		t.Errorf("dialed %d times, want 2", mock.Dials())
+	}
+}
+
+func TestPoolReplacesDeadConnections(t *testing.T) {
+	quietPackage(t)
+	mock := newMockServer(t, nil)
+	pool := NewPool(authPass, delimiter, 1)
+	defer pool.Close()
+
+	conn, err := pool.Get(mock.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool.Put(mock.Addr(), conn)
+
+	// Drop the server side of the idle connection; the pool must dial a fresh one
+	mock.mu.Lock()
+	for _, c := range mock.conns {
+		c.Close()
+	}
+	mock.mu.Unlock()
+	time.Sleep(10 * time.Millisecond)
+
+	fresh, err := pool.Get(mock.Addr())
+	if err != nil {
+		t.Fatalf("Get after the server dropped the connection: %v", err)
+	}
+	defer pool.Discard(mock.Addr(), fresh)
+	if fresh == conn || mock.Dials() != 2 {
+		t.Errorf("reused the dead connection (%d dials), want a new one", mock.Dials())
+	}
+}
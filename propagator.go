@@ -1,13 +1,18 @@
 package main
 
 import (
-	"bufio"
-	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
+	"math"
 	"net"
+	"net/netip"
+	"os"
+	"runtime/debug"
+	"slices"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -23,27 +28,77 @@ const (
 	timeoutSec = 10
 )
 
+// defaultCoordKeys are the planet position keys used by the current server version.
+var defaultCoordKeys = [3]string{"x", "y", "z"}
+
 // --- MAIN STRUCTS ---
 
 type SparseScanner struct {
-	Hosts      []string
-	StartPort  int
-	PortStep   int
-	NumPods    int
-	AuthPass   string
-	EndMarker  string
-	TimeoutSec int
+	Hosts        []string
+	StartPort    int
+	PortStep     int
+	NumPods      int
+	Ports        []int // Explicit ports to scan on every host; overrides StartPort, PortStep, and NumPods when set
+	AuthPass     string
+	EndMarker    string
+	TimeoutSec   int
+	HostTimeouts map[string]time.Duration // Per-host dial and read timeout, overriding TimeoutSec
+	CoordKeys    [3]string                // Keys of the x, y, z components in Planet.Position
+
+	MaxWorkers      int // Concurrent requests for parallel cube queries (0 uses defaultMaxWorkers)
+	MaxConcurrency  int // Pods checked at once by ScanAllPods (0 uses defaultMaxConcurrency)
+	MaxConnsPerHost int // Open pod connections allowed per host at once (0 means unlimited)
+	DialAttempts    int // Dial attempts per pod, backing off by DialBaseDelay (0 uses the package DialAttempts; 1 makes an unreachable pod cost one timeout in wide sweeps)
+
+	AllowLargeRanges bool // Let ExpandHosts expand CIDR blocks larger than an IPv4 /16, up to an IPv4 /8
+	QueryVersion     bool // Ask each pod for its server version with get_version while scanning
+
+	Logger      Logger          // Receives scan diagnostics (nil uses DefaultLogger)
+	Metrics     Metrics         // Receives pod scan counts and durations (nil uses DefaultMetrics)
+	OnPodResult func(PodResult) // Called as each pod completes during ScanAllPods or RescanFailures; calls never overlap
+
+	Session *Session // Reused by GetCubesAndConnections(Parallel) for joint queries instead of dialing per cube
+	Pool    *Pool    // Lends connections for joint queries when Session is not set
+	DryRun  bool     // Record and log pod commands instead of sending them (see DryRunMessages); scans still run
+	dryRun  DryRunRecorder
+
+	// PlanetMergeEpsilon merges planets whose coordinates all lie within this distance of a
+	// recorded planet into that record, whatever their names. 0 keys planets by name only.
+	PlanetMergeEpsilon float64
 
 	Results    []PodResult
 	PlanetsMap map[string]PlanetRecord
 	CubesMap   map[string]string // cubeName -> host
+
+	cubeAddrs map[string]string // cubeName -> host:port of the owning pod
+
+	resultsMu    sync.Mutex    // Guards Results and resultsJSONL while a scan is appending to it
+	callbackMu   sync.Mutex    // Serializes OnPodResult calls
+	resultsJSONL *json.Encoder // Streams appended results, set by WriteResultsJSONL
+	mapsMu       sync.RWMutex  // Guards PlanetsMap, CubesMap, and cubeAddrs
+
+	transformCache   map[string][3]float64 // Positions fetched by CubesNear
+	transformCacheAt time.Time
+	transformCacheMu sync.Mutex
+
+	hostSlots   map[string]chan struct{} // host -> semaphore enforcing MaxConnsPerHost
+	hostSlotsMu sync.Mutex
 }
 
+// defaultMaxWorkers is the number of concurrent requests used when MaxWorkers is not set.
+const defaultMaxWorkers = 10
+
+// defaultMaxConcurrency is the number of pods ScanAllPods checks at once when MaxConcurrency is not set.
+const defaultMaxConcurrency = 64
+
 type PlanetRecord struct {
 	Name        string
 	Coordinates [3]float64
+	Seed        int
+	BiomeType   int
 	Host        string
 	Port        int
+	Hosts       []string // host:port of every pod that reported this planet
 }
 
 type PodResult struct {
@@ -51,10 +106,14 @@ type PodResult struct {
 	Port    int
 	Success bool
 	Error   string
+	Version string // Server version reported by get_version, "unknown", or "" unless QueryVersion is set
 	Cubes   []string
 	Planets []Planet
 }
 
+// unknownVersion is recorded for pods that do not answer get_version.
+const unknownVersion = "unknown"
+
 type Planet struct {
 	Position          map[string]float64   `json:"Position"`
 	Seed              int                  `json:"Seed"`
@@ -80,15 +139,18 @@ type JointInfo struct {
 
 func NewSparseScanner(hosts []string, startPort int) *SparseScanner {
 	return &SparseScanner{
-		Hosts:      hosts,
-		StartPort:  startPort,
-		PortStep:   portStep,
-		NumPods:    numPods,
-		AuthPass:   authPass,
-		EndMarker:  endMarker,
-		TimeoutSec: timeoutSec,
-		PlanetsMap: make(map[string]PlanetRecord),
-		CubesMap:   make(map[string]string),
+		Hosts:          hosts,
+		StartPort:      startPort,
+		PortStep:       portStep,
+		NumPods:        numPods,
+		AuthPass:       authPass,
+		EndMarker:      endMarker,
+		TimeoutSec:     timeoutSec,
+		CoordKeys:      defaultCoordKeys,
+		MaxConcurrency: defaultMaxConcurrency,
+		PlanetsMap:     make(map[string]PlanetRecord),
+		CubesMap:       make(map[string]string),
+		cubeAddrs:      make(map[string]string),
 	}
 }
 
@@ -100,62 +162,470 @@ func (s *SparseScanner) InitSparseScanner(hosts []string, startPort int) {
 	s.AuthPass = authPass
 	s.EndMarker = endMarker
 	s.TimeoutSec = timeoutSec
+	s.CoordKeys = defaultCoordKeys
+	s.MaxConcurrency = defaultMaxConcurrency
+	s.mapsMu.Lock()
 	s.PlanetsMap = make(map[string]PlanetRecord)
 	s.CubesMap = make(map[string]string)
+	s.cubeAddrs = make(map[string]string)
+	s.mapsMu.Unlock()
 }
 
 // --- MAIN METHODS ---
 
+// Validate reports a configuration that leaves nothing to scan, such as an empty host list or NumPods
+// of zero with no Ports, or an out-of-range entry in Ports.
+func (s *SparseScanner) Validate() error {
+	if len(s.Hosts) == 0 {
+		return fmt.Errorf("no hosts configured")
+	}
+	for _, port := range s.Ports {
+		if port <= 0 || port > 65535 {
+			return fmt.Errorf("invalid port %d in Ports", port)
+		}
+	}
+	if len(s.Ports) == 0 && s.NumPods <= 0 {
+		return fmt.Errorf("NumPods must be positive, got %d", s.NumPods)
+	}
+	return nil
+}
+
+// maxCIDRHostBits is the most host bits ExpandHosts expands without AllowLargeRanges (an IPv4 /16).
+const maxCIDRHostBits = 16
+
+// maxLargeCIDRHostBits is the most host bits ExpandHosts expands even with AllowLargeRanges (an
+// IPv4 /8), so an IPv6 prefix such as a /64 is refused instead of exhausting memory.
+const maxLargeCIDRHostBits = 24
+
+// ExpandHosts replaces Hosts with the final list of addresses to scan: CIDR entries such as
+// "192.168.0.0/24" become their individual host addresses (without the network and broadcast
+// addresses), hostnames are resolved, and literal IPs are kept. Duplicates are dropped.
+// Call it before ScanAllPods; Hosts is left unchanged if any entry fails to expand.
+func (s *SparseScanner) ExpandHosts() error {
+	var expanded []string
+	seen := make(map[string]bool)
+	add := func(host string) {
+		if !seen[host] {
+			seen[host] = true
+			expanded = append(expanded, host)
+		}
+	}
+
+	for _, entry := range s.Hosts {
+		switch {
+		case strings.Contains(entry, "/"):
+			ips, err := expandCIDR(entry, s.AllowLargeRanges)
+			if err != nil {
+				return fmt.Errorf("[ExpandHosts] %v", err)
+			}
+			for _, ip := range ips {
+				add(ip)
+			}
+		case net.ParseIP(entry) != nil:
+			add(entry)
+		default:
+			addrs, err := net.LookupHost(entry)
+			if err != nil {
+				return fmt.Errorf("[ExpandHosts] Failed to resolve %s: %v", entry, err)
+			}
+			for _, addr := range addrs {
+				add(addr)
+			}
+		}
+	}
+
+	s.Hosts = expanded
+	return nil
+}
+
+// expandCIDR lists the host addresses in a CIDR block. For IPv4 blocks larger than /31 the
+// network and broadcast addresses are skipped.
+func expandCIDR(cidr string, allowLarge bool) ([]string, error) {
+	prefix, err := netip.ParsePrefix(cidr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CIDR %s: %v", cidr, err)
+	}
+	prefix = prefix.Masked()
+	hostBits := prefix.Addr().BitLen() - prefix.Bits()
+	if hostBits > maxLargeCIDRHostBits {
+		return nil, fmt.Errorf("CIDR %s has more than %d addresses, split it into smaller blocks", cidr, 1<<maxLargeCIDRHostBits)
+	}
+	if !allowLarge && hostBits > maxCIDRHostBits {
+		return nil, fmt.Errorf("CIDR %s has more than %d addresses, set AllowLargeRanges to scan it", cidr, 1<<maxCIDRHostBits)
+	}
+
+	var ips []string
+	for addr := prefix.Addr(); addr.IsValid() && prefix.Contains(addr); addr = addr.Next() {
+		ips = append(ips, addr.String())
+	}
+	if prefix.Addr().Is4() && hostBits >= 2 {
+		ips = ips[1 : len(ips)-1]
+	}
+	return ips, nil
+}
+
+// scanPorts returns the ports scanned on every host: Ports if set, otherwise
+// NumPods ports starting at StartPort, PortStep apart.
+func (s *SparseScanner) scanPorts() []int {
+	if len(s.Ports) > 0 {
+		return s.Ports
+	}
+	return PortRange(s.StartPort, s.StartPort+(s.NumPods-1)*s.PortStep, s.PortStep)
+}
+
+// PortRange returns the ports from start to end inclusive, step apart, for use as Ports.
+func PortRange(start, end, step int) []int {
+	if step <= 0 {
+		step = 1
+	}
+	var ports []int
+	for port := start; port <= end; port += step {
+		ports = append(ports, port)
+	}
+	return ports
+}
+
 func (s *SparseScanner) ScanAllPods() {
+	s.ScanAllPodsContext(context.Background())
+}
+
+// ScanAllPodsContext is ScanAllPods with cancellation. When ctx is cancelled, no new pods are
+// dialed, in-flight pods are abandoned, and the results gathered so far are kept and processed.
+// It returns ctx.Err() if the scan was cut short.
+func (s *SparseScanner) ScanAllPodsContext(ctx context.Context) error {
+	if err := s.Validate(); err != nil {
+		s.log().Warnf("⚠️ [ScanAllPods] Nothing to scan: %v", err)
+		return nil
+	}
+
 	startTime := time.Now()
 	var wg sync.WaitGroup
-	resultsChan := make(chan PodResult, s.NumPods*len(s.Hosts))
 
+	// Limit how many pods are dialed at once so large host lists do not exhaust file descriptors
+	sem := make(chan struct{}, s.maxConcurrency())
+
+	// Each result is appended as soon as its pod answers, so an interrupted scan keeps what it found
+dispatch:
 	for _, host := range s.Hosts {
-		for i := 0; i < s.NumPods; i++ {
-			port := s.StartPort + i*s.PortStep
+		for _, port := range s.scanPorts() {
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				break dispatch
+			}
 			wg.Add(1)
 			go func(host string, port int) {
 				defer wg.Done()
-				result := s.checkPod(host, port)
-				resultsChan <- result
+				defer func() { <-sem }()
+				defer func() {
+					if r := recover(); r != nil {
+						s.log().Errorf("❌ [ScanAllPods] Panic scanning %s: %v\n%s", podAddr(host, port), r, debug.Stack())
+						s.appendResult(PodResult{Host: host, Port: port, Error: fmt.Sprintf("panic during scan: %v", r)})
+					}
+				}()
+				podStart := time.Now()
+				result := s.checkPodContext(ctx, host, port)
+				if !result.Success && ctx.Err() != nil {
+					return // Abandoned by cancellation, not a real pod failure
+				}
+				s.metrics().ObserveScanDuration(time.Since(podStart))
+				s.metrics().PodScanned(result.Success)
+				s.appendResult(result)
 			}(host, port)
 		}
 	}
 
 	wg.Wait()
-	close(resultsChan)
 
-	for result := range resultsChan {
-		s.Results = append(s.Results, result)
+	s.processResults()
+
+	if err := ctx.Err(); err != nil {
+		s.log().Warnf("⚠️ Discovery cancelled after %s: %v", time.Since(startTime), err)
+		return err
 	}
+	s.log().Infof("🌌 Discovery complete in %s", time.Since(startTime))
+	return nil
+}
 
-	s.processResults()
+// RescanFailures checks every failed pod in Results again, replacing each entry in place and
+// recording the planets and cubes of pods that now answer. It uses the same concurrency bound
+// as ScanAllPods and returns the number of pods that recovered. Each new result is also written to
+// the WriteResultsJSONL stream and passed to OnPodResult, as a record that replaces the earlier one
+// for the same host and port. If Results is replaced while it runs, for example by Rescan, results
+// for pods no longer listed are dropped.
+func (s *SparseScanner) RescanFailures() int {
+	type failedPod struct {
+		idx  int
+		host string
+		port int
+	}
+	s.resultsMu.Lock()
+	var failed []failedPod
+	for i, res := range s.Results {
+		if !res.Success {
+			failed = append(failed, failedPod{i, res.Host, res.Port})
+		}
+	}
+	s.resultsMu.Unlock()
+
+	var wg sync.WaitGroup
+	recovered := 0 // Guarded by resultsMu
+	sem := make(chan struct{}, s.maxConcurrency())
+	for _, pod := range failed {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(pod failedPod) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			result := s.checkPod(pod.host, pod.port)
 
-	fmt.Printf("\n🌌 Discovery complete in %s\n", time.Since(startTime))
+			s.resultsMu.Lock()
+			replaced := s.replaceResult(pod.idx, result)
+			if replaced {
+				s.streamResult(result)
+				if result.Success {
+					recovered++
+				}
+			}
+			s.resultsMu.Unlock()
+			if replaced {
+				s.notifyResult(result)
+				s.recordPodResult(result)
+			}
+		}(pod)
+	}
+	wg.Wait()
+
+	s.log().Infof("🔁 Rescanned %d failed pods, %d recovered", len(failed), recovered)
+	return recovered
 }
 
-func (s *SparseScanner) processResults() {
-	for _, result := range s.Results {
-		if !result.Success {
-			continue
-		}
-		for _, planet := range result.Planets {
-			coords := [3]float64{
-				planet.Position["x"],
-				planet.Position["y"],
-				planet.Position["z"],
+// replaceResult stores result over the entry for the same pod, looking first at idx and then, if
+// Results has changed since idx was taken, at every entry. It reports false if the pod is no
+// longer listed. The caller must hold resultsMu.
+func (s *SparseScanner) replaceResult(idx int, result PodResult) bool {
+	samePod := func(i int) bool {
+		return s.Results[i].Host == result.Host && s.Results[i].Port == result.Port
+	}
+	if idx >= len(s.Results) || !samePod(idx) {
+		idx = -1
+		for i := range s.Results {
+			if samePod(i) {
+				idx = i
+				break
 			}
-			s.PlanetsMap[planet.Name] = PlanetRecord{
-				Name:        planet.Name,
-				Coordinates: coords,
-				Host:        result.Host,
-				Port:        result.Port,
+		}
+		if idx < 0 {
+			return false
+		}
+	}
+	s.Results[idx] = result
+	return true
+}
+
+// PingPod dials a pod and authenticates without fetching cubes or planets. It reports whether
+// the pod accepted the credentials and the round-trip time of the auth exchange.
+func (s *SparseScanner) PingPod(host string, port int) (bool, time.Duration, error) {
+	addr := podAddr(host, port)
+	timeout := s.hostTimeout(host)
+	rawConn, err := s.dial(addr, timeout)
+	if err != nil {
+		return false, 0, fmt.Errorf("[PingPod] Failed to connect to %s: %v", addr, err)
+	}
+	conn := withReadTimeout(withDelimiter(rawConn, s.EndMarker), timeout)
+	defer conn.Close()
+
+	start := time.Now()
+	if err := send(conn, string(AuthMessage(s.AuthPass))); err != nil {
+		return false, 0, fmt.Errorf("[PingPod] Failed to send auth to %s: %v", addr, err)
+	}
+	authResp := read(conn)
+	latency := time.Since(start)
+	if !strings.Contains(authResp, "auth_success") {
+		return false, latency, fmt.Errorf("[PingPod] Authentication failed on %s: %s", addr, authResp)
+	}
+	return true, latency, nil
+}
+
+// PingAll pings every configured pod with the same concurrency bound as ScanAllPods and
+// returns the auth round-trip time of each pod that answered, keyed by host:port.
+func (s *SparseScanner) PingAll() map[string]time.Duration {
+	alive := make(map[string]time.Duration)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, s.maxConcurrency())
+	for _, host := range s.Hosts {
+		for _, port := range s.scanPorts() {
+			sem <- struct{}{}
+			wg.Add(1)
+			go func(host string, port int) {
+				defer wg.Done()
+				defer func() { <-sem }()
+				ok, latency, _ := s.PingPod(host, port)
+				if !ok {
+					return
+				}
+				mu.Lock()
+				alive[podAddr(host, port)] = latency
+				mu.Unlock()
+			}(host, port)
+		}
+	}
+	wg.Wait()
+
+	s.log().Infof("📡 %d pods alive", len(alive))
+	return alive
+}
+
+// dialAttempts returns the scanner's DialAttempts, or the package DialAttempts if it is not set.
+func (s *SparseScanner) dialAttempts() int {
+	if s.DialAttempts > 0 {
+		return s.DialAttempts
+	}
+	return DialAttempts
+}
+
+// dial connects to a pod, retrying as dialAttempts says, with timeout per attempt.
+func (s *SparseScanner) dial(addr string, timeout time.Duration) (net.Conn, error) {
+	return dialWithRetryContext(context.Background(), &net.Dialer{Timeout: timeout}, addr, s.dialAttempts(), DialBaseDelay)
+}
+
+// hostTimeout returns the dial and read timeout for a host: its HostTimeouts entry if set,
+// otherwise TimeoutSec.
+func (s *SparseScanner) hostTimeout(host string) time.Duration {
+	if timeout, ok := s.HostTimeouts[host]; ok && timeout > 0 {
+		return timeout
+	}
+	return time.Duration(s.TimeoutSec) * time.Second
+}
+
+// DryRunMessages returns the pod commands the scanner would have sent while DryRun was set.
+func (s *SparseScanner) DryRunMessages() []string {
+	return s.dryRun.Messages()
+}
+
+// log returns the scanner's logger.
+func (s *SparseScanner) log() Logger {
+	return loggerOrDefault(s.Logger)
+}
+
+// metrics returns the scanner's metrics sink.
+func (s *SparseScanner) metrics() Metrics {
+	return metricsOrDefault(s.Metrics)
+}
+
+// maxConcurrency returns the number of pods scanned at once.
+func (s *SparseScanner) maxConcurrency() int {
+	if s.MaxConcurrency > 0 {
+		return s.MaxConcurrency
+	}
+	return defaultMaxConcurrency
+}
+
+// appendResult adds a pod result to s.Results, streams it to the WriteResultsJSONL writer, and
+// passes it to OnPodResult; it is safe to call from scan goroutines.
+func (s *SparseScanner) appendResult(result PodResult) {
+	s.resultsMu.Lock()
+	s.Results = append(s.Results, result)
+	s.streamResult(result)
+	s.resultsMu.Unlock()
+	s.notifyResult(result)
+}
+
+// streamResult writes result to the WriteResultsJSONL writer, if any. The caller must hold resultsMu.
+func (s *SparseScanner) streamResult(result PodResult) {
+	if s.resultsJSONL == nil {
+		return
+	}
+	if err := s.resultsJSONL.Encode(result); err != nil {
+		s.log().Warnf("⚠️ [WriteResultsJSONL] Failed to write result for %s:%d: %v", result.Host, result.Port, err)
+	}
+}
+
+// notifyResult passes result to OnPodResult, if set, one call at a time.
+func (s *SparseScanner) notifyResult(result PodResult) {
+	if s.OnPodResult == nil {
+		return
+	}
+	s.callbackMu.Lock()
+	s.OnPodResult(result)
+	s.callbackMu.Unlock()
+}
+
+// resultsCopy returns a copy of s.Results taken under resultsMu, safe to walk while scans run.
+func (s *SparseScanner) resultsCopy() []PodResult {
+	s.resultsMu.Lock()
+	defer s.resultsMu.Unlock()
+	return append([]PodResult(nil), s.Results...)
+}
+
+func (s *SparseScanner) processResults() {
+	for _, result := range s.resultsCopy() {
+		s.recordPodResult(result)
+	}
+}
+
+// recordPodResult adds a successful pod's planets and cubes to PlanetsMap and CubesMap.
+func (s *SparseScanner) recordPodResult(result PodResult) {
+	if !result.Success {
+		return
+	}
+	s.mapsMu.Lock()
+	defer s.mapsMu.Unlock()
+	addr := podAddr(result.Host, result.Port)
+	for _, planet := range result.Planets {
+		record := PlanetRecord{
+			Name:        planet.Name,
+			Coordinates: s.planetCoords(planet),
+			Seed:        planet.Seed,
+			BiomeType:   planet.BiomeType,
+			Host:        result.Host,
+			Port:        result.Port,
+		}
+		key := planet.Name
+		if s.PlanetMergeEpsilon > 0 {
+			if name, ok := s.matchPlanet(record.Coordinates); ok {
+				// The same physical planet seen again: keep the first record, add this pod to it
+				key, record = name, s.PlanetsMap[name]
 			}
 		}
-		for _, cube := range result.Cubes {
-			s.CubesMap[cube] = result.Host
+		existing := s.PlanetsMap[key]
+		record.Hosts = append([]string(nil), existing.Hosts...)
+		if !slices.Contains(record.Hosts, addr) {
+			record.Hosts = append(record.Hosts, addr)
 		}
+		s.PlanetsMap[key] = record
+	}
+	for _, cube := range result.Cubes {
+		s.CubesMap[cube] = result.Host
+		s.cubeAddrs[cube] = podAddr(result.Host, result.Port)
+	}
+}
+
+// matchPlanet returns the name of a recorded planet whose coordinates are each within
+// PlanetMergeEpsilon of coords. The caller must hold mapsMu.
+func (s *SparseScanner) matchPlanet(coords [3]float64) (string, bool) {
+	for name, planet := range s.PlanetsMap {
+		if math.Abs(planet.Coordinates[0]-coords[0]) <= s.PlanetMergeEpsilon &&
+			math.Abs(planet.Coordinates[1]-coords[1]) <= s.PlanetMergeEpsilon &&
+			math.Abs(planet.Coordinates[2]-coords[2]) <= s.PlanetMergeEpsilon {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// planetCoords reads a planet's position using the scanner's configured coordinate keys,
+// falling back to lowercase x/y/z when none are set.
+func (s *SparseScanner) planetCoords(planet Planet) [3]float64 {
+	keys := s.CoordKeys
+	if keys == [3]string{} {
+		keys = defaultCoordKeys
+	}
+	return [3]float64{
+		planet.Position[keys[0]],
+		planet.Position[keys[1]],
+		planet.Position[keys[2]],
 	}
 }
 
@@ -165,24 +635,32 @@ func (s *SparseScanner) PrintSummary() {
 	successCount := 0
 
 	fmt.Println("\n=== MULTIVERSE SUMMARY ===")
+	if err := s.Validate(); err != nil {
+		fmt.Printf("⚠️ No pods were scanned: %v\n", err)
+		return
+	}
 	for _, res := range s.Results {
 		if res.Success {
 			successCount++
 			totalCubes += len(res.Cubes)
 			totalPlanets += len(res.Planets)
-			fmt.Printf("[%s:%d] ✅ Connected: Cubes=%d Planets=%d\n", res.Host, res.Port, len(res.Cubes), len(res.Planets))
+			fmt.Printf("[%s:%d] ✅ Connected: Version=%s Cubes=%d Planets=%d\n", res.Host, res.Port, res.Version, len(res.Cubes), len(res.Planets))
 		} else {
 			fmt.Printf("[%s:%d] ❌ Failed: %s\n", res.Host, res.Port, res.Error)
 		}
 	}
 
-	fmt.Printf("\n✅ Successful pods: %d / %d\n", successCount, s.NumPods*len(s.Hosts))
+	fmt.Printf("\n✅ Successful pods: %d / %d\n", successCount, len(s.scanPorts())*len(s.Hosts))
 	fmt.Printf("🧱 Total Cubes: %d\n", totalCubes)
 	fmt.Printf("🪐 Total Planets: %d\n", totalPlanets)
+	s.mapsMu.RLock()
 	fmt.Printf("🔭 Total unique planets mapped: %d\n", len(s.PlanetsMap))
+	s.mapsMu.RUnlock()
 }
 
 func (s *SparseScanner) ExtractPlanetCenters() [][]float64 {
+	s.mapsMu.RLock()
+	defer s.mapsMu.RUnlock()
 	centers := [][]float64{}
 	for _, planet := range s.PlanetsMap {
 		centers = append(centers, []float64{
@@ -194,40 +672,159 @@ func (s *SparseScanner) ExtractPlanetCenters() [][]float64 {
 	return centers
 }
 
+// FindNearestPlanet returns the discovered planet closest to point, its Euclidean distance,
+// and false if no planets were discovered or point does not have 3 components.
+func (s *SparseScanner) FindNearestPlanet(point []float64) (PlanetRecord, float64, bool) {
+	if len(point) < 3 {
+		return PlanetRecord{}, 0, false
+	}
+	s.mapsMu.RLock()
+	defer s.mapsMu.RUnlock()
+
+	var best PlanetRecord
+	bestDist := math.Inf(1)
+	found := false
+	for _, planet := range s.PlanetsMap {
+		dx := planet.Coordinates[0] - point[0]
+		dy := planet.Coordinates[1] - point[1]
+		dz := planet.Coordinates[2] - point[2]
+		distance := math.Sqrt(dx*dx + dy*dy + dz*dz)
+		// Break ties by name so the result does not depend on map order
+		if !found || distance < bestDist || (distance == bestDist && planet.Name < best.Name) {
+			best, bestDist, found = planet, distance, true
+		}
+	}
+	return best, bestDist, found
+}
+
+// GetPlanetsByBiome returns the discovered planets of the given biome, sorted by name.
+func (s *SparseScanner) GetPlanetsByBiome(biome int) []PlanetRecord {
+	s.mapsMu.RLock()
+	defer s.mapsMu.RUnlock()
+	var planets []PlanetRecord
+	for _, planet := range s.PlanetsMap {
+		if planet.BiomeType == biome {
+			planets = append(planets, planet)
+		}
+	}
+	sort.Slice(planets, func(i, j int) bool { return planets[i].Name < planets[j].Name })
+	return planets
+}
+
+// SaveResultsJSON writes the scan results to filename so a discovery run can be replayed offline.
+func (s *SparseScanner) SaveResultsJSON(filename string) error {
+	s.resultsMu.Lock()
+	data, err := json.MarshalIndent(s.Results, "", "  ")
+	s.resultsMu.Unlock()
+	if err != nil {
+		return fmt.Errorf("[SaveResultsJSON] Failed to encode results: %v", err)
+	}
+	if err := os.WriteFile(filename, data, 0644); err != nil {
+		return fmt.Errorf("[SaveResultsJSON] Failed to write %s: %v", filename, err)
+	}
+	return nil
+}
+
+// WriteResultsJSONL writes each result gathered so far to w as one JSON object per line, then
+// keeps writing every result appended by later scans as its pod completes, so w can be tailed
+// during a long scan. RescanFailures writes a second line for each pod it checks again; the later
+// line for a host and port replaces the earlier one. Write errors on streamed results are logged. Pass nil to stop streaming.
+func (s *SparseScanner) WriteResultsJSONL(w io.Writer) error {
+	s.resultsMu.Lock()
+	defer s.resultsMu.Unlock()
+	if w == nil {
+		s.resultsJSONL = nil
+		return nil
+	}
+	enc := json.NewEncoder(w)
+	for _, result := range s.Results {
+		if err := enc.Encode(result); err != nil {
+			return fmt.Errorf("[WriteResultsJSONL] Failed to write result for %s:%d: %v", result.Host, result.Port, err)
+		}
+	}
+	s.resultsJSONL = enc
+	return nil
+}
+
+// LoadResultsJSON replaces the scan results with those saved by SaveResultsJSON and rebuilds
+// PlanetsMap and CubesMap from them, as if the pods had just been scanned.
+func (s *SparseScanner) LoadResultsJSON(filename string) error {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return fmt.Errorf("[LoadResultsJSON] Failed to read %s: %v", filename, err)
+	}
+	var results []PodResult
+	if err := json.Unmarshal(data, &results); err != nil {
+		return fmt.Errorf("[LoadResultsJSON] Failed to decode %s: %v", filename, err)
+	}
+
+	s.resultsMu.Lock()
+	s.Results = results
+	s.resultsMu.Unlock()
+	s.mapsMu.Lock()
+	s.PlanetsMap = make(map[string]PlanetRecord)
+	s.CubesMap = make(map[string]string)
+	s.cubeAddrs = make(map[string]string)
+	s.mapsMu.Unlock()
+	s.processResults()
+	return nil
+}
+
 // --- INTERNAL HELPERS ---
 
 func (s *SparseScanner) checkPod(host string, port int) PodResult {
-	addr := fmt.Sprintf("%s:%d", host, port)
-	conn, err := net.DialTimeout("tcp", addr, time.Duration(s.TimeoutSec)*time.Second)
+	return s.checkPodContext(context.Background(), host, port)
+}
+
+// checkPodContext is checkPod with cancellation. Cancelling ctx aborts the dial or closes the
+// connection, so a pod that is mid-handshake stops promptly.
+func (s *SparseScanner) checkPodContext(ctx context.Context, host string, port int) PodResult {
+	addr := podAddr(host, port)
+	timeout := s.hostTimeout(host)
+	rawConn, err := dialWithRetryContext(ctx, &net.Dialer{Timeout: timeout}, addr, s.dialAttempts(), DialBaseDelay)
 	if err != nil {
 		return PodResult{Host: host, Port: port, Success: false, Error: fmt.Sprintf("Failed to connect: %v", err)}
 	}
+	conn := withReadTimeout(withDelimiter(rawConn, s.EndMarker), timeout)
 	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
 
-	if err := send(conn, s.AuthPass); err != nil {
+	if err := send(conn, string(AuthMessage(s.AuthPass))); err != nil {
 		return PodResult{Host: host, Port: port, Success: false, Error: fmt.Sprintf("Failed to send auth: %v", err)}
 	}
 	authResp := read(conn)
+	if err := ctx.Err(); err != nil {
+		return PodResult{Host: host, Port: port, Success: false, Error: fmt.Sprintf("Scan cancelled: %v", err)}
+	}
 	if !strings.Contains(authResp, "auth_success") {
+		reportRawResponse(authResp)
 		return PodResult{Host: host, Port: port, Success: false, Error: fmt.Sprintf("Authentication failed: %s", authResp)}
 	}
 
-	if err := send(conn, `{"type":"get_cube_list"}`); err != nil {
-		return PodResult{Host: host, Port: port, Success: false, Error: "Failed to request cubes"}
+	var version string
+	if s.QueryVersion {
+		version = queryVersion(conn)
+	}
+
+	cubes, err := GetCubeList(conn)
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return PodResult{Host: host, Port: port, Success: false, Error: fmt.Sprintf("Scan cancelled: %v", ctxErr)}
 	}
-	cubesRaw := read(conn)
-	var cubeData map[string]interface{}
-	if err := json.Unmarshal([]byte(cubesRaw), &cubeData); err != nil {
-		return PodResult{Host: host, Port: port, Success: false, Error: "Failed to parse cube list"}
+	if err != nil {
+		return PodResult{Host: host, Port: port, Success: false, Error: fmt.Sprintf("Failed to get cube list: %v", err)}
 	}
-	cubes := toStringArray(cubeData["cubes"])
 
 	if err := send(conn, `{"type":"get_planets"}`); err != nil {
 		return PodResult{Host: host, Port: port, Success: false, Error: "Failed to request planets"}
 	}
 	planetsRaw := read(conn)
+	if err := ctx.Err(); err != nil {
+		return PodResult{Host: host, Port: port, Success: false, Error: fmt.Sprintf("Scan cancelled: %v", err)}
+	}
 	var planetData map[string][]Planet
 	if err := json.Unmarshal([]byte(planetsRaw), &planetData); err != nil {
+		reportRawResponse(planetsRaw)
 		return PodResult{Host: host, Port: port, Success: false, Error: "Failed to parse planet list"}
 	}
 	var allPlanets []Planet
@@ -239,82 +836,176 @@ func (s *SparseScanner) checkPod(host string, port int) PodResult {
 		Host:    host,
 		Port:    port,
 		Success: true,
+		Version: version,
 		Cubes:   cubes,
 		Planets: allPlanets,
 	}
 }
 
+// cubeAddr returns the host:port of the pod that owns the cube, according to the latest scan.
+func (s *SparseScanner) cubeAddr(cubeName string) (string, error) {
+	s.mapsMu.RLock()
+	addr, ok := s.cubeAddrs[cubeName]
+	s.mapsMu.RUnlock()
+	if !ok {
+		return "", fmt.Errorf("cube %s not found in scan results", cubeName)
+	}
+	return addr, nil
+}
+
+// maxWorkers returns the configured request concurrency, falling back to defaultMaxWorkers.
+func (s *SparseScanner) maxWorkers() int {
+	if s.MaxWorkers > 0 {
+		return s.MaxWorkers
+	}
+	return defaultMaxWorkers
+}
+
+// acquireHostSlot blocks until a connection to host is allowed under MaxConnsPerHost
+// and returns the function that gives the slot back.
+func (s *SparseScanner) acquireHostSlot(host string) func() {
+	if s.MaxConnsPerHost <= 0 {
+		return func() {}
+	}
+	s.hostSlotsMu.Lock()
+	if s.hostSlots == nil {
+		s.hostSlots = make(map[string]chan struct{})
+	}
+	slots, ok := s.hostSlots[host]
+	if !ok {
+		slots = make(chan struct{}, s.MaxConnsPerHost)
+		s.hostSlots[host] = slots
+	}
+	s.hostSlotsMu.Unlock()
+
+	slots <- struct{}{}
+	var once sync.Once
+	return func() { once.Do(func() { <-slots }) }
+}
+
+// hostLimitedConn releases its MaxConnsPerHost slot when closed.
+type hostLimitedConn struct {
+	net.Conn
+	release func()
+}
+
+func (c *hostLimitedConn) Close() error {
+	defer c.release()
+	return c.Conn.Close()
+}
+
+// dialPod connects to a pod and authenticates with the scanner's credentials.
+// The connection counts against MaxConnsPerHost until it is closed.
+func (s *SparseScanner) dialPod(addr string) (net.Conn, error) {
+	return s.dialPodWith(addr, s.AuthPass, s.EndMarker)
+}
+
+// dialPodWith is dialPod with explicit credentials and message delimiter.
+func (s *SparseScanner) dialPodWith(addr, authPass, delim string) (net.Conn, error) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pod address %s: %v", addr, err)
+	}
+	if s.DryRun {
+		conn, _, err := authenticate(newDryRunConn(delim, &s.dryRun, s.log(), false, nil), addr, authPass, delim)
+		return conn, err
+	}
+	release := s.acquireHostSlot(host)
+
+	timeout := s.hostTimeout(host)
+	rawConn, err := s.dial(addr, timeout)
+	if err != nil {
+		release()
+		return nil, fmt.Errorf("failed to connect to %s: %v", addr, err)
+	}
+	conn := withReadTimeout(withDelimiter(&hostLimitedConn{Conn: rawConn, release: release}, delim), timeout)
+	if err := send(conn, string(AuthMessage(authPass))); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to send auth to %s: %v", addr, err)
+	}
+	if authResp := read(conn); !strings.Contains(authResp, "auth_success") {
+		conn.Close()
+		reportRawResponse(authResp)
+		return nil, fmt.Errorf("authentication failed on %s: %s", addr, authResp)
+	}
+	return conn, nil
+}
+
+// queryCube sends a command to the pod that owns the cube and returns the raw response.
+func (s *SparseScanner) queryCube(cubeName string, msg Message) (string, error) {
+	addr, err := s.cubeAddr(cubeName)
+	if err != nil {
+		return "", err
+	}
+	conn, err := s.dialPod(addr)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+
+	if err := sendJSONMessage(conn, msg); err != nil {
+		return "", fmt.Errorf("failed to send %v to %s: %v", msg["type"], addr, err)
+	}
+	return readResponse(conn)
+}
+
+// queryVersion asks an authenticated pod for its server version. Servers that do not support
+// get_version are not treated as failures; their version is recorded as "unknown". The reply is
+// only waited for AckTimeout, and one that arrives later is skipped by the next read.
+func queryVersion(conn net.Conn) string {
+	msg := Message{"type": "get_version"}
+	if err := sendJSONMessage(conn, msg); err != nil {
+		return unknownVersion
+	}
+	raw, err := awaitAck(conn, msg, "version")
+	if err != nil || raw == "" {
+		return unknownVersion
+	}
+	var resp struct {
+		Version string `json:"version"`
+	}
+	if err := json.Unmarshal([]byte(raw), &resp); err != nil || resp.Version == "" {
+		reportRawResponse(raw)
+		return unknownVersion
+	}
+	return resp.Version
+}
+
 // --- GLOBAL HELPERS ---
 
+// podAddr formats a host and port as a dialable address.
+func podAddr(host string, port int) string {
+	return net.JoinHostPort(host, strconv.Itoa(port))
+}
+
 func send(conn net.Conn, msg string) error {
-	_, err := conn.Write([]byte(msg + endMarker))
+	_, err := conn.Write([]byte(msg + connDelimiter(conn)))
 	return err
 }
 
+// read returns the next message on conn with surrounding whitespace trimmed, or "" if no full
+// message arrived before its read timeout expired. A delimited connection keeps the start of a cut
+// message for the next read; on other connections that partial message is returned instead.
 func read(conn net.Conn) string {
-	reader := bufio.NewReader(conn)
-	conn.SetReadDeadline(time.Now().Add(timeoutSec * time.Second))
-	var buf bytes.Buffer
-	chunk := make([]byte, 1024)
-	for {
-		n, err := reader.Read(chunk)
-		if err != nil && err != io.EOF {
-			break
-		}
-		buf.Write(chunk[:n])
-		if strings.HasSuffix(buf.String(), endMarker) {
-			break
-		}
-		if err == io.EOF {
-			break
-		}
-	}
-	msg := buf.String()
-	if len(msg) >= len(endMarker) && strings.HasSuffix(msg, endMarker) {
-		return msg[:len(msg)-len(endMarker)]
-	}
+	msg, _ := readReply(conn, connReadTimeout(conn))
 	return msg
 }
 
 func (s *SparseScanner) ScanSinglePod(host string, port int) PodResult {
 	result := s.checkPod(host, port)
-	if result.Success {
-		for _, planet := range result.Planets {
-			coords := [3]float64{planet.Position["x"], planet.Position["y"], planet.Position["z"]}
-			s.PlanetsMap[planet.Name] = PlanetRecord{
-				Name:        planet.Name,
-				Coordinates: coords,
-				Host:        result.Host,
-				Port:        result.Port,
-			}
-		}
-		for _, cube := range result.Cubes {
-			s.CubesMap[cube] = result.Host
-		}
-	}
+	s.recordPodResult(result)
 	return result // Do not append to s.Results here
 }
 
 func (s *SparseScanner) AddPodResult(result PodResult) {
-	s.Results = append(s.Results, result)
-	if result.Success {
-		for _, planet := range result.Planets {
-			coords := [3]float64{planet.Position["x"], planet.Position["y"], planet.Position["z"]}
-			s.PlanetsMap[planet.Name] = PlanetRecord{
-				Name:        planet.Name,
-				Coordinates: coords,
-				Host:        result.Host,
-				Port:        result.Port,
-			}
-		}
-		for _, cube := range result.Cubes {
-			s.CubesMap[cube] = result.Host
-		}
-	}
+	s.appendResult(result)
+	s.recordPodResult(result)
 }
 
 // GetCubesByPrefix returns a list of cube names that start with the given prefix.
 func (s *SparseScanner) GetCubesByPrefix(prefix string) []string {
+	s.mapsMu.RLock()
+	defer s.mapsMu.RUnlock()
 	filteredCubes := []string{}
 	for cubeName := range s.CubesMap {
 		if strings.HasPrefix(cubeName, prefix) {
@@ -335,8 +1026,7 @@ func (s *SparseScanner) GetCubesAndConnections(prefix string) ([]CubeConnection,
 	// Step 2: For each cube, get its joints and attempt to find connections
 	result := make([]CubeConnection, 0, len(cubes))
 	for _, cube := range cubes {
-		// Get joints for this cube using the existing function
-		joints := getJointsForCube(cube)
+		joints := s.cubeJoints(cube)
 
 		// Prepare the list of joint information
 		jointInfos := make([]JointInfo, 0, len(joints))
@@ -378,6 +1068,42 @@ func (s *SparseScanner) GetCubesAndConnections(prefix string) ([]CubeConnection,
 	return result, nil
 }
 
+// cubeJoints returns the joints attached to a cube, over Session when one is set, then over a
+// connection from Pool, and otherwise over a new connection to serverAddr. Failures are logged
+// and yield no joints.
+func (s *SparseScanner) cubeJoints(cubeName string) []string {
+	switch {
+	case s.Session != nil:
+		joints, err := s.Session.GetJointsForCube(cubeName)
+		if err != nil {
+			s.log().Warnf("[getJointsForCube] %v", err)
+			return nil
+		}
+		return joints
+	case s.Pool != nil:
+		conn, err := s.Pool.Get(serverAddr)
+		if err != nil {
+			s.log().Warnf("[getJointsForCube] %v", err)
+			return nil
+		}
+		raw, err := sendCommand(conn, Message{"type": "get_joints_for_cube", "cube_name": cubeName})
+		if err != nil {
+			s.Pool.Discard(serverAddr, conn)
+			s.log().Warnf("[getJointsForCube] %v", err)
+			return nil
+		}
+		s.Pool.Put(serverAddr, conn)
+		joints, err := parseJointsForCube(raw)
+		if err != nil {
+			s.log().Warnf("[getJointsForCube] %v", err)
+			return nil
+		}
+		return joints
+	default:
+		return getJointsForCube(cubeName)
+	}
+}
+
 // GetCubesAndConnectionsParallel retrieves all cubes starting with the given prefix and their connections,
 // using a thread pool to parallelize the requests.
 func (s *SparseScanner) GetCubesAndConnectionsParallel(prefix string) ([]CubeConnection, error) {
@@ -388,11 +1114,17 @@ func (s *SparseScanner) GetCubesAndConnectionsParallel(prefix string) ([]CubeCon
 	}
 
 	// Step 2: Set up thread pool parameters
-	const maxWorkers = 10                                // Maximum number of concurrent requests to the server
+	maxWorkers := s.maxWorkers()                         // Maximum number of concurrent requests to the server
 	sem := make(chan struct{}, maxWorkers)               // Semaphore to limit concurrency
 	var wg sync.WaitGroup                                // To wait for all goroutines to complete
 	resultsChan := make(chan CubeConnection, len(cubes)) // Channel to collect results
 
+	// getJointsForCube always talks to serverAddr, so that is the host whose connections are capped
+	serverHost, _, err := net.SplitHostPort(serverAddr)
+	if err != nil {
+		serverHost = serverAddr
+	}
+
 	// Step 3: Process each cube in parallel
 	for _, cube := range cubes {
 		wg.Add(1)
@@ -401,8 +1133,15 @@ func (s *SparseScanner) GetCubesAndConnectionsParallel(prefix string) ([]CubeCon
 			defer wg.Done()
 			defer func() { <-sem }() // Release the slot when done
 
-			// Get joints for this cube
-			joints := getJointsForCube(cubeName)
+			// Get joints for this cube, within the per-host connection cap when dialing
+			var joints []string
+			if s.Session != nil || s.Pool != nil {
+				joints = s.cubeJoints(cubeName)
+			} else {
+				release := s.acquireHostSlot(serverHost)
+				joints = s.cubeJoints(cubeName)
+				release()
+			}
 
 			// Prepare the list of joint information
 			jointInfos := make([]JointInfo, 0, len(joints))
@@ -460,3 +1199,345 @@ func (s *SparseScanner) GetCubesAndConnectionsParallel(prefix string) ([]CubeCon
 
 	return result, nil
 }
+
+// ungroupedConstruct is the key used by GroupCubesIntoConstructs for cubes whose names
+// do not carry a generateUnitID prefix.
+const ungroupedConstruct = "ungrouped"
+
+// GroupCubesIntoConstructs scans a single pod and groups its cubes by their unit name,
+// returning a map of construct -> cube names. Cubes that do not follow the generateUnitID
+// naming scheme are collected under the "ungrouped" key.
+func (s *SparseScanner) GroupCubesIntoConstructs(host string, port int) (map[string][]string, error) {
+	result := s.ScanSinglePod(host, port)
+	if !result.Success {
+		return nil, fmt.Errorf("failed to scan pod %s:%d: %s", host, port, result.Error)
+	}
+
+	constructs := make(map[string][]string)
+	for _, cube := range result.Cubes {
+		unitName, ok := parseUnitID(cube)
+		if !ok {
+			unitName = ungroupedConstruct
+		}
+		constructs[unitName] = append(constructs[unitName], cube)
+	}
+
+	// Sort cube names within each construct for consistency
+	for _, cubes := range constructs {
+		sort.Strings(cubes)
+	}
+
+	return constructs, nil
+}
+
+// IsFrozen asks the pod that owns the cube whether the cube is currently frozen.
+func (s *SparseScanner) IsFrozen(cubeName string) (bool, error) {
+	raw, err := s.queryCube(cubeName, Message{
+		"type":      "get_freeze_state",
+		"cube_name": cubeName,
+	})
+	if err != nil {
+		return false, fmt.Errorf("[IsFrozen] %v", err)
+	}
+	if err := responseError(raw); err != nil {
+		return false, fmt.Errorf("[IsFrozen] Cube %s: %v", cubeName, err)
+	}
+
+	var resp struct {
+		Frozen *bool `json:"frozen"`
+	}
+	if err := json.Unmarshal([]byte(raw), &resp); err != nil || resp.Frozen == nil {
+		reportRawResponse(raw)
+		return false, fmt.Errorf("[IsFrozen] Unexpected freeze state response for %s: %s", cubeName, raw)
+	}
+	return *resp.Frozen, nil
+}
+
+// AllFrozen reports whether every cube whose name starts with prefix is frozen.
+// It stops at the first cube that is not frozen.
+func (s *SparseScanner) AllFrozen(prefix string) (bool, error) {
+	cubes := s.GetCubesByPrefix(prefix)
+	if len(cubes) == 0 {
+		return false, fmt.Errorf("[AllFrozen] no cubes found with prefix %s", prefix)
+	}
+	for _, cube := range cubes {
+		frozen, err := s.IsFrozen(cube)
+		if err != nil {
+			return false, err
+		}
+		if !frozen {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// SeededPlanetLayout returns n spawn positions on a sphere of the given radius around a discovered planet.
+// Positions are derived from the planet's Seed, so re-running the spawner reproduces the same layout.
+func (s *SparseScanner) SeededPlanetLayout(planetName string, n int, radius float64) ([][]float64, error) {
+	s.mapsMu.RLock()
+	planet, ok := s.PlanetsMap[planetName]
+	s.mapsMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("planet %s not found in scan results", planetName)
+	}
+	center := []float64{planet.Coordinates[0], planet.Coordinates[1], planet.Coordinates[2]}
+	return seededSpherePositions(int64(planet.Seed), n, radius, center), nil
+}
+
+// hostColor derives a stable, saturated color for a host name.
+func hostColor(host string) string {
+	hue := float64(hashKey(host) % 360)
+	return hsvToHex(hue, 0.8, 0.95)
+}
+
+// ColorByHost colors every known cube according to the host that owns it, giving a visual
+// map of how the world is sharded across pods. Each pod is updated over a single connection.
+func (s *SparseScanner) ColorByHost() error {
+	// Group cubes by the pod that owns them
+	cubesByAddr := make(map[string][]string)
+	hostByAddr := make(map[string]string)
+	s.mapsMu.RLock()
+	for cube, addr := range s.cubeAddrs {
+		cubesByAddr[addr] = append(cubesByAddr[addr], cube)
+		hostByAddr[addr] = s.CubesMap[cube]
+	}
+	s.mapsMu.RUnlock()
+	if len(cubesByAddr) == 0 {
+		return fmt.Errorf("[ColorByHost] no cubes found in scan results")
+	}
+
+	var wg sync.WaitGroup
+	var errMu sync.Mutex
+	var errs []error
+	for addr, cubes := range cubesByAddr {
+		wg.Add(1)
+		go func(addr string, cubes []string) {
+			defer wg.Done()
+			conn, err := s.dialPod(addr)
+			if err != nil {
+				errMu.Lock()
+				errs = append(errs, err)
+				errMu.Unlock()
+				return
+			}
+			defer conn.Close()
+
+			hex := hostColor(hostByAddr[addr])
+			for _, cube := range cubes {
+				colorMsg := Message{
+					"type":      "set_color",
+					"cube_name": cube,
+					"hex":       hex,
+				}
+				if err := sendJSONMessage(conn, colorMsg); err != nil {
+					errMu.Lock()
+					errs = append(errs, fmt.Errorf("failed to color cube %s on %s: %v", cube, addr, err))
+					errMu.Unlock()
+				}
+			}
+			s.log().Infof("🎨 Colored %d cubes on %s as %s", len(cubes), addr, hex)
+		}(addr, cubes)
+	}
+	wg.Wait()
+
+	if len(errs) > 0 {
+		return fmt.Errorf("[ColorByHost] %d errors, first: %v", len(errs), errs[0])
+	}
+	return nil
+}
+
+// DespawnByPrefix despawns every scanned cube whose name starts with prefix on the pod that owns
+// it, over one connection per pod, and forgets the cubes it despawned. Empty authPass or delimiter
+// fall back to the scanner's AuthPass and EndMarker.
+func (s *SparseScanner) DespawnByPrefix(prefix, authPass, delimiter string) error {
+	if authPass == "" {
+		authPass = s.AuthPass
+	}
+	if delimiter == "" {
+		delimiter = s.EndMarker
+	}
+
+	// Group cubes by the pod that owns them
+	cubesByAddr := make(map[string][]string)
+	s.mapsMu.RLock()
+	for cube, addr := range s.cubeAddrs {
+		if strings.HasPrefix(cube, prefix) {
+			cubesByAddr[addr] = append(cubesByAddr[addr], cube)
+		}
+	}
+	s.mapsMu.RUnlock()
+	if len(cubesByAddr) == 0 {
+		return fmt.Errorf("[DespawnByPrefix] no cubes found with prefix %q", prefix)
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var errs []error
+	removed := make(map[string]bool)
+	for addr, cubes := range cubesByAddr {
+		wg.Add(1)
+		go func(addr string, cubes []string) {
+			defer wg.Done()
+			conn, err := s.dialPodWith(addr, authPass, delimiter)
+			if err != nil {
+				mu.Lock()
+				errs = append(errs, err)
+				mu.Unlock()
+				return
+			}
+			defer conn.Close()
+
+			sent := 0
+			for _, cube := range cubes {
+				err := sendJSONMessage(conn, Message{
+					"type":      "despawn_cube",
+					"cube_name": cube,
+				})
+				mu.Lock()
+				if err != nil {
+					errs = append(errs, fmt.Errorf("failed to despawn cube %s on %s: %v", cube, addr, err))
+				} else {
+					removed[cube] = true
+					sent++
+				}
+				mu.Unlock()
+			}
+			s.metrics().CubesDespawned(sent)
+			s.log().Infof("🧹 Despawned %d cubes on %s", sent, addr)
+		}(addr, cubes)
+	}
+	wg.Wait()
+
+	if !s.DryRun {
+		s.mapsMu.Lock()
+		for cube := range removed {
+			delete(s.CubesMap, cube)
+			delete(s.cubeAddrs, cube)
+		}
+		s.mapsMu.Unlock()
+
+		cubeListMutex.Lock()
+		kept := globalCubeList[:0]
+		for _, name := range globalCubeList {
+			if !removed[name] {
+				kept = append(kept, name)
+			}
+		}
+		globalCubeList = kept
+		cubeListMutex.Unlock()
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("[DespawnByPrefix] %d errors, first: %v", len(errs), errs[0])
+	}
+	return nil
+}
+
+// transformBatchSize is the number of cube positions fetched over one connection by GetCubesWithTransforms.
+const transformBatchSize = 25
+
+// GetCubesWithTransforms returns the current position of every cube whose name starts with prefix.
+// Cubes are fetched in batches over one connection per batch, with at most MaxWorkers batches
+// in flight, so the owning pods are not flooded with connections.
+func (s *SparseScanner) GetCubesWithTransforms(prefix string) (map[string][3]float64, error) {
+	cubes := s.GetCubesByPrefix(prefix)
+	if len(cubes) == 0 {
+		return nil, fmt.Errorf("no cubes found with prefix %s", prefix)
+	}
+
+	// Group cubes by owning pod and split each pod's cubes into batches
+	cubesByAddr := make(map[string][]string)
+	for _, cube := range cubes {
+		addr, err := s.cubeAddr(cube)
+		if err != nil {
+			return nil, err
+		}
+		cubesByAddr[addr] = append(cubesByAddr[addr], cube)
+	}
+
+	sem := make(chan struct{}, s.maxWorkers())
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	positions := make(map[string][3]float64, len(cubes))
+	var errs []error
+
+	for addr, podCubes := range cubesByAddr {
+		for start := 0; start < len(podCubes); start += transformBatchSize {
+			end := start + transformBatchSize
+			if end > len(podCubes) {
+				end = len(podCubes)
+			}
+			wg.Add(1)
+			sem <- struct{}{}
+			go func(addr string, batch []string) {
+				defer wg.Done()
+				defer func() { <-sem }()
+
+				conn, err := s.dialPod(addr)
+				if err != nil {
+					mu.Lock()
+					errs = append(errs, err)
+					mu.Unlock()
+					return
+				}
+				defer conn.Close()
+
+				for _, cube := range batch {
+					pos, err := getCubePosition(conn, cube)
+					mu.Lock()
+					if err != nil {
+						errs = append(errs, err)
+					} else {
+						positions[cube] = pos
+					}
+					mu.Unlock()
+				}
+			}(addr, podCubes[start:end])
+		}
+	}
+	wg.Wait()
+
+	if len(errs) > 0 {
+		return positions, fmt.Errorf("[GetCubesWithTransforms] %d of %d cubes failed, first: %v", len(errs), len(cubes), errs[0])
+	}
+	return positions, nil
+}
+
+// transformCacheTTL is how long CubesNear reuses fetched cube positions before fetching them again.
+const transformCacheTTL = 2 * time.Second
+
+// CubesNear returns the names of all known cubes within radius of pos, sorted by name.
+// Cube positions are fetched with GetCubesWithTransforms and cached for transformCacheTTL,
+// so repeated queries in quick succession do not refetch every transform.
+func (s *SparseScanner) CubesNear(pos []float64, radius float64) ([]string, error) {
+	if err := validatePositions([][]float64{pos}); err != nil {
+		return nil, fmt.Errorf("[CubesNear] %v", err)
+	}
+	if radius < 0 {
+		return nil, fmt.Errorf("[CubesNear] radius must not be negative, got %v", radius)
+	}
+
+	s.transformCacheMu.Lock()
+	defer s.transformCacheMu.Unlock()
+	if s.transformCache == nil || time.Since(s.transformCacheAt) > transformCacheTTL {
+		positions, err := s.GetCubesWithTransforms("")
+		if err != nil {
+			return nil, fmt.Errorf("[CubesNear] %v", err)
+		}
+		s.transformCache = positions
+		s.transformCacheAt = time.Now()
+	}
+
+	var near []string
+	for cube, p := range s.transformCache {
+		dx := p[0] - pos[0]
+		dy := p[1] - pos[1]
+		dz := p[2] - pos[2]
+		if dx*dx+dy*dy+dz*dz <= radius*radius {
+			near = append(near, cube)
+		}
+	}
+	sort.Strings(near)
+	return near, nil
+}
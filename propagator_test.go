@@ -0,0 +1,711 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newTestScanner returns a quiet scanner for 127.0.0.1 that scans exactly the given ports.
+func newTestScanner(ports ...int) *SparseScanner {
+	s := NewSparseScanner([]string{"127.0.0.1"}, 0)
+	s.Ports = ports
+	s.TimeoutSec = 2
+	s.DialAttempts = 1
+	s.Logger = NopLogger{}
+	return s
+}
+
+// mockPodPorts starts n mock pods with the same reply and returns their ports.
+func mockPodPorts(t *testing.T, n int, reply func(string) string) []int {
+	t.Helper()
+	ports := make([]int, n)
+	for i := range ports {
+		_, ports[i] = newMockServer(t, reply).HostPort(t)
+	}
+	return ports
+}
+
+func TestScanAllPodsRespectsMaxConcurrency(t *testing.T) {
+	var mu sync.Mutex
+	inFlight, peak := 0, 0
+	scan := podReply([]string{"cube_BASE"})
+	reply := func(msg string) string {
+		if messageType(msg) != "get_cube_list" {
+			return scan(msg)
+		}
+		mu.Lock()
+		inFlight++
+		if inFlight > peak {
+			peak = inFlight
+		}
+		mu.Unlock()
+		time.Sleep(20 * time.Millisecond)
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+		return scan(msg)
+	}
+
+	s := newTestScanner(mockPodPorts(t, 6, reply)...)
+	s.MaxConcurrency = 2
+	s.ScanAllPods()
+
+	if len(s.Results) != 6 {
+		t.Fatalf("got %d results, want 6", len(s.Results))
+	}
+	for _, res := range s.Results {
+		if !res.Success {
+			t.Errorf("pod %d failed: %s", res.Port, res.Error)
+		}
+	}
+	if peak > 2 {
+		t.Errorf("%d pods were checked at once, want at most 2", peak)
+	}
+	if peak < 2 {
+		t.Errorf("at most %d pod was checked at once, want 2 in parallel", peak)
+	}
+}
+
+// Run with -race: ScanSinglePod and AddPodResult write the maps while readers iterate them.
+func TestScanSinglePodConcurrent(t *testing.T) {
+	portA := mockPodPorts(t, 1, podReply([]string{"a1_BASE", "a2_BASE"}, "alpha"))[0]
+	portB := mockPodPorts(t, 1, podReply([]string{"b1_BASE"}, "beta"))[0]
+	s := newTestScanner()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(3)
+		go func(i int) {
+			defer wg.Done()
+			port := portA
+			if i%2 == 1 {
+				port = portB
+			}
+			if res := s.ScanSinglePod("127.0.0.1", port); !res.Success {
+				t.Errorf("ScanSinglePod(%d): %s", port, res.Error)
+			}
+		}(i)
+		go func() {
+			defer wg.Done()
+			s.AddPodResult(PodResult{Host: "10.0.0.1", Port: 1, Success: true, Cubes: []string{"c_BASE"}})
+		}()
+		go func() {
+			defer wg.Done()
+			s.GetCubesByPrefix("a")
+			s.ExtractPlanetCenters()
+		}()
+	}
+	wg.Wait()
+
+	if got := len(s.GetCubesByPrefix("")); got != 4 {
+		t.Errorf("got %d cubes, want 4", got)
+	}
+	if got := len(s.ExtractPlanetCenters()); got != 2 {
+		t.Errorf("got %d planet centers, want 2", got)
+	}
+}
+
+func TestCheckPodReadsCubeListWithinHostTimeout(t *testing.T) {
+	// The pod authenticates but never answers get_cube_list
+	port := mockPodPorts(t, 1, func(string) string { return "" })[0]
+	s := newTestScanner(port)
+	s.TimeoutSec = 30
+	s.HostTimeouts = map[string]time.Duration{"127.0.0.1": 100 * time.Millisecond}
+
+	start := time.Now()
+	res := s.checkPod("127.0.0.1", port)
+	if res.Success || !strings.Contains(res.Error, "cube list") {
+		t.Fatalf("result = %+v, want a cube list failure", res)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("checkPod took %s, want it bounded by the 100ms host timeout", elapsed)
+	}
+}
+
+func TestCheckPodDialAttempts(t *testing.T) {
+	// Reserve a port, then start the pod on it shortly after the scan begins
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := ln.Addr().(*net.TCPAddr).Port
+	ln.Close()
+
+	s := newTestScanner(port)
+	if res := s.checkPod("127.0.0.1", port); res.Success {
+		t.Fatal("checkPod reached a pod that is not listening")
+	}
+
+	quietPackage(t)
+	DialBaseDelay = 40 * time.Millisecond
+	started := make(chan *mockServer, 1)
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		m, _ := startMockServer(podAddr("127.0.0.1", port), podReply([]string{"late_BASE"}))
+		started <- m
+	}()
+	s.DialAttempts = 4
+	res := s.checkPod("127.0.0.1", port)
+	if m := <-started; m != nil {
+		m.Close()
+	}
+	if !res.Success {
+		t.Fatalf("checkPod with retries: %s", res.Error)
+	}
+}
+
+func TestScannerDialAttemptsDefaultsToPackage(t *testing.T) {
+	s := NewSparseScanner([]string{"127.0.0.1"}, 0)
+	if got := s.dialAttempts(); got != DialAttempts {
+		t.Errorf("dialAttempts() = %d, want the package DialAttempts %d", got, DialAttempts)
+	}
+	s.DialAttempts = 1
+	if got := s.dialAttempts(); got != 1 {
+		t.Errorf("dialAttempts() = %d, want 1 once set", got)
+	}
+}
+
+// readResponsePayload returns a get_cube_list style payload of about size bytes.
+func readResponsePayload(size int) string {
+	var b strings.Builder
+	b.WriteString(`{"cubes":[`)
+	for i := 0; b.Len() < size; i++ {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, `"cube_%d_BASE"`, i)
+	}
+	b.WriteString(`]}`)
+	return b.String()
+}
+
+// serveOnce writes payload and the delimiter to a new pipe and returns the delimited client end.
+func serveOnce(payload string) net.Conn {
+	client, server := net.Pipe()
+	go func() {
+		server.Write([]byte(payload + delimiter))
+		server.Close()
+	}()
+	return withReadTimeout(withDelimiter(client, delimiter), 10*time.Second)
+}
+
+func TestReadStripsDelimiter(t *testing.T) {
+	payload := readResponsePayload(64 << 10)
+	conn := serveOnce(payload)
+	defer conn.Close()
+	if got := read(conn); got != payload {
+		t.Fatalf("read returned %d bytes, want the %d byte payload without the delimiter", len(got), len(payload))
+	}
+}
+
+// readWholeBufferScan is read() as it was before it stopped converting the whole buffer to a
+// string after every chunk, kept as the baseline for BenchmarkRead.
+func readWholeBufferScan(conn net.Conn) string {
+	marker := connDelimiter(conn)
+	var buf bytes.Buffer
+	chunk := make([]byte, 1024)
+	for {
+		n, err := conn.Read(chunk)
+		buf.Write(chunk[:n])
+		if strings.HasSuffix(buf.String(), marker) || err != nil {
+			break
+		}
+	}
+	return strings.TrimSuffix(buf.String(), marker)
+}
+
+func BenchmarkRead(b *testing.B) {
+	sizes := []struct {
+		name     string
+		size     int
+		baseline bool // The quadratic baseline takes seconds per op at 5 MB, so it stops at 1 MB
+	}{
+		{"64KB", 64 << 10, true},
+		{"1MB", 1 << 20, true},
+		{"5MB", 5 << 20, false},
+	}
+	for _, size := range sizes {
+		payload := readResponsePayload(size.size)
+		b.Run("TailCheck/"+size.name, func(b *testing.B) {
+			b.SetBytes(int64(len(payload)))
+			for i := 0; i < b.N; i++ {
+				conn := serveOnce(payload)
+				if got := read(conn); len(got) != len(payload) {
+					b.Fatalf("read %d bytes, want %d", len(got), len(payload))
+				}
+				conn.Close()
+			}
+		})
+		if !size.baseline {
+			continue
+		}
+		b.Run("WholeBufferScan/"+size.name, func(b *testing.B) {
+			b.SetBytes(int64(len(payload)))
+			for i := 0; i < b.N; i++ {
+				conn := serveOnce(payload)
+				if got := readWholeBufferScan(conn); len(got) != len(payload) {
+					b.Fatalf("read %d bytes, want %d", len(got), len(payload))
+				}
+				conn.Close()
+			}
+		})
+	}
+}
+
+// testPlanet builds a planet at (x, y, z) as a pod reports it.
+func testPlanet(name string, x, y, z float64, biome int) Planet {
+	return Planet{Name: name, Position: map[string]float64{"x": x, "y": y, "z": z}, Seed: 7, BiomeType: biome}
+}
+
+func TestSaveAndLoadResultsJSON(t *testing.T) {
+	s := newTestScanner()
+	s.AddPodResult(PodResult{Host: "10.0.0.1", Port: 10002, Success: true, Version: "1.2",
+		Cubes: []string{"a_BASE", "b_BASE"}, Planets: []Planet{testPlanet("alpha", 100, 0, 0, 1)}})
+	s.AddPodResult(PodResult{Host: "10.0.0.2", Port: 10005, Success: false, Error: "Failed to connect"})
+	path := filepath.Join(t.TempDir(), "results.json")
+	if err := s.SaveResultsJSON(path); err != nil {
+		t.Fatalf("SaveResultsJSON: %v", err)
+	}
+
+	loaded := newTestScanner()
+	if err := loaded.LoadResultsJSON(path); err != nil {
+		t.Fatalf("LoadResultsJSON: %v", err)
+	}
+	if !reflect.DeepEqual(loaded.Results, s.Results) {
+		t.Errorf("results differ after reload:\n got %+v\nwant %+v", loaded.Results, s.Results)
+	}
+	if got := loaded.ExtractPlanetCenters(); !reflect.DeepEqual(got, [][]float64{{100, 0, 0}}) {
+		t.Errorf("planet centers = %v, want [[100 0 0]]", got)
+	}
+	if addr, err := loaded.cubeAddr("b_BASE"); err != nil || addr != "10.0.0.1:10002" {
+		t.Errorf("cubeAddr(b_BASE) = %q, %v; want 10.0.0.1:10002", addr, err)
+	}
+	if loaded.CubesMap["a_BASE"] != "10.0.0.1" {
+		t.Errorf("CubesMap[a_BASE] = %q, want 10.0.0.1", loaded.CubesMap["a_BASE"])
+	}
+}
+
+func TestLoadResultsJSONRejectsBadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "results.json")
+	os.WriteFile(path, []byte("{not json"), 0644)
+	if err := newTestScanner().LoadResultsJSON(path); err == nil {
+		t.Error("LoadResultsJSON accepted a malformed file")
+	}
+}
+
+func TestPlanetMergeEpsilonDedupesByCoordinates(t *testing.T) {
+	// Two pods see the same planet under different names, a third sees another planet
+	podA := mockPodPorts(t, 1, func(msg string) string {
+		if messageType(msg) == "get_planets" {
+			return `{"planets":[{"Name":"Gaia","Position":{"x":100,"y":50,"z":-20},"BiomeType":1}]}`
+		}
+		return podReply(nil)(msg)
+	})[0]
+	podB := mockPodPorts(t, 1, func(msg string) string {
+		if messageType(msg) == "get_planets" {
+			return `{"planets":[{"Name":"Gaia-2","Position":{"x":100.004,"y":50,"z":-20.003},"BiomeType":1},` +
+				`{"Name":"Ares","Position":{"x":900,"y":0,"z":0},"BiomeType":2}]}`
+		}
+		return podReply(nil)(msg)
+	})[0]
+
+	s := newTestScanner(podA, podB)
+	s.MaxConcurrency = 1 // Scan podA first so Gaia is the record kept
+	s.PlanetMergeEpsilon = 0.01
+	s.ScanAllPods()
+
+	if len(s.PlanetsMap) != 2 {
+		t.Fatalf("got %d planets, want 2: %v", len(s.PlanetsMap), s.PlanetsMap)
+	}
+	gaia, ok := s.PlanetsMap["Gaia"]
+	if !ok {
+		t.Fatalf("Gaia was not kept: %v", s.PlanetsMap)
+	}
+	wantHosts := []string{podAddr("127.0.0.1", podA), podAddr("127.0.0.1", podB)}
+	if !reflect.DeepEqual(gaia.Hosts, wantHosts) {
+		t.Errorf("Gaia hosts = %v, want %v", gaia.Hosts, wantHosts)
+	}
+	if hosts := s.PlanetsMap["Ares"].Hosts; len(hosts) != 1 {
+		t.Errorf("Ares hosts = %v, want only the second pod", hosts)
+	}
+
+	// Without an epsilon the names stay apart
+	s = newTestScanner(podA, podB)
+	s.ScanAllPods()
+	if len(s.PlanetsMap) != 3 {
+		t.Errorf("got %d planets without merging, want 3", len(s.PlanetsMap))
+	}
+}
+
+func TestGetPlanetsByBiome(t *testing.T) {
+	s := newTestScanner()
+	s.AddPodResult(PodResult{Host: "10.0.0.1", Port: 10002, Success: true, Planets: []Planet{
+		testPlanet("zeta", 1, 0, 0, 3),
+		testPlanet("beta", 2, 0, 0, 1),
+		testPlanet("alpha", 3, 0, 0, 3),
+	}})
+	s.AddPodResult(PodResult{Host: "10.0.0.2", Port: 10002, Success: true, Planets: []Planet{
+		testPlanet("mu", 4, 0, 0, 3),
+		testPlanet("nu", 5, 0, 0, 2),
+	}})
+
+	var names []string
+	for _, planet := range s.GetPlanetsByBiome(3) {
+		if planet.BiomeType != 3 {
+			t.Errorf("planet %s has biome %d, want 3", planet.Name, planet.BiomeType)
+		}
+		names = append(names, planet.Name)
+	}
+	if want := []string{"alpha", "mu", "zeta"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("biome 3 planets = %v, want %v", names, want)
+	}
+	if planets := s.GetPlanetsByBiome(9); len(planets) != 0 {
+		t.Errorf("biome 9 planets = %v, want none", planets)
+	}
+}
+
+// reservePort returns a loopback port with nothing listening on it.
+func reservePort(t *testing.T) int {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	return ln.Addr().(*net.TCPAddr).Port
+}
+
+func TestRescanFailuresRecoversPod(t *testing.T) {
+	up := mockPodPorts(t, 1, podReply([]string{"up_BASE"}))[0]
+	booting := reservePort(t)
+	s := newTestScanner(up, booting)
+	s.MaxConcurrency = 1
+	s.ScanAllPods()
+	if s.Results[1].Success {
+		t.Fatal("a pod that is not listening was scanned successfully")
+	}
+
+	newMockServerAt(t, podAddr("127.0.0.1", booting), podReply([]string{"late_BASE"}, "late-planet"))
+	if recovered := s.RescanFailures(); recovered != 1 {
+		t.Fatalf("RescanFailures recovered %d pods, want 1", recovered)
+	}
+	if len(s.Results) != 2 {
+		t.Fatalf("got %d results, want the 2 original entries", len(s.Results))
+	}
+	if res := s.Results[1]; !res.Success || res.Port != booting {
+		t.Errorf("Results[1] = %+v, want the recovered pod in place", res)
+	}
+	if addr, err := s.cubeAddr("late_BASE"); err != nil || addr != podAddr("127.0.0.1", booting) {
+		t.Errorf("cubeAddr(late_BASE) = %q, %v", addr, err)
+	}
+	if _, ok := s.PlanetsMap["late-planet"]; !ok {
+		t.Error("the recovered pod's planet was not recorded")
+	}
+	if recovered := s.RescanFailures(); recovered != 0 {
+		t.Errorf("second RescanFailures recovered %d pods, want 0", recovered)
+	}
+}
+
+// Run with -race: Rescan replaces Results while RescanFailures is writing into it.
+func TestRescanFailuresDuringRescan(t *testing.T) {
+	up := mockPodPorts(t, 1, podReply([]string{"up_BASE"}))[0]
+	down := []int{reservePort(t), reservePort(t), reservePort(t)}
+	s := newTestScanner(append([]int{up}, down...)...)
+	s.ScanAllPods()
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		s.RescanFailures()
+	}()
+	go func() {
+		defer wg.Done()
+		s.Rescan()
+	}()
+	wg.Wait()
+
+	s.resultsMu.Lock()
+	defer s.resultsMu.Unlock()
+	seen := make(map[int]bool)
+	for _, res := range s.Results {
+		if seen[res.Port] {
+			t.Errorf("pod %d is listed twice", res.Port)
+		}
+		seen[res.Port] = true
+		if res.Success != (res.Port == up) {
+			t.Errorf("pod %d success = %v", res.Port, res.Success)
+		}
+	}
+	if len(s.Results) != 4 {
+		t.Errorf("got %d results, want 4", len(s.Results))
+	}
+}
+
+func TestExpandHostsCIDR(t *testing.T) {
+	s := newTestScanner()
+	s.Hosts = []string{"10.0.0.0/30", "10.0.0.2", "192.168.1.7"}
+	if err := s.ExpandHosts(); err != nil {
+		t.Fatalf("ExpandHosts: %v", err)
+	}
+	if want := []string{"10.0.0.1", "10.0.0.2", "192.168.1.7"}; !reflect.DeepEqual(s.Hosts, want) {
+		t.Errorf("Hosts = %v, want %v", s.Hosts, want)
+	}
+}
+
+func TestExpandHostsRejectsBadRanges(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		host       string
+		allowLarge bool
+		want       string
+	}{
+		{"malformed", "10.0.0.0/33", false, "invalid CIDR"},
+		{"not an address", "pods/24", false, "invalid CIDR"},
+		{"large range", "10.0.0.0/8", false, "AllowLargeRanges"},
+		{"IPv6 prefix", "fd00::/64", true, "split it into smaller blocks"},
+	} {
+		s := newTestScanner()
+		s.Hosts = []string{"127.0.0.1", tc.host}
+		s.AllowLargeRanges = tc.allowLarge
+		err := s.ExpandHosts()
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: err = %v, want it to mention %q", tc.name, err, tc.want)
+		}
+		if len(s.Hosts) != 2 {
+			t.Errorf("%s: Hosts = %v, want it left unchanged", tc.name, s.Hosts)
+		}
+	}
+
+	// The override lifts the IPv4 /16 limit
+	s := newTestScanner()
+	s.Hosts = []string{"10.0.0.0/15"}
+	s.AllowLargeRanges = true
+	if err := s.ExpandHosts(); err != nil || len(s.Hosts) != 1<<17-2 {
+		t.Errorf("ExpandHosts with AllowLargeRanges = %d hosts, %v", len(s.Hosts), err)
+	}
+}
+
+func TestHostTimeout(t *testing.T) {
+	s := newTestScanner()
+	s.TimeoutSec = 5
+	s.HostTimeouts = map[string]time.Duration{"10.0.0.9": 30 * time.Second, "10.0.0.8": 0}
+	for host, want := range map[string]time.Duration{
+		"10.0.0.9": 30 * time.Second,
+		"10.0.0.8": 5 * time.Second, // A zero override falls back to TimeoutSec
+		"10.0.0.1": 5 * time.Second,
+	} {
+		if got := s.hostTimeout(host); got != want {
+			t.Errorf("hostTimeout(%s) = %s, want %s", host, got, want)
+		}
+	}
+}
+
+func TestCheckPodHonoursLongerHostTimeout(t *testing.T) {
+	// The pod takes longer than TimeoutSec to list its cubes
+	list := podReply([]string{"slow_BASE"})
+	port := mockPodPorts(t, 1, func(msg string) string {
+		if messageType(msg) == "get_cube_list" {
+			time.Sleep(1500 * time.Millisecond)
+		}
+		return list(msg)
+	})[0]
+	s := newTestScanner(port)
+	s.TimeoutSec = 1
+
+	if res := s.checkPod("127.0.0.1", port); res.Success {
+		t.Fatal("checkPod outlasted TimeoutSec without a host override")
+	}
+	s.HostTimeouts = map[string]time.Duration{"127.0.0.1": 5 * time.Second}
+	if res := s.checkPod("127.0.0.1", port); !res.Success || len(res.Cubes) != 1 {
+		t.Fatalf("checkPod with a 5s host timeout = %+v, want the slow pod's cube", res)
+	}
+}
+
+func TestDespawnByPrefixAcrossHosts(t *testing.T) {
+	quietPackage(t)
+	first := newMockServer(t, podReply([]string{"unitA_x_BASE", "unitB_y_BASE"}))
+	_, port := first.HostPort(t)
+	second, err := startMockServer(podAddr("127.0.0.2", port), podReply([]string{"unitA_z_BASE"}))
+	if err != nil {
+		t.Skipf("cannot listen on a second loopback address: %v", err)
+	}
+	t.Cleanup(second.Close)
+
+	s := newTestScanner(port)
+	s.Hosts = []string{"127.0.0.1", "127.0.0.2"}
+	s.ScanAllPods()
+	if len(s.CubesMap) != 3 {
+		t.Fatalf("scanned cubes %v, want 3", s.CubesMap)
+	}
+
+	if err := s.DespawnByPrefix("unitA_", "", ""); err != nil {
+		t.Fatalf("DespawnByPrefix: %v", err)
+	}
+	for m, want := range map[*mockServer]string{first: "unitA_x_BASE", second: "unitA_z_BASE"} {
+		if got := waitForMessages(t, m, "despawn_cube", 1); len(got) != 1 || got[0]["cube_name"] != want {
+			t.Errorf("%s received despawns %v, want only %s", m.Addr(), got, want)
+		}
+	}
+	if _, ok := s.CubesMap["unitB_y_BASE"]; !ok || len(s.CubesMap) != 1 {
+		t.Errorf("CubesMap = %v, want only unitB_y_BASE left", s.CubesMap)
+	}
+	if err := s.DespawnByPrefix("unitA_", "", ""); err == nil {
+		t.Error("a second DespawnByPrefix found cubes that were already despawned")
+	}
+}
+
+// jointsPod serves a prefix of n cubes at serverAddr, each with one joint, and returns the
+// scanner holding them and the pod.
+func jointsPod(tb testing.TB, n int) (*SparseScanner, *mockServer) {
+	tb.Helper()
+	cubes := make([]string, n)
+	for i := range cubes {
+		cubes[i] = fmt.Sprintf("bench_c%02d_BASE", i)
+	}
+	mock := newMockServerAt(tb, serverAddr, func(msg string) string {
+		if m := decodeMessage(msg); m["type"] == "get_joints_for_cube" {
+			return fmt.Sprintf(`{"type":"joints_for_cube","cube_name":%q,"joints":["joint_%s"]}`, m["cube_name"], m["cube_name"])
+		}
+		return replySuccess(msg)
+	})
+	host, port := mock.HostPort(tb)
+	s := newTestScanner(port)
+	s.AddPodResult(PodResult{Host: host, Port: port, Success: true, Cubes: cubes})
+	return s, mock
+}
+
+// jointQueryModes are the ways GetCubesAndConnections(Parallel) can reach the server.
+var jointQueryModes = []struct {
+	name  string
+	setup func(tb testing.TB, s *SparseScanner)
+}{
+	{"DialPerCube", func(testing.TB, *SparseScanner) {}},
+	{"Session", func(tb testing.TB, s *SparseScanner) {
+		session, err := NewSession(serverAddr, authPass, delimiter)
+		if err != nil {
+			tb.Fatalf("NewSession: %v", err)
+		}
+		tb.Cleanup(func() { session.Close() })
+		s.Session = session
+	}},
+	{"Pool", func(tb testing.TB, s *SparseScanner) {
+		s.Pool = NewPool(authPass, delimiter, 4)
+		tb.Cleanup(func() { s.Pool.Close() })
+	}},
+}
+
+func TestGetCubesAndConnectionsReusesConnections(t *testing.T) {
+	quietPackage(t)
+	wantDials := map[string]int{"DialPerCube": 50, "Session": 1, "Pool": 4}
+	for _, mode := range jointQueryModes {
+		s, mock := jointsPod(t, 50)
+		dialsBefore := mock.Dials()
+		mode.setup(t, s)
+		conns, err := s.GetCubesAndConnectionsParallel("bench_")
+		if err != nil {
+			t.Fatalf("%s: %v", mode.name, err)
+		}
+		if len(conns) != 50 {
+			t.Errorf("%s: got %d cubes, want 50", mode.name, len(conns))
+		}
+		for _, c := range conns {
+			if len(c.Joints) != 1 || c.Joints[0].JointName != "joint_"+c.CubeName {
+				t.Errorf("%s: cube %s joints = %+v", mode.name, c.CubeName, c.Joints)
+			}
+		}
+		if dials := mock.Dials() - dialsBefore; dials > wantDials[mode.name] || (mode.name == "DialPerCube" && dials != 50) {
+			t.Errorf("%s: dialed %d times, want at most %d", mode.name, dials, wantDials[mode.name])
+		}
+		mock.Close()
+	}
+}
+
+// BenchmarkGetCubesAndConnections reports the dials needed to read the joints of a 50-cube prefix.
+func BenchmarkGetCubesAndConnections(b *testing.B) {
+	for _, mode := range jointQueryModes {
+		b.Run(mode.name, func(b *testing.B) {
+			quietPackage(b)
+			s, mock := jointsPod(b, 50)
+			mode.setup(b, s)
+			dialsBefore := mock.Dials()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := s.GetCubesAndConnectionsParallel("bench_"); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(mock.Dials()-dialsBefore)/float64(b.N), "dials/op")
+		})
+	}
+}
+
+func TestWriteResultsJSONLStreamsTwoPodScan(t *testing.T) {
+	fast := mockPodPorts(t, 1, podReply([]string{"fast_BASE"}))[0]
+	// The slow pod answers only once the fast pod's result has been delivered
+	fastDelivered := make(chan struct{})
+	streamedEarly := true
+	slowList := podReply([]string{"slow_BASE"}, "slow-planet")
+	slow := mockPodPorts(t, 1, func(msg string) string {
+		if messageType(msg) == "get_cube_list" {
+			select {
+			case <-fastDelivered:
+			case <-time.After(2 * time.Second):
+				streamedEarly = false
+			}
+		}
+		return slowList(msg)
+	})[0]
+
+	s := newTestScanner(fast, slow)
+	var out bytes.Buffer
+	if err := s.WriteResultsJSONL(&out); err != nil {
+		t.Fatal(err)
+	}
+	var delivered []PodResult
+	s.OnPodResult = func(res PodResult) {
+		delivered = append(delivered, res)
+		if res.Port == fast {
+			close(fastDelivered)
+		}
+	}
+	s.ScanAllPods()
+
+	if !streamedEarly {
+		t.Error("the fast pod's result was not delivered until the slow pod finished")
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("wrote %d lines, want 2:\n%s", len(lines), out.String())
+	}
+	for i, line := range lines {
+		var res PodResult
+		if err := json.Unmarshal([]byte(line), &res); err != nil {
+			t.Fatalf("line %d is not a JSON object: %v", i+1, err)
+		}
+		if !reflect.DeepEqual(res, s.Results[i]) || !reflect.DeepEqual(res, delivered[i]) {
+			t.Errorf("line %d = %+v, want Results[%d] = %+v", i+1, res, i, s.Results[i])
+		}
+	}
+	if s.Results[0].Port != fast || len(s.Results[1].Planets) != 1 {
+		t.Errorf("results = %+v, want the fast pod first and the slow pod's planet", s.Results)
+	}
+
+	// A writer attached after the scan gets the results so far
+	var late bytes.Buffer
+	if err := s.WriteResultsJSONL(&late); err != nil {
+		t.Fatal(err)
+	}
+	if late.String() != out.String() {
+		t.Errorf("late writer got:\n%s\nwant:\n%s", late.String(), out.String())
+	}
+}
@@ -0,0 +1,307 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+)
+
+// ErrSessionClosed is returned when a command is issued on a session that has been closed or shut down.
+var ErrSessionClosed = errors.New("session closed")
+
+// ErrConnectionLost is returned by every command after the session's connection has failed.
+// A command that only times out does not fail the connection. The session cannot recover; open a new one.
+var ErrConnectionLost = errors.New("session connection lost")
+
+// Session is an authenticated connection to a server that can be reused for many commands.
+type Session struct {
+	addr      string
+	authPass  string
+	delimiter string
+	conn      net.Conn
+	pool      *Pool // Set when conn was borrowed from a pool and goes back to it on close
+
+	// TrailingNewline appends "\n" after the delimiter of every command, for servers that read
+	// line-framed messages. Use NewSessionWithNewline so the auth message is framed the same way.
+	TrailingNewline bool
+
+	Logger Logger // Receives diagnostics such as a lost connection (nil uses DefaultLogger)
+
+	// DryRun records and logs commands instead of writing them to the connection, answering each
+	// with a synthesized success response. Read-only queries get one too unless DryRunForwardReads
+	// is set, in which case they still go to the server. See DryRunMessages.
+	DryRun             bool
+	DryRunForwardReads bool
+	dryRun             DryRunRecorder
+	dryConn            net.Conn // Lazily created dry-run connection, guarded by mu
+
+	mu       sync.Mutex // Serializes request/response pairs on the connection
+	stateMu  sync.Mutex // Guards closed, released, and inflight registration
+	closed   bool
+	released bool // conn has been closed or returned to its pool
+	inflight sync.WaitGroup
+	lost     error // First I/O error on the connection, guarded by mu; timeouts do not count
+}
+
+// NewSession dials addr once and authenticates, returning a session ready for commands.
+func NewSession(addr, authPass, delimiter string) (*Session, error) {
+	return newSession(addr, authPass, delimiter, false)
+}
+
+// NewSessionWithNewline is NewSession for servers that expect "\n" after every delimiter,
+// including the one that ends the auth message.
+func NewSessionWithNewline(addr, authPass, delimiter string) (*Session, error) {
+	return newSession(addr, authPass, delimiter, true)
+}
+
+func newSession(addr, authPass, delimiter string, trailingNewline bool) (*Session, error) {
+	rawConn, err := dialTCP(addr)
+	if err != nil {
+		return nil, fmt.Errorf("[Session] Failed to connect to %s: %v", addr, err)
+	}
+	conn := withDelimiter(rawConn, delimiter)
+	auth := authFrame(authPass, delimiter)
+	if trailingNewline {
+		auth = append(auth, '\n')
+	}
+	if _, err := conn.Write(auth); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("[Session] Auth write error to %s: %v", addr, err)
+	}
+	if _, err := readResponse(conn); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("[Session] Failed to read auth response from %s: %v", addr, err)
+	}
+
+	return &Session{
+		addr:      addr,
+		authPass:  authPass,
+		delimiter: delimiter,
+		conn:      conn,
+
+		TrailingNewline: trailingNewline,
+	}, nil
+}
+
+// NewPooledSession borrows a connection to addr from pool instead of dialing one. Close and
+// Shutdown return the connection to the pool, or discard it if it has failed.
+func NewPooledSession(pool *Pool, addr string) (*Session, error) {
+	conn, err := pool.Get(addr)
+	if err != nil {
+		return nil, fmt.Errorf("[Session] %v", err)
+	}
+	return &Session{
+		addr:      addr,
+		authPass:  pool.authPass,
+		delimiter: pool.delimiter,
+		conn:      conn,
+		pool:      pool,
+	}, nil
+}
+
+// releaseConn closes the connection, or hands it back to the pool it was borrowed from. A pooled
+// connection is returned only once no command is using it and it has not failed; discard forces
+// it to be closed instead.
+func (s *Session) releaseConn(discard bool) error {
+	if s.pool == nil {
+		return s.conn.Close()
+	}
+	s.stateMu.Lock()
+	released := s.released
+	s.released = true
+	s.stateMu.Unlock()
+	if released {
+		return nil
+	}
+	if discard {
+		s.pool.Discard(s.addr, s.conn)
+		return nil
+	}
+	s.mu.Lock()
+	lost := s.lost
+	s.mu.Unlock()
+	if lost != nil {
+		s.pool.Discard(s.addr, s.conn)
+	} else {
+		s.pool.Put(s.addr, s.conn)
+	}
+	return nil
+}
+
+// begin registers an in-flight operation, refusing new work once the session is closing.
+func (s *Session) begin() error {
+	s.stateMu.Lock()
+	defer s.stateMu.Unlock()
+	if s.closed {
+		return ErrSessionClosed
+	}
+	s.inflight.Add(1)
+	return nil
+}
+
+// Send writes a command and returns the server's response.
+func (s *Session) Send(msg Message) (string, error) {
+	return s.exchange(msg, "", false)
+}
+
+// exchange writes a command and reads its response. With optional set, the response is read with
+// awaitAck, and a missing one returns "" with no error; key is passed on to awaitAck.
+func (s *Session) exchange(msg Message, key string, optional bool) (string, error) {
+	if err := s.begin(); err != nil {
+		return "", err
+	}
+	defer s.inflight.Done()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.lost != nil {
+		return "", fmt.Errorf("%w: %v", ErrConnectionLost, s.lost)
+	}
+
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return "", err
+	}
+	data = append(data, []byte(s.delimiter)...)
+	if s.TrailingNewline {
+		data = append(data, '\n')
+	}
+	if s.DryRun && !(s.DryRunForwardReads && readOnlyCommands[fmt.Sprint(msg["type"])]) {
+		if s.dryConn == nil {
+			s.dryConn = newDryRunConn(s.delimiter, &s.dryRun, loggerOrDefault(s.Logger), true, nil)
+		}
+		s.dryConn.Write(data)
+		return readResponse(s.dryConn)
+	}
+	if _, err := s.conn.Write(data); err != nil {
+		s.lost = fmt.Errorf("failed to send %v to %s: %v", msg["type"], s.addr, err)
+		loggerOrDefault(s.Logger).Errorf("[Session] Connection lost: %v", s.lost)
+		return "", fmt.Errorf("%w: %v", ErrConnectionLost, s.lost)
+	}
+	var resp string
+	if optional {
+		resp, err = awaitAck(s.conn, msg, key)
+	} else {
+		resp, err = readResponse(s.conn)
+	}
+	if err != nil {
+		if isTimeout(err) {
+			// The server may still answer, so skip that reply when it arrives
+			expectLateReply(s.conn, msg, "")
+			return "", fmt.Errorf("[Session] No %v response from %s: %v", msg["type"], s.addr, err)
+		}
+		s.lost = fmt.Errorf("failed to read %v response from %s: %v", msg["type"], s.addr, err)
+		loggerOrDefault(s.Logger).Errorf("[Session] Connection lost: %v", s.lost)
+		return "", fmt.Errorf("%w: %v", ErrConnectionLost, s.lost)
+	}
+	return resp, nil
+}
+
+// DryRunMessages returns the commands the session would have sent while DryRun was set.
+func (s *Session) DryRunMessages() []string {
+	return s.dryRun.Messages()
+}
+
+// GetCubeList returns the names of every cube currently on the session's server.
+func (s *Session) GetCubeList() ([]string, error) {
+	raw, err := s.Send(Message{"type": "get_cube_list"})
+	if err != nil {
+		return nil, err
+	}
+	return parseCubeList(raw)
+}
+
+// GetJointsForCube returns the names of the joints attached to a cube on the session's server.
+func (s *Session) GetJointsForCube(cubeName string) ([]string, error) {
+	raw, err := s.Send(Message{"type": "get_joints_for_cube", "cube_name": cubeName})
+	if err != nil {
+		return nil, err
+	}
+	if err := responseError(raw); err != nil {
+		return nil, fmt.Errorf("[Session] Joints of cube %s: %v", cubeName, err)
+	}
+	joints, err := parseJointsForCube(raw)
+	if err != nil {
+		return nil, fmt.Errorf("[Session] Joints of cube %s: %v", cubeName, err)
+	}
+	return joints, nil
+}
+
+// SpawnCube spawns a cube over the session and records the server-assigned cube ID. Like
+// spawnCube, it does not require the server to acknowledge the spawn.
+func (s *Session) SpawnCube(cube Cube) error {
+	if err := validatePositions([][]float64{cube.Position}); err != nil {
+		return fmt.Errorf("[Session] Invalid position for cube %s: %v", cube.Name, err)
+	}
+	if err := validateRotation(cube.Rotation); err != nil {
+		return fmt.Errorf("[Session] Invalid rotation for cube %s: %v", cube.Name, err)
+	}
+	resp, err := s.exchange(newSpawnMessage(cube), "cube_id", true)
+	if err != nil {
+		return err
+	}
+	if err := responseError(resp); err != nil {
+		return fmt.Errorf("[Session] Failed to spawn cube %s: %v", cube.Name, err)
+	}
+	recordSpawnResponse(cube.Name, resp)
+
+	cubeListMutex.Lock()
+	globalCubeList = append(globalCubeList, resolveCubeID(cube.Name))
+	cubeListMutex.Unlock()
+	return nil
+}
+
+// SetJointParam sets a single joint parameter over the session.
+func (s *Session) SetJointParam(jointName, paramName string, value float64) error {
+	return s.SetJointParams(jointName, map[string]float64{paramName: value})
+}
+
+// SetJointParams sets several parameters of one joint in a single command over the session.
+func (s *Session) SetJointParams(jointName string, params map[string]float64) error {
+	resp, err := s.Send(Message{
+		"type":       "set_joint_params",
+		"joint_name": jointName,
+		"params":     params,
+	})
+	if err != nil {
+		return err
+	}
+	if err := responseError(resp); err != nil {
+		return fmt.Errorf("[Session] Joint %s: %v", jointName, err)
+	}
+	return nil
+}
+
+// Close closes the connection immediately without waiting for in-flight commands. A pooled
+// session instead waits for the current command and returns its connection to the pool.
+func (s *Session) Close() error {
+	s.stateMu.Lock()
+	s.closed = true
+	s.stateMu.Unlock()
+	return s.releaseConn(false)
+}
+
+// Shutdown stops accepting new commands, waits for in-flight ones to finish, and then closes
+// the connection. If ctx expires first, the connection is closed anyway and ctx.Err() is returned.
+func (s *Session) Shutdown(ctx context.Context) error {
+	s.stateMu.Lock()
+	s.closed = true
+	s.stateMu.Unlock()
+
+	drained := make(chan struct{})
+	go func() {
+		s.inflight.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+		return s.releaseConn(false)
+	case <-ctx.Done():
+		s.releaseConn(true)
+		return ctx.Err()
+	}
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"net"
 	"sync"
 	"time"
 )
@@ -45,8 +46,8 @@ func spawnConstructsAroundSphere(gen int, role string, domain string, planetCent
 	for planetIdx, center := range planetCenters {
 		fmt.Printf("🪐 Setting up Planet %d at (%.2f, %.2f, %.2f)\n", planetIdx+1, center[0], center[1], center[2])
 
-		// Generate evenly distributed points using Fibonacci sphere
-		positions := fibonacciSphere(constructsPerPlanet, radius, center)
+		// Generate evenly distributed points using the offset Fibonacci sphere, so none sits on a pole
+		positions := fibonacciSphereOffset(constructsPerPlanet, radius, center, 0.5)
 
 		for i, pos := range positions {
 			wg.Add(1)
@@ -54,7 +55,7 @@ func spawnConstructsAroundSphere(gen int, role string, domain string, planetCent
 				defer wg.Done()
 				ver := (planetIdx * constructsPerPlanet) + i + 1 // Unique version ID
 				unitName := generateUnitID(role, domain, gen, ver)
-				allUnits = appendUnitSafely(allUnits, unitName)
+				appendUnitSafely(&allUnits, unitName)
 				fmt.Printf("\n🚀 Spawning unit: %s at position (%.2f, %.2f, %.2f)\n", unitName, position[0], position[1], position[2])
 				buildDynamicConstruct(unitName, position, radius, 0) // Angle is unused with Fibonacci sphere
 				targetedUnfreezeAllCubes(unitName)                   // Unfreeze right after spawning
@@ -88,13 +89,17 @@ func buildDynamicConstruct(unitName string, center []float64, radius float64, an
 		{Name: unitName + "_right_foot", Position: []float64{center[0] + 0.6, center[1] + 0.0, center[2]}},
 	}
 
-	// Spawn all cubes
-	var wg sync.WaitGroup
-	wg.Add(len(cubes))
-	for _, cube := range cubes {
-		go spawnCube(cube, &wg)
+	// Spawn all cubes in one batch
+	conn, _, err := dialAndAuth(serverAddr, authPass, delimiter)
+	if err != nil {
+		fmt.Printf("❌ Error spawning %s: %v\n", unitName, err)
+		return
+	}
+	err = SpawnCubesBatch(conn, cubes)
+	conn.Close()
+	if err != nil {
+		fmt.Println(err)
 	}
-	wg.Wait()
 	fmt.Printf("✅ Construct %s spawned\n", unitName)
 
 	// Define joint stiffness
@@ -108,13 +113,165 @@ func buildDynamicConstruct(unitName string, center []float64, radius float64, an
 
 	// Link the joints
 	chains := [][]string{
-		{unitName + "_head_BASE", unitName + "_body_BASE"},
-		{unitName + "_body_BASE", unitName + "_left_arm_BASE", unitName + "_left_leg_BASE", unitName + "_left_foot_BASE"},
-		{unitName + "_body_BASE", unitName + "_right_arm_BASE", unitName + "_right_leg_BASE", unitName + "_right_foot_BASE"},
+		{unitName + "_head", unitName + "_body"},
+		{unitName + "_body", unitName + "_left_arm", unitName + "_left_leg", unitName + "_left_foot"},
+		{unitName + "_body", unitName + "_right_arm", unitName + "_right_leg", unitName + "_right_foot"},
 	}
-	if err := linkCubeChains(chains, "hinge", jointParams); err != nil {
+	if _, err := linkCubeChains(resolveChains(chains), "hinge", jointParams); err != nil {
 		fmt.Printf("❌ Error linking cubes for %s: %v\n", unitName, err)
 		return
 	}
 	fmt.Printf("🔗 Construct %s linked\n", unitName)
 }
+
+// MeasureSpawnThroughput spawns count dummy cubes on addr over a single connection authenticated
+// with pass and delim, times how long the server takes to accept them, despawns them again, and
+// returns the spawn rate in cubes per second. Each spawn waits for the server's acknowledgement,
+// so the rate reflects full round trips.
+func MeasureSpawnThroughput(addr, pass, delim string, count int) (float64, error) {
+	if count <= 0 {
+		return 0, fmt.Errorf("[Throughput] count must be positive, got %d", count)
+	}
+
+	conn, _, err := dialAndAuth(addr, pass, delim)
+	if err != nil {
+		return 0, fmt.Errorf("[Throughput] %v", err)
+	}
+	defer conn.Close()
+
+	prefix := fmt.Sprintf("throughput_%d", time.Now().UnixNano())
+	names := make([]string, 0, count)
+	defer func() { forgetCubeIDs(names) }()
+
+	startTime := time.Now()
+	for i := 0; i < count; i++ {
+		cube := Cube{
+			Name:     fmt.Sprintf("%s_%d", prefix, i),
+			Position: []float64{float64(i%100) * 2, 500, float64(i/100) * 2}, // Spread out high above the origin
+		}
+		if err := sendJSONMessage(conn, newSpawnMessage(cube)); err != nil {
+			return 0, fmt.Errorf("[Throughput] Failed to spawn cube %d on %s: %v", i, addr, err)
+		}
+		resp, err := readResponse(conn)
+		if err != nil {
+			return 0, fmt.Errorf("[Throughput] Failed to read spawn response for cube %d on %s: %v", i, addr, err)
+		}
+		recordSpawnResponse(cube.Name, resp)
+		names = append(names, cube.Name)
+	}
+	elapsed := time.Since(startTime)
+
+	for _, name := range names {
+		id := resolveCubeID(name)
+		if err := sendJSONMessage(conn, Message{"type": "despawn_cube", "cube_name": id}); err != nil {
+			DefaultLogger.Warnf("[Throughput] Failed to despawn cube %s on %s: %v", id, addr, err)
+		}
+	}
+
+	rate := float64(count) / elapsed.Seconds()
+	DefaultLogger.Infof("⏱️ Spawned %d cubes on %s in %s (%.1f cubes/sec)", count, addr, elapsed, rate)
+	return rate, nil
+}
+
+// SpawnHandle records the cubes created by SpawnCubesOnly and the server they live on,
+// so they can be linked later with LinkHandle.
+type SpawnHandle struct {
+	Addr  string   // Server the cubes were spawned on
+	Cubes []string // Client names of the cubes that were spawned successfully
+
+	authPass  string // Password LinkHandle authenticates with on Addr
+	delimiter string // Message delimiter used on Addr
+}
+
+// SpawnCubesOnly spawns cubes over a single connection to the default server without linking
+// them, returning a handle for a later LinkHandle call. If some cubes fail, the handle still lists
+// those that succeeded.
+func SpawnCubesOnly(cubes []Cube) (SpawnHandle, error) {
+	handle := SpawnHandle{Addr: serverAddr, authPass: authPass, delimiter: delimiter}
+	return spawnCubesOnly(handle, cubes, DefaultLogger, func() (net.Conn, string, error) {
+		return dialAndAuth(serverAddr, authPass, delimiter)
+	})
+}
+
+// SpawnCubesOnly spawns cubes on the Construct's own server without linking them. The returned
+// handle records that server, so LinkHandle links the cubes on the same pod.
+func (c *Construct) SpawnCubesOnly(cubes []Cube) (SpawnHandle, error) {
+	handle := SpawnHandle{Addr: c.constructServerAddr, authPass: c.constructAuthPass, delimiter: c.constructDelimiter}
+	return spawnCubesOnly(handle, cubes, c.log(), c.connect)
+}
+
+// spawnCubesOnly spawns cubes over the connection returned by connect, recording the successes in
+// handle and logging the failures to log.
+func spawnCubesOnly(handle SpawnHandle, cubes []Cube, log Logger, connect func() (net.Conn, string, error)) (SpawnHandle, error) {
+	conn, _, err := connect()
+	if err != nil {
+		return handle, fmt.Errorf("[SpawnCubesOnly] %v", err)
+	}
+	defer conn.Close()
+
+	var failed []string
+	for _, cube := range cubes {
+		if err := validatePositions([][]float64{cube.Position}); err != nil {
+			log.Warnf("[SpawnCubesOnly] Invalid position for cube %s: %v", cube.Name, err)
+			failed = append(failed, cube.Name)
+			continue
+		}
+		if err := validateRotation(cube.Rotation); err != nil {
+			log.Warnf("[SpawnCubesOnly] Invalid rotation for cube %s: %v", cube.Name, err)
+			failed = append(failed, cube.Name)
+			continue
+		}
+		spawn := newSpawnMessage(cube)
+		if err := sendJSONMessage(conn, spawn); err != nil {
+			log.Warnf("[SpawnCubesOnly] Failed to spawn cube %s: %v", cube.Name, err)
+			failed = append(failed, cube.Name)
+			continue
+		}
+		if resp, err := awaitAck(conn, spawn, "cube_id"); err == nil && resp != "" {
+			recordSpawnResponse(cube.Name, resp)
+		}
+
+		handle.Cubes = append(handle.Cubes, cube.Name)
+		cubeListMutex.Lock()
+		globalCubeList = append(globalCubeList, resolveCubeID(cube.Name))
+		cubeListMutex.Unlock()
+	}
+
+	if len(failed) > 0 {
+		return handle, fmt.Errorf("[SpawnCubesOnly] %d of %d cubes failed to spawn: %v", len(failed), len(cubes), failed)
+	}
+	return handle, nil
+}
+
+// LinkHandle links cubes previously created by SpawnCubesOnly on the server recorded in the handle.
+// Chains use the client cube names and may only reference cubes recorded in the handle.
+func LinkHandle(h SpawnHandle, chains [][]string, jointType string, jointParams map[string]float64) error {
+	spawned := make(map[string]bool, len(h.Cubes))
+	for _, name := range h.Cubes {
+		spawned[name] = true
+	}
+	for _, chain := range chains {
+		for _, name := range chain {
+			if !spawned[name] {
+				return fmt.Errorf("[LinkHandle] cube %s was not spawned by this handle", name)
+			}
+		}
+	}
+
+	pass, delim := h.authPass, h.delimiter
+	if pass == "" {
+		pass, delim = authPass, delimiter
+	}
+	conn, _, err := dialAndAuth(h.Addr, pass, delim)
+	if err != nil {
+		return fmt.Errorf("[LinkHandle] %v", err)
+	}
+	defer conn.Close()
+
+	report, err := sendLinkChains(conn, resolveChains(chains), jointType, jointParams, LinkBatchSize, DefaultLogger)
+	if err != nil {
+		return fmt.Errorf("[LinkHandle] %v", err)
+	}
+	DefaultLogger.Infof("🔗 Linked handle on %s (%d joints created, %d skipped)", h.Addr, report.Created, report.Skipped)
+	return nil
+}
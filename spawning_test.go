@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestMeasureSpawnThroughput(t *testing.T) {
+	quietPackage(t)
+	mock := newMockServer(t, func(msg string) string {
+		if messageType(msg) == "spawn_cube" {
+			return fmt.Sprintf(`{"status":"success","cube_id":"id_%v"}`, decodeMessage(msg)["cube_name"])
+		}
+		return ""
+	})
+
+	rate, err := MeasureSpawnThroughput(mock.Addr(), authPass, delimiter, 20)
+	if err != nil {
+		t.Fatalf("MeasureSpawnThroughput: %v", err)
+	}
+	if rate <= 0 {
+		t.Fatalf("rate = %v, want a positive rate", rate)
+	}
+	if got := len(mock.MessagesOfType("spawn_cube")); got != 20 {
+		t.Errorf("spawned %d cubes, want 20", got)
+	}
+	for _, msg := range waitForMessages(t, mock, "despawn_cube", 20) {
+		if name := fmt.Sprint(msg["cube_name"]); !strings.HasPrefix(name, "id_throughput_") {
+			t.Errorf("despawned %s, want the server-assigned ID", name)
+		}
+	}
+
+	cubeIDMutex.Lock()
+	defer cubeIDMutex.Unlock()
+	if len(serverCubeIDs) != 0 {
+		t.Errorf("serverCubeIDs = %v, want the despawned cubes forgotten", serverCubeIDs)
+	}
+}
+
+func TestMeasureSpawnThroughputRejectsNonPositiveCount(t *testing.T) {
+	if _, err := MeasureSpawnThroughput("127.0.0.1:1", authPass, delimiter, 0); err == nil || !strings.Contains(err.Error(), "count must be positive") {
+		t.Fatalf("err = %v, want a count error", err)
+	}
+}
+
+func BenchmarkMeasureSpawnThroughput(b *testing.B) {
+	quietPackage(b)
+	mock := newMockServer(b, func(msg string) string {
+		if messageType(msg) == "spawn_cube" {
+			return replySuccess(msg)
+		}
+		return ""
+	})
+
+	var rate float64
+	for i := 0; i < b.N; i++ {
+		var err error
+		if rate, err = MeasureSpawnThroughput(mock.Addr(), authPass, delimiter, 100); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportMetric(rate, "cubes/sec")
+}
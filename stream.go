@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"time"
+)
+
+// CubeState is one pushed update of a cube's transform.
+type CubeState struct {
+	Name     string    `json:"cube_name"`
+	Position []float64 `json:"position"`
+	Rotation []float64 `json:"rotation,omitempty"`
+	Velocity []float64 `json:"velocity,omitempty"`
+}
+
+// SubscribeCubeState asks the server to push the state of cubeNames and delivers each update on
+// the returned channel until ctx is cancelled or the connection fails, then closes the channel.
+//
+// The subscription is sent as {"type": "subscribe_cube_state", "cube_names": [...]}. The server
+// may acknowledge it with any non-error message, then sends one delimiter-framed message per
+// update shaped like {"cube_name": ..., "position": [x, y, z], "rotation": [...], "velocity": [...]}.
+// An error reply to the subscription is returned immediately. The connection is dedicated to the
+// stream: once it ends, an unsubscribe_cube_state message is sent if ctx was cancelled and the
+// connection is closed, since updates may still be in flight on it.
+func SubscribeCubeState(ctx context.Context, conn net.Conn, cubeNames []string) (<-chan CubeState, error) {
+	if len(cubeNames) == 0 {
+		return nil, fmt.Errorf("[SubscribeCubeState] no cubes to subscribe to")
+	}
+	if err := sendJSONMessage(conn, Message{
+		"type":       "subscribe_cube_state",
+		"cube_names": cubeNames,
+	}); err != nil {
+		return nil, fmt.Errorf("[SubscribeCubeState] Failed to subscribe: %v", err)
+	}
+
+	conn = withDelimiter(conn, "") // Keep one buffered reader for the whole stream
+	first, err := readResponse(conn)
+	if err != nil {
+		return nil, fmt.Errorf("[SubscribeCubeState] Failed to read subscription reply: %v", err)
+	}
+	if err := responseError(first); err != nil {
+		return nil, fmt.Errorf("[SubscribeCubeState] Subscription rejected: %v", err)
+	}
+
+	states := make(chan CubeState)
+	go func() {
+		defer close(states)
+		defer conn.Close()
+		// Unblock the pending read when ctx is cancelled
+		stop := context.AfterFunc(ctx, func() { conn.SetReadDeadline(time.Now()) })
+		defer stop()
+
+		frame := first
+		for {
+			if state, ok := parseCubeState(frame); ok {
+				select {
+				case states <- state:
+				case <-ctx.Done():
+				}
+			}
+			if ctx.Err() != nil {
+				break
+			}
+			if frame, err = readFrame(conn, 0); err != nil {
+				if ctx.Err() == nil {
+					DefaultLogger.Warnf("[SubscribeCubeState] Stream ended: %v", err)
+				}
+				break
+			}
+		}
+
+		conn.SetReadDeadline(time.Time{})
+		if ctx.Err() != nil {
+			sendJSONMessage(conn, Message{"type": "unsubscribe_cube_state", "cube_names": cubeNames})
+		}
+	}()
+	return states, nil
+}
+
+// parseCubeState decodes a pushed update, skipping acknowledgements and other messages.
+func parseCubeState(frame string) (CubeState, bool) {
+	var state CubeState
+	if err := json.Unmarshal([]byte(frame), &state); err != nil || state.Name == "" {
+		if err != nil {
+			reportRawResponse(frame)
+		}
+		return state, false
+	}
+	return state, true
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSubscribeCubeState(t *testing.T) {
+	quietPackage(t)
+	// The ack and the first two updates arrive in one write, so the stream must split them
+	mock := newMockServer(t, func(msg string) string {
+		if messageType(msg) != "subscribe_cube_state" {
+			return ""
+		}
+		return strings.Join([]string{
+			`{"type":"subscribed"}`,
+			`{"cube_name":"a_BASE","position":[1,2,3]}`,
+			`{"cube_name":"b_BASE","position":[4,5,6],"velocity":[0,-1,0]}`,
+			`{"cube_name":"a_BASE","position":[1,1,3],"rotation":[0,90,0]}`,
+		}, delimiter)
+	})
+	conn := dialMock(t, mock)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	states, err := SubscribeCubeState(ctx, conn, []string{"a_BASE", "b_BASE"})
+	if err != nil {
+		t.Fatalf("SubscribeCubeState: %v", err)
+	}
+
+	want := []CubeState{
+		{Name: "a_BASE", Position: []float64{1, 2, 3}},
+		{Name: "b_BASE", Position: []float64{4, 5, 6}, Velocity: []float64{0, -1, 0}},
+		{Name: "a_BASE", Position: []float64{1, 1, 3}, Rotation: []float64{0, 90, 0}},
+	}
+	for i, w := range want {
+		select {
+		case got := <-states:
+			if !reflect.DeepEqual(got, w) {
+				t.Errorf("update %d = %+v, want %+v", i, got, w)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timed out waiting for update %d", i)
+		}
+	}
+
+	cancel()
+	select {
+	case _, ok := <-states:
+		if ok {
+			t.Error("received an update after cancelling")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("the channel was not closed after cancelling")
+	}
+	unsub := waitForMessages(t, mock, "unsubscribe_cube_state", 1)
+	if names, _ := unsub[0]["cube_names"].([]interface{}); len(names) != 2 {
+		t.Errorf("unsubscribe message = %v, want both cubes", unsub[0])
+	}
+	if got := mock.MessagesOfType("subscribe_cube_state"); len(got) != 1 {
+		t.Errorf("sent %d subscribe messages, want 1", len(got))
+	}
+}
+
+func TestSubscribeCubeStateRejected(t *testing.T) {
+	quietPackage(t)
+	conn := dialMock(t, newMockServer(t, func(string) string {
+		return `{"type":"error","message":"unknown command"}`
+	}))
+	if _, err := SubscribeCubeState(context.Background(), conn, []string{"a_BASE"}); err == nil || !strings.Contains(err.Error(), "rejected") {
+		t.Errorf("err = %v, want the subscription to be rejected", err)
+	}
+	if _, err := SubscribeCubeState(context.Background(), conn, nil); err == nil {
+		t.Error("subscribed to no cubes")
+	}
+}
+
+func TestSubscribeCubeStateClosesWhenServerDisconnects(t *testing.T) {
+	quietPackage(t)
+	discardStdout(t)
+	mock := newMockServer(t, func(string) string { return `{"cube_name":"a_BASE","position":[0,0,0]}` })
+	conn := dialMock(t, mock)
+	states, err := SubscribeCubeState(context.Background(), conn, []string{"a_BASE"})
+	if err != nil {
+		t.Fatalf("SubscribeCubeState: %v", err)
+	}
+	<-states
+	mock.Close()
+	select {
+	case _, ok := <-states:
+		if ok {
+			t.Error("received an update the server never sent")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("the channel was not closed after the server disconnected")
+	}
+}
@@ -1,7 +1,11 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 
 	paragon "github.com/OpenFluke/PARAGON"
 )
@@ -12,16 +16,78 @@ type ExperimentModel struct {
 	Cubes         int
 	Planets       int
 	ExpectedCubes int
-	Template      Construct
+	Template      *Construct
 	Model         *paragon.Network
 }
 
 var ExperimentModels []ExperimentModel // Public array to store experiment models
 
+// ModelDir is the directory StartEMLst loads each pod's saved model from. Empty disables loading.
+var ModelDir string
+
+// ModelFilename returns a stable file name for the model trained on a pod, e.g.
+// "model_192.168.0.227_10002.json". Characters that are unsafe in file names are replaced.
+func ModelFilename(host string, port int) string {
+	safeHost := strings.Map(func(r rune) rune {
+		switch r {
+		case ':', '/', '\\', '%':
+			return '_'
+		}
+		return r
+	}, host)
+	return fmt.Sprintf("model_%s_%d.json", safeHost, port)
+}
+
+// SaveModel writes model to path as JSON, creating the directory if needed. The whole network is
+// stored, including each connection's source neuron and each neuron's activation, which
+// PARAGON's own SaveToJSON leaves out, so a loaded model computes the same outputs.
+// ADHD performance statistics are not saved.
+func SaveModel(model *paragon.Network, path string) error {
+	if model == nil {
+		return fmt.Errorf("[SaveModel] model is nil")
+	}
+	// ADHD performance stats are per run, and their open-ended bucket holds +Inf, which JSON cannot encode
+	stored := *model
+	stored.Performance = nil
+	data, err := json.Marshal(&stored)
+	if err != nil {
+		return fmt.Errorf("[SaveModel] Failed to encode model: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("[SaveModel] Failed to create directory for %s: %v", path, err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("[SaveModel] Failed to write %s: %v", path, err)
+	}
+	return nil
+}
+
+// LoadModel reads a model written by SaveModel.
+func LoadModel(path string) (*paragon.Network, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("[LoadModel] Failed to read %s: %v", path, err)
+	}
+	model := &paragon.Network{}
+	if err := json.Unmarshal(data, model); err != nil {
+		return nil, fmt.Errorf("[LoadModel] Failed to decode %s: %v", path, err)
+	}
+	if len(model.Layers) == 0 {
+		return nil, fmt.Errorf("[LoadModel] %s holds no network layers", path)
+	}
+	model.Performance = paragon.NewADHDPerformance()
+	return model, nil
+}
+
 // tmpSweep scans the multiverse and returns the total number of detected cubes.
-func QuickScan(quick []string, port int) int {
+// QuickScan scans the given hosts and returns the total number of cubes found. An error is
+// returned when there is nothing to scan, so it is not mistaken for an empty universe.
+func QuickScan(quick []string, port int) (int, error) {
 	scannerTmp := &SparseScanner{}
 	scannerTmp.InitSparseScanner(quick, port) // starting port
+	if err := scannerTmp.Validate(); err != nil {
+		return 0, fmt.Errorf("[QuickScan] %v", err)
+	}
 	scannerTmp.ScanAllPods()
 	scannerTmp.PrintSummary()
 
@@ -32,10 +98,13 @@ func QuickScan(quick []string, port int) int {
 			totalCubes += len(res.Cubes)
 		}
 	}
-	return totalCubes
+	return totalCubes, nil
 }
 
-func StartEMLst(quick []string, port int, aPass string, aDel string) {
+// StartEMLst scans the given hosts and builds an ExperimentModel for every reachable pod. A pod
+// whose construct config fails to load is skipped; the error returned at the end lists every
+// pod that was skipped.
+func StartEMLst(quick []string, port int, aPass string, aDel string) error {
 	scannerTmp := &SparseScanner{}
 	scannerTmp.InitSparseScanner(quick, port) // starting port
 	scannerTmp.ScanAllPods()
@@ -44,20 +113,21 @@ func StartEMLst(quick []string, port int, aPass string, aDel string) {
 	// Load JSON from a file into a string
 	jsonStr, err := LoadJSONFileToString("construct_config.json")
 	if err != nil {
-		fmt.Printf("Error loading JSON file: %v\n", err)
-		return
+		return fmt.Errorf("[StartEMLst] Error loading JSON file: %v", err)
 	}
 	fmt.Printf("JSON string loaded:\n%s\n", jsonStr)
 
+	var failed []string
 	for num, res := range scannerTmp.Results {
 		if res.Success {
-
-			tmp := *NewConstruct(res.Host+":"+string(res.Port), aPass, aDel)
+			addr := podAddr(res.Host, res.Port)
+			tmp := NewConstruct(addr, aPass, aDel)
 
 			// Load the JSON string for validation/storage
 			if err := tmp.LoadJSONToString(jsonStr); err != nil {
-				fmt.Printf("Error loading JSON string: %v\n", err)
-				return
+				fmt.Printf("Error loading JSON string for %s, skipping pod: %v\n", addr, err)
+				failed = append(failed, fmt.Sprintf("%s: %v", addr, err))
+				continue
 			}
 
 			// Generate a unique unitName for this Construct (e.g., "POD_192.168.0.227_10008")
@@ -67,8 +137,9 @@ func StartEMLst(quick []string, port int, aPass string, aDel string) {
 
 			// Load the JSON string and parse it into tmp.Config
 			if err := tmp.LoadConfigFromJSONString(jsonStr, unitName); err != nil {
-				fmt.Printf("Error loading JSON string for %s: %v\n", unitName, err)
-				return
+				fmt.Printf("Error loading JSON string for %s, skipping pod: %v\n", unitName, err)
+				failed = append(failed, fmt.Sprintf("%s: %v", addr, err))
+				continue
 			}
 
 			fmt.Println(unitName + " expecting " + fmt.Sprintf("%d", len(tmp.Config.Cubes)) + " cubes")
@@ -82,6 +153,20 @@ func StartEMLst(quick []string, port int, aPass string, aDel string) {
 				ExpectedCubes: len(tmp.Config.Cubes),
 			}
 
+			// Pick up where the last run left off if this pod has a saved model
+			if ModelDir != "" {
+				modelPath := filepath.Join(ModelDir, ModelFilename(res.Host, res.Port))
+				if FileExists(modelPath) {
+					model, err := LoadModel(modelPath)
+					if err != nil {
+						fmt.Printf("Error loading model for %s: %v\n", unitName, err)
+					} else {
+						expModel.Model = model
+						fmt.Printf("Loaded model for %s from %s\n", unitName, modelPath)
+					}
+				}
+			}
+
 			ExperimentModels = append(ExperimentModels, expModel)
 
 			/*tmp.PrintCubesTable()
@@ -101,4 +186,8 @@ func StartEMLst(quick []string, port int, aPass string, aDel string) {
 		}
 	}
 
+	if len(failed) > 0 {
+		return fmt.Errorf("[StartEMLst] %d pods skipped: %s", len(failed), strings.Join(failed, "; "))
+	}
+	return nil
 }
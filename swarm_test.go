@@ -0,0 +1,209 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	paragon "github.com/OpenFluke/PARAGON"
+)
+
+// inTempDir runs the test in an empty directory holding a copy of construct_config.json, since
+// StartEMLst reads the template and writes its CSV tables in the working directory.
+func inTempDir(t *testing.T) {
+	t.Helper()
+	template, err := os.ReadFile("construct_config.json")
+	if err != nil {
+		t.Fatalf("failed to read template: %v", err)
+	}
+	dir := t.TempDir()
+	t.Chdir(dir)
+	if err := os.WriteFile("construct_config.json", template, 0644); err != nil {
+		t.Fatalf("failed to write template: %v", err)
+	}
+}
+
+// resetExperimentModels clears ExperimentModels for the test and again when it ends.
+func resetExperimentModels(t *testing.T) {
+	ExperimentModels = nil
+	t.Cleanup(func() { ExperimentModels = nil })
+}
+
+func TestPodAddr(t *testing.T) {
+	for _, tc := range []struct {
+		host string
+		port int
+		want string
+	}{
+		{"192.168.0.227", 10008, "192.168.0.227:10008"},
+		{"localhost", 14000, "localhost:14000"},
+		{"::1", 10002, "[::1]:10002"},
+	} {
+		if got := podAddr(tc.host, tc.port); got != tc.want {
+			t.Errorf("podAddr(%q, %d) = %q, want %q", tc.host, tc.port, got, tc.want)
+		}
+	}
+}
+
+func TestStartEMLstUsesPodAddress(t *testing.T) {
+	quietPackage(t)
+	discardStdout(t)
+	inTempDir(t)
+	resetExperimentModels(t)
+	base, _ := newMockPods(t, 1, portStep, podReply([]string{"a_BASE"}, "p1"))
+
+	if err := StartEMLst([]string{"127.0.0.1"}, base, authPass, delimiter); err != nil {
+		t.Fatalf("StartEMLst: %v", err)
+	}
+	if len(ExperimentModels) != 1 {
+		t.Fatalf("got %d experiment models, want 1", len(ExperimentModels))
+	}
+	model := ExperimentModels[0]
+	if want := podAddr("127.0.0.1", base); model.Template.constructServerAddr != want {
+		t.Errorf("constructServerAddr = %q, want %q", model.Template.constructServerAddr, want)
+	}
+	if model.Cubes != 1 || model.Planets != 1 {
+		t.Errorf("model saw %d cubes and %d planets, want 1 and 1", model.Cubes, model.Planets)
+	}
+}
+
+// testModel returns a small network with a different activation on each layer and nonzero biases,
+// so a model that lost any of them on a round trip would compute different outputs.
+func testModel() *paragon.Network {
+	model := paragon.NewNetwork(
+		[]struct{ Width, Height int }{{3, 2}, {5, 1}, {4, 1}, {2, 1}},
+		[]string{"linear", "leaky_relu", "tanh", "sigmoid"},
+		[]bool{true, true, true, true},
+	)
+	for l, layer := range model.Layers {
+		for _, row := range layer.Neurons {
+			for x, n := range row {
+				n.Bias = 0.1 * float64(l+x)
+			}
+		}
+	}
+	return model
+}
+
+func TestSaveAndLoadModel(t *testing.T) {
+	model := testModel()
+	path := filepath.Join(t.TempDir(), "models", ModelFilename("192.168.0.227", 10002))
+	if err := SaveModel(model, path); err != nil {
+		t.Fatalf("SaveModel: %v", err)
+	}
+	loaded, err := LoadModel(path)
+	if err != nil {
+		t.Fatalf("LoadModel: %v", err)
+	}
+
+	obs := []float64{0.5, -1, 2, 0.25, -0.75, 1.5}
+	want, err := RunPolicyStep(model, obs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := RunPolicyStep(loaded, obs)
+	if err != nil {
+		t.Fatalf("forward pass of the loaded model: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("loaded model outputs %v, want %v", got, want)
+	}
+	if loaded.Performance == nil {
+		t.Error("loaded model has no performance tracker")
+	}
+
+	if err := os.WriteFile(path, []byte(`{"Layers":[]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadModel(path); err == nil {
+		t.Error("LoadModel accepted a file with no layers")
+	}
+	if err := SaveModel(nil, path); err == nil {
+		t.Error("SaveModel accepted a nil model")
+	}
+}
+
+func TestModelFilename(t *testing.T) {
+	for host, want := range map[string]string{
+		"192.168.0.227": "model_192.168.0.227_10002.json",
+		"fe80::1%eth0":  "model_fe80__1_eth0_10002.json",
+	} {
+		if got := ModelFilename(host, 10002); got != want {
+			t.Errorf("ModelFilename(%q) = %q, want %q", host, got, want)
+		}
+	}
+}
+
+func TestStartEMLstLoadsSavedModels(t *testing.T) {
+	quietPackage(t)
+	discardStdout(t)
+	inTempDir(t)
+	resetExperimentModels(t)
+	base, _ := newMockPods(t, 2, portStep, podReply([]string{"a_BASE"}))
+	ModelDir = t.TempDir()
+	t.Cleanup(func() { ModelDir = "" })
+	if err := SaveModel(testModel(), filepath.Join(ModelDir, ModelFilename("127.0.0.1", base))); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := StartEMLst([]string{"127.0.0.1"}, base, authPass, delimiter); err != nil {
+		t.Fatalf("StartEMLst: %v", err)
+	}
+	if len(ExperimentModels) != 2 {
+		t.Fatalf("got %d experiment models, want 2", len(ExperimentModels))
+	}
+	for _, m := range ExperimentModels {
+		if hasModel := m.Model != nil; hasModel != (m.Port == base) {
+			t.Errorf("pod %d: model loaded = %v, want it only for the pod with a saved model", m.Port, hasModel)
+		}
+	}
+}
+
+func TestStartEMLstSkipsBadPods(t *testing.T) {
+	quietPackage(t)
+	discardStdout(t)
+	inTempDir(t)
+	resetExperimentModels(t)
+	// Pods 0 and 2 are good; pod 1 and the rest of the scanned range are unreachable
+	base, pods := newMockPods(t, 3, portStep, podReply([]string{"a_BASE"}))
+	pods[1].Close()
+
+	if err := StartEMLst([]string{"127.0.0.1"}, base, authPass, delimiter); err != nil {
+		t.Fatalf("StartEMLst: %v", err)
+	}
+	ports := make(map[int]bool)
+	for _, m := range ExperimentModels {
+		ports[m.Port] = true
+	}
+	if len(ExperimentModels) != 2 || !ports[base] || !ports[base+2*portStep] {
+		t.Errorf("got models for ports %v, want %d and %d", ports, base, base+2*portStep)
+	}
+}
+
+func TestStartEMLstReportsEveryPodWithBadConfig(t *testing.T) {
+	quietPackage(t)
+	discardStdout(t)
+	inTempDir(t)
+	resetExperimentModels(t)
+	base, _ := newMockPods(t, 2, portStep, podReply([]string{"a_BASE"}))
+	// Valid JSON, but the chain names a cube that is not declared
+	bad := `{"cubes":[{"Name":"a","Position":[0,0,0]}],"chains":[["a","ghost"]],"joint_type":"hinge"}`
+	if err := os.WriteFile("construct_config.json", []byte(bad), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	err := StartEMLst([]string{"127.0.0.1"}, base, authPass, delimiter)
+	if err == nil || !strings.Contains(err.Error(), "2 pods skipped") {
+		t.Fatalf("err = %v, want both pods reported", err)
+	}
+	for _, port := range []int{base, base + portStep} {
+		if addr := podAddr("127.0.0.1", port); !strings.Contains(err.Error(), addr) {
+			t.Errorf("err = %v, want it to name %s", err, addr)
+		}
+	}
+	if len(ExperimentModels) != 0 {
+		t.Errorf("got %d experiment models from a bad config, want 0", len(ExperimentModels))
+	}
+}
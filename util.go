@@ -36,6 +36,24 @@ func SaveToCSV(data [][]string, filename string) error {
 	return nil
 }
 
+// LoadFromCSV reads every row of a CSV file, such as one written by SaveToCSV. Rows may have
+// different numbers of fields.
+func LoadFromCSV(filename string) ([][]string, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open CSV file %s: %v", filename, err)
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV file %s: %v", filename, err)
+	}
+	return rows, nil
+}
+
 // FileExists checks if a file exists at the given path and returns true if it does, false otherwise.
 func FileExists(filename string) bool {
 	_, err := os.Stat(filename)
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"strconv"
+)
+
+// VerifyReport lists what a spawned construct is missing on the server.
+type VerifyReport struct {
+	MissingCubes  []string
+	MissingJoints []string
+}
+
+// OK reports whether every expected cube and joint was found.
+func (r VerifyReport) OK() bool {
+	return len(r.MissingCubes) == 0 && len(r.MissingJoints) == 0
+}
+
+// Verify checks a spawned construct against the server: the scanner rescans the construct's pod
+// to confirm every cube exists, and get_all_joints confirms every tracked joint between the
+// construct's cubes exists. An error is returned only if the server could not be queried.
+func (c *Construct) Verify(scanner *SparseScanner) (VerifyReport, error) {
+	var report VerifyReport
+	names := c.SpawnedCubes()
+	if len(names) == 0 {
+		return report, fmt.Errorf("[Verify] construct %s has not been spawned", c.unitName)
+	}
+
+	host, portStr, err := net.SplitHostPort(c.constructServerAddr)
+	if err != nil {
+		return report, fmt.Errorf("[Verify] invalid server address %s: %v", c.constructServerAddr, err)
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return report, fmt.Errorf("[Verify] invalid port in %s: %v", c.constructServerAddr, err)
+	}
+
+	// Step 1: Every spawned cube must appear in a fresh scan of the pod
+	result := scanner.ScanSinglePod(host, port)
+	if !result.Success {
+		return report, fmt.Errorf("[Verify] failed to scan %s: %s", c.constructServerAddr, result.Error)
+	}
+	onServer := make(map[string]bool, len(result.Cubes))
+	for _, cube := range result.Cubes {
+		onServer[cube] = true
+	}
+	cubeNames := make(map[string]bool, len(names))
+	for _, name := range names {
+		cubeNames[name] = true
+		if !onServer[name] {
+			report.MissingCubes = append(report.MissingCubes, name)
+		}
+	}
+
+	// Step 2: Every tracked joint of this construct must appear in get_all_joints
+	linkListMutex.Lock()
+	var expected []string
+	for _, link := range globalCubeLinks {
+		if cubeNames[link.CubeA] && cubeNames[link.CubeB] {
+			expected = append(expected, link.JointName)
+		}
+	}
+	linkListMutex.Unlock()
+
+	if len(expected) > 0 {
+		conn, _, err := c.connect()
+		if err != nil {
+			return report, fmt.Errorf("[Verify] %v", err)
+		}
+		defer conn.Close()
+
+		raw, err := sendCommand(conn, Message{"type": "get_all_joints"})
+		if err != nil {
+			return report, fmt.Errorf("[Verify] %v", err)
+		}
+		var resp struct {
+			Joints []string `json:"joints"`
+		}
+		if err := json.Unmarshal([]byte(raw), &resp); err != nil {
+			reportRawResponse(raw)
+			return report, fmt.Errorf("[Verify] failed to parse joint list: %v", err)
+		}
+
+		existing := make(map[string]bool, len(resp.Joints))
+		for _, joint := range resp.Joints {
+			existing[joint] = true
+		}
+		for _, joint := range expected {
+			if !existing[joint] {
+				report.MissingJoints = append(report.MissingJoints, joint)
+			}
+		}
+	}
+
+	if report.OK() {
+		c.log().Infof("✅ Construct %s verified: %d cubes, %d joints", c.unitName, len(cubeNames), len(expected))
+	} else {
+		c.log().Warnf("⚠️ Construct %s is missing %d cubes and %d joints", c.unitName, len(report.MissingCubes), len(report.MissingJoints))
+	}
+	return report, nil
+}
@@ -0,0 +1,361 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// WorldFile is a snapshot of everything a scanner discovered, in a form ImportWorld can respawn.
+type WorldFile struct {
+	Planets    []PlanetRecord   `json:"planets"`
+	Constructs []WorldConstruct `json:"constructs"`
+}
+
+// WorldConstruct is one construct in a WorldFile. Config cube names are stored without the unit
+// prefix and cube positions are relative to Position, so the config can be spawned again as is.
+type WorldConstruct struct {
+	UnitName     string          `json:"unit_name"`
+	Addr         string          `json:"addr"`          // host:port of the pod the construct lives on
+	Position     []float64       `json:"position"`      // Centroid of the construct's cubes
+	PlanetCenter []float64       `json:"planet_center"` // Center of the nearest planet, or Position if none
+	Config       ConstructConfig `json:"config"`
+}
+
+// defaultWorldJointType is used when a construct's joint names do not reveal their type.
+const defaultWorldJointType = "hinge"
+
+// ExportWorld writes the scanned planets and every construct found in the scan results to filename.
+// Each construct's config is rebuilt from the current cube positions and the tracked joints, so
+// only joints in globalCubeLinks appear in its chains, with the parameters they were linked or
+// overridden with as JointParams and JointOverrides. Cubes that do not carry a unit name are skipped.
+// Only positions are read back from the pods, so exported cubes lose their Rotation, CollisionGroup
+// and CollisionMask and are respawned by ImportWorld unrotated with the default collision settings.
+func (s *SparseScanner) ExportWorld(filename string) error {
+	world := WorldFile{}
+	unitAddrs := make(map[string]string)
+	s.mapsMu.RLock()
+	for _, planet := range s.PlanetsMap {
+		world.Planets = append(world.Planets, planet)
+	}
+	// Group cubes by unit name, remembering the pod each unit lives on
+	for cube, addr := range s.cubeAddrs {
+		if unitName, ok := parseUnitID(cube); ok {
+			unitAddrs[unitName] = addr
+		}
+	}
+	s.mapsMu.RUnlock()
+	sort.Slice(world.Planets, func(i, j int) bool { return world.Planets[i].Name < world.Planets[j].Name })
+
+	unitNames := make([]string, 0, len(unitAddrs))
+	for unitName := range unitAddrs {
+		unitNames = append(unitNames, unitName)
+	}
+	sort.Strings(unitNames)
+
+	for _, unitName := range unitNames {
+		construct, err := s.reconstructWorldConstruct(unitName, unitAddrs[unitName])
+		if err != nil {
+			return fmt.Errorf("[ExportWorld] Construct %s: %v", unitName, err)
+		}
+		world.Constructs = append(world.Constructs, construct)
+	}
+
+	data, err := json.MarshalIndent(world, "", "  ")
+	if err != nil {
+		return fmt.Errorf("[ExportWorld] Failed to encode world: %v", err)
+	}
+	if err := os.WriteFile(filename, data, 0644); err != nil {
+		return fmt.Errorf("[ExportWorld] Failed to write %s: %v", filename, err)
+	}
+	s.log().Infof("💾 Exported %d planets and %d constructs to %s", len(world.Planets), len(world.Constructs), filename)
+	return nil
+}
+
+// reconstructWorldConstruct rebuilds a construct's config from its live cube positions and tracked joints.
+func (s *SparseScanner) reconstructWorldConstruct(unitName, addr string) (WorldConstruct, error) {
+	prefix := unitName + "_"
+	positions, err := s.GetCubesWithTransforms(prefix)
+	if err != nil {
+		return WorldConstruct{}, err
+	}
+
+	var centroid [3]float64
+	for _, pos := range positions {
+		centroid[0] += pos[0]
+		centroid[1] += pos[1]
+		centroid[2] += pos[2]
+	}
+	count := float64(len(positions))
+	centroid[0] /= count
+	centroid[1] /= count
+	centroid[2] /= count
+
+	construct := WorldConstruct{
+		UnitName: unitName,
+		Addr:     addr,
+		Position: []float64{centroid[0], centroid[1], centroid[2]},
+		Config:   ConstructConfig{JointType: defaultWorldJointType},
+	}
+	construct.PlanetCenter = s.nearestPlanetCenter(construct.Position)
+
+	for cube, pos := range positions {
+		construct.Config.Cubes = append(construct.Config.Cubes, Cube{
+			Name:     worldCubeName(cube, prefix),
+			Position: []float64{pos[0] - centroid[0], pos[1] - centroid[1], pos[2] - centroid[2]},
+		})
+	}
+	sort.Slice(construct.Config.Cubes, func(i, j int) bool {
+		return construct.Config.Cubes[i].Name < construct.Config.Cubes[j].Name
+	})
+
+	// Every tracked joint between two of this construct's cubes becomes a two-cube chain
+	linkListMutex.Lock()
+	for _, link := range globalCubeLinks {
+		if !strings.HasPrefix(link.CubeA, prefix) || !strings.HasPrefix(link.CubeB, prefix) {
+			continue
+		}
+		chain := ChainSpec{Names: []string{
+			worldCubeName(link.CubeA, prefix),
+			worldCubeName(link.CubeB, prefix),
+		}}
+		if jointType := jointTypeFromName(link.JointName); jointType != "" {
+			chain.JointType = jointType
+		}
+		construct.Config.Chains = append(construct.Config.Chains, chain)
+		addWorldJointParams(&construct.Config, chain.Names[0], chain.Names[1], link.Params)
+	}
+	linkListMutex.Unlock()
+
+	return construct, nil
+}
+
+// addWorldJointParams records a link's tracked parameters in config. The first link with parameters
+// sets JointParams; later links that differ from it get a JointOverrides entry with the changed keys.
+func addWorldJointParams(config *ConstructConfig, cubeA, cubeB string, params map[string]float64) {
+	if len(params) == 0 {
+		return
+	}
+	if config.JointParams == nil {
+		config.JointParams = make(map[string]float64, len(params))
+		for key, value := range params {
+			config.JointParams[key] = value
+		}
+		return
+	}
+	override := make(map[string]float64)
+	for key, value := range params {
+		if def, ok := config.JointParams[key]; !ok || def != value {
+			override[key] = value
+		}
+	}
+	if len(override) == 0 {
+		return
+	}
+	if config.JointOverrides == nil {
+		config.JointOverrides = make(map[string]map[string]float64)
+	}
+	config.JointOverrides[jointOverrideKey(cubeA, cubeB)] = override
+}
+
+// worldCubeName strips the unit prefix and the server's "_BASE" suffix from a cube name.
+func worldCubeName(cubeName, prefix string) string {
+	return strings.TrimSuffix(strings.TrimPrefix(cubeName, prefix), "_BASE")
+}
+
+// jointTypeFromName extracts the type from joint names of the form "joint_<type>_<cubeA>_<cubeB>".
+func jointTypeFromName(jointName string) string {
+	parts := strings.SplitN(jointName, "_", 3)
+	if len(parts) < 3 || parts[0] != "joint" {
+		return ""
+	}
+	return parts[1]
+}
+
+// nearestPlanetCenter returns the coordinates of the planet closest to pos, or pos itself
+// when no planets were discovered.
+func (s *SparseScanner) nearestPlanetCenter(pos []float64) []float64 {
+	planet, _, ok := s.FindNearestPlanet(pos)
+	if !ok {
+		return []float64{pos[0], pos[1], pos[2]}
+	}
+	return []float64{planet.Coordinates[0], planet.Coordinates[1], planet.Coordinates[2]}
+}
+
+// ImportWorld reads a file written by ExportWorld and respawns every construct on the pod it was
+// exported from. Planets are generated by the servers, so they are only checked against the
+// current scan and reported if missing. All constructs are attempted; the first error is returned.
+func (s *SparseScanner) ImportWorld(filename string) error {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return fmt.Errorf("[ImportWorld] Failed to read %s: %v", filename, err)
+	}
+	var world WorldFile
+	if err := json.Unmarshal(data, &world); err != nil {
+		return fmt.Errorf("[ImportWorld] Failed to decode %s: %v", filename, err)
+	}
+
+	s.mapsMu.RLock()
+	var missingPlanets []string
+	for _, planet := range world.Planets {
+		if _, ok := s.PlanetsMap[planet.Name]; !ok {
+			missingPlanets = append(missingPlanets, planet.Name)
+		}
+	}
+	s.mapsMu.RUnlock()
+	for _, name := range missingPlanets {
+		s.log().Warnf("⚠️ [ImportWorld] Planet %s from %s was not found in the current scan", name, filename)
+	}
+
+	var firstErr error
+	spawned := 0
+	for _, wc := range world.Constructs {
+		construct := NewConstruct(wc.Addr, s.AuthPass, s.EndMarker)
+		construct.Logger = s.Logger
+		configJSON, err := json.Marshal(wc.Config)
+		if err == nil {
+			err = construct.LoadConfigFromJSONString(string(configJSON), wc.UnitName)
+		}
+		if err == nil {
+			err = construct.Spawn(wc.Position, wc.PlanetCenter)
+		}
+		if err != nil {
+			s.log().Errorf("❌ [ImportWorld] Failed to respawn %s: %v", wc.UnitName, err)
+			if firstErr == nil {
+				firstErr = fmt.Errorf("[ImportWorld] Construct %s: %v", wc.UnitName, err)
+			}
+			continue
+		}
+		spawned++
+	}
+
+	s.log().Infof("📦 Imported %d of %d constructs from %s", spawned, len(world.Constructs), filename)
+	return firstErr
+}
+
+// WorldSnapshot is the state of every cube and joint on one server at a moment in time.
+type WorldSnapshot struct {
+	TakenAt time.Time       `json:"taken_at"`
+	Cubes   []SnapshotCube  `json:"cubes"`
+	Joints  []SnapshotJoint `json:"joints"`
+}
+
+// SnapshotCube is one cube in a WorldSnapshot. Position is nil if the server did not report it.
+type SnapshotCube struct {
+	Name     string    `json:"name"`
+	Position []float64 `json:"position,omitempty"`
+}
+
+// SnapshotJoint is one joint in a WorldSnapshot. CubeA and CubeB are empty if unknown.
+type SnapshotJoint struct {
+	Name  string `json:"name"`
+	CubeA string `json:"cube_a,omitempty"`
+	CubeB string `json:"cube_b,omitempty"`
+}
+
+// SnapshotWorld captures every cube and joint on the server in one get_world_snapshot round trip.
+// The server is expected to reply with
+//
+//	{"cubes": [{"name": ..., "position": [x, y, z]}], "joints": [{"joint_name": ..., "cube1": ..., "cube2": ...}]}
+//
+// where positions, endpoints, and either list may be omitted and joints may be plain names.
+// Servers without get_world_snapshot are queried with get_cube_list and get_all_joints instead,
+// which gives no positions; joint endpoints then come from the tracked links.
+func SnapshotWorld(conn net.Conn) (WorldSnapshot, error) {
+	snapshot := WorldSnapshot{TakenAt: time.Now()}
+	raw, err := sendCommand(conn, Message{"type": "get_world_snapshot"})
+	if err != nil {
+		if responseError(raw) == nil {
+			return snapshot, fmt.Errorf("[SnapshotWorld] %v", err)
+		}
+		return snapshotFromLists(conn, snapshot)
+	}
+
+	var resp struct {
+		Cubes []struct {
+			Name     string    `json:"name"`
+			Position []float64 `json:"position"`
+		} `json:"cubes"`
+		Joints []json.RawMessage `json:"joints"`
+	}
+	if err := json.Unmarshal([]byte(raw), &resp); err != nil {
+		reportRawResponse(raw)
+		return snapshot, fmt.Errorf("[SnapshotWorld] Failed to parse snapshot: %v", err)
+	}
+	for _, cube := range resp.Cubes {
+		snapshot.Cubes = append(snapshot.Cubes, SnapshotCube{Name: cube.Name, Position: cube.Position})
+	}
+	for _, rawJoint := range resp.Joints {
+		var name string
+		if json.Unmarshal(rawJoint, &name) == nil {
+			snapshot.Joints = append(snapshot.Joints, trackedSnapshotJoint(name))
+			continue
+		}
+		var joint struct {
+			JointName string `json:"joint_name"`
+			Cube1     string `json:"cube1"`
+			Cube2     string `json:"cube2"`
+		}
+		if err := json.Unmarshal(rawJoint, &joint); err != nil {
+			reportRawResponse(raw)
+			return snapshot, fmt.Errorf("[SnapshotWorld] Failed to parse joint %s: %v", rawJoint, err)
+		}
+		snapshot.Joints = append(snapshot.Joints, SnapshotJoint{Name: joint.JointName, CubeA: joint.Cube1, CubeB: joint.Cube2})
+	}
+	return snapshot, nil
+}
+
+// snapshotFromLists fills a snapshot from get_cube_list and get_all_joints.
+func snapshotFromLists(conn net.Conn, snapshot WorldSnapshot) (WorldSnapshot, error) {
+	cubes, err := GetCubeList(conn)
+	if err != nil {
+		return snapshot, fmt.Errorf("[SnapshotWorld] %v", err)
+	}
+	for _, cube := range cubes {
+		snapshot.Cubes = append(snapshot.Cubes, SnapshotCube{Name: cube})
+	}
+
+	raw, err := sendCommand(conn, Message{"type": "get_all_joints"})
+	if err != nil {
+		return snapshot, fmt.Errorf("[SnapshotWorld] %v", err)
+	}
+	var resp struct {
+		Joints []string `json:"joints"`
+	}
+	if err := json.Unmarshal([]byte(raw), &resp); err != nil {
+		reportRawResponse(raw)
+		return snapshot, fmt.Errorf("[SnapshotWorld] Failed to parse joint list: %v", err)
+	}
+	for _, name := range resp.Joints {
+		snapshot.Joints = append(snapshot.Joints, trackedSnapshotJoint(name))
+	}
+	return snapshot, nil
+}
+
+// trackedSnapshotJoint looks up a joint's endpoints in globalCubeLinks.
+func trackedSnapshotJoint(name string) SnapshotJoint {
+	linkListMutex.Lock()
+	defer linkListMutex.Unlock()
+	for _, link := range globalCubeLinks {
+		if link.JointName == name {
+			return SnapshotJoint{Name: name, CubeA: link.CubeA, CubeB: link.CubeB}
+		}
+	}
+	return SnapshotJoint{Name: name}
+}
+
+// SaveJSON writes the snapshot to filename as indented JSON, so runs can be diffed.
+func (w WorldSnapshot) SaveJSON(filename string) error {
+	data, err := json.MarshalIndent(w, "", "  ")
+	if err != nil {
+		return fmt.Errorf("[SaveJSON] Failed to encode snapshot: %v", err)
+	}
+	if err := os.WriteFile(filename, data, 0644); err != nil {
+		return fmt.Errorf("[SaveJSON] Failed to write %s: %v", filename, err)
+	}
+	return nil
+}